@@ -0,0 +1,342 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The prefix of API keys, which tells them apart from other bearer tokens
+const apiKeyPrefix = "ak_"
+
+// The longest an old secret keeps working after its key is rotated
+const maxRotationGrace = 7 * 24 * time.Hour
+
+/*
+An API key callers authenticate with.  Only a hash of the secret is stored, so the key is
+only shown when it is created or rotated.  After a rotation the previous secret keeps
+working until PreviousExpires, so callers can switch over without downtime.
+
+The key signed requests are checked with is derived from the secret, and stored encrypted
+with the configured signing_encryption_key, so reading the database isn't enough to sign.
+*/
+type APIKey struct {
+	ID                 bson.ObjectId `json:"id" bson:"_id"`
+	Name               string        `json:"name" bson:"name"`
+	Services           []string      `json:"services" bson:"services"`
+	Scopes             []string      `json:"scopes" bson:"scopes"`
+	Hash               string        `json:"-" bson:"hash"`
+	PreviousHash       string        `json:"-" bson:"previous_hash,omitempty"`
+	SigningKey         string        `json:"-" bson:"signing_key,omitempty"`
+	PreviousSigningKey string        `json:"-" bson:"previous_signing_key,omitempty"`
+	PreviousExpires    *time.Time    `json:"previous_expires,omitempty" bson:"previous_expires,omitempty"`
+	Created            time.Time     `json:"created" bson:"created"`
+	Rotated            *time.Time    `json:"rotated,omitempty" bson:"rotated,omitempty"`
+	Revoked            *time.Time    `json:"revoked,omitempty" bson:"revoked,omitempty"`
+	Key                string        `json:"key,omitempty" bson:"-"`
+}
+
+// Gets the API key collection in the database of the ACL collection
+func apiKeyCollection(c *mgo.Collection) *mgo.Collection {
+	return c.Database.C("api_keys")
+}
+
+// Hashes the secret of an API key for storing
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Generates a new secret for an API key
+func newAPIKeySecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Splits an API key into the ID of the key and its secret
+func parseAPIKey(key string) (bson.ObjectId, string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, apiKeyPrefix), ".", 2)
+	if len(parts) != 2 || !bson.IsObjectIdHex(parts[0]) || parts[1] == "" {
+		return "", "", false
+	}
+	return bson.ObjectIdHex(parts[0]), parts[1], true
+}
+
+// Checks if the secret is the key's current secret, or its previous one during a rotation
+func (k APIKey) matches(secret string, now time.Time) bool {
+	hash := hashAPIKeySecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(k.Hash)) == 1 {
+		return true
+	}
+	return k.PreviousHash != "" && k.PreviousExpires != nil && now.Before(*k.PreviousExpires) &&
+		subtle.ConstantTimeCompare([]byte(hash), []byte(k.PreviousHash)) == 1
+}
+
+// Creates an API key, returning it with the plaintext key set
+func (k APIKey) Create(c *mgo.Collection, item apiKeyItem) (APIKey, error) {
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return APIKey{}, err
+	}
+	signingKey, err := sealSigningKey(deriveSigningKey(secret))
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	key := APIKey{
+		ID:         bson.NewObjectId(),
+		Name:       item.Name,
+		Services:   item.Services,
+		Scopes:     item.Scopes,
+		Hash:       hashAPIKeySecret(secret),
+		SigningKey: signingKey,
+		Created:    time.Now().UTC(),
+	}
+
+	log.Finest("Creating API key: %s %s", key.ID.Hex(), key.Name)
+	err = apiKeyCollection(c).Insert(key)
+	key.Key = apiKeyPrefix + key.ID.Hex() + "." + secret
+	return key, err
+}
+
+// Gets an API key
+func (k APIKey) Get(c *mgo.Collection, id bson.ObjectId) (APIKey, error) {
+	result := APIKey{}
+	err := apiKeyCollection(c).FindId(id).One(&result)
+	return result, err
+}
+
+// Lists the API keys, including revoked ones
+func (k APIKey) List(c *mgo.Collection) ([]APIKey, error) {
+	result := []APIKey{}
+	err := apiKeyCollection(c).Find(nil).Sort("created").All(&result)
+	return result, err
+}
+
+// Gives an API key a new secret, returning it with the plaintext key set.  The old secret
+// keeps working for the grace period.
+func (k APIKey) Rotate(c *mgo.Collection, id bson.ObjectId, grace time.Duration) (APIKey, error) {
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	signingKey, err := sealSigningKey(deriveSigningKey(secret))
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	now := time.Now().UTC()
+	update := bson.M{"hash": hashAPIKeySecret(secret), "rotated": now}
+	unset := bson.M{}
+	if signingKey != "" {
+		update["signing_key"] = signingKey
+	} else {
+		unset["signing_key"] = ""
+	}
+	if grace > 0 {
+		current, err := k.Get(c, id)
+		if err != nil {
+			return APIKey{}, err
+		}
+		update["previous_hash"] = current.Hash
+		update["previous_expires"] = now.Add(grace)
+		if current.SigningKey != "" {
+			update["previous_signing_key"] = current.SigningKey
+		} else {
+			unset["previous_signing_key"] = ""
+		}
+	} else {
+		unset["previous_hash"] = ""
+		unset["previous_expires"] = ""
+		unset["previous_signing_key"] = ""
+	}
+
+	change := bson.M{"$set": update}
+	if len(unset) > 0 {
+		change["$unset"] = unset
+	}
+
+	log.Finest("Rotating API key: %s", id.Hex())
+	err = apiKeyCollection(c).Update(bson.M{"_id": id, "revoked": nil}, change)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	key, err := k.Get(c, id)
+	key.Key = apiKeyPrefix + id.Hex() + "." + secret
+	return key, err
+}
+
+// Revokes an API key, so neither its current nor previous secret work any more
+func (k APIKey) Revoke(c *mgo.Collection, id bson.ObjectId) error {
+	log.Finest("Revoking API key: %s", id.Hex())
+	return apiKeyCollection(c).Update(bson.M{"_id": id, "revoked": nil},
+		bson.M{"$set": bson.M{"revoked": time.Now().UTC()}})
+}
+
+// Gets the API key a request carries in the X-API-Key header, or as an Authorization
+// bearer token
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "Bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+// Authenticates with an API key
+func authenticateAPIKey(r *http.Request, c *mgo.Collection) (*Caller, *APIError) {
+	key := requestAPIKey(r)
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil
+	}
+
+	invalid := &APIError{Code: ErrCodeUnauthenticated, Message: "Invalid API key"}
+	id, secret, ok := parseAPIKey(key)
+	if !ok {
+		return nil, invalid
+	}
+
+	stored, err := APIKey{}.Get(c, id)
+	if err == mgo.ErrNotFound {
+		return nil, invalid
+	} else if err != nil {
+		log.Error("An error occurred getting API key %s: %s", id.Hex(), err)
+		return nil, &APIError{Code: ErrCodeStorage, Message: "An error occurred checking API key"}
+	}
+	if stored.Revoked != nil || !stored.matches(secret, time.Now()) {
+		return nil, invalid
+	}
+
+	return &Caller{ID: callerKeyPrefix + stored.ID.Hex(), Method: "api_key", Services: stored.Services, Scopes: stored.Scopes}, nil
+}
+
+// Gets the ID of the API key in the URL, responding not found if it isn't one
+func getAPIKeyID(w http.ResponseWriter, r *http.Request) (bson.ObjectId, bool) {
+	return getWebhookID(w, r, "key")
+}
+
+// Writes an API key, or a list of them, as a JSON response
+func writeAPIKeys(w http.ResponseWriter, status int, result interface{}, action string) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred "+action)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// This is a URL handler that creates API keys.  The keys are only shown in this response.
+func createAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+
+	items := []apiKeyItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+
+	result := []APIKey{}
+	for idx, item := range items {
+		key, err := APIKey{}.Create(c, item)
+		if err != nil {
+			log.Error("An error occurred creating API key. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred creating API key", idx, "")
+			return
+		}
+		result = append(result, key)
+	}
+
+	writeAPIKeys(w, 201, result, "creating API keys")
+}
+
+// This is a URL handler that lists the API keys
+func listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+
+	result, err := APIKey{}.List(c)
+	if err != nil {
+		log.Error("An error occurred listing API keys. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred listing API keys")
+		return
+	}
+
+	writeAPIKeys(w, 200, result, "listing API keys")
+}
+
+// This is a URL handler that gives an API key a new secret.  The grace_seconds parameter
+// keeps the old secret working for a while.
+func rotateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+	id, ok := getAPIKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	var grace time.Duration
+	if value := r.URL.Query().Get("grace_seconds"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxRotationGrace {
+			writeError(w, 400, ErrCodeInvalidParameter, "grace_seconds must be between 0 and "+
+				strconv.Itoa(int(maxRotationGrace/time.Second)))
+			return
+		}
+		grace = time.Duration(seconds) * time.Second
+	}
+
+	key, err := APIKey{}.Rotate(c, id, grace)
+	if err == mgo.ErrNotFound {
+		writeError(w, 404, ErrCodeNotFound, "No such API key")
+		return
+	} else if err != nil {
+		log.Error("An error occurred rotating API key. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred rotating API key")
+		return
+	}
+
+	writeAPIKeys(w, 200, key, "rotating API key")
+}
+
+// This is a URL handler that revokes an API key
+func revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+	id, ok := getAPIKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	err := APIKey{}.Revoke(c, id)
+	if err == mgo.ErrNotFound {
+		writeError(w, 404, ErrCodeNotFound, "No such API key")
+		return
+	} else if err != nil {
+		log.Error("An error occurred revoking API key. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred revoking API key")
+		return
+	}
+
+	w.WriteHeader(204)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gorilla/context"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// The caller recorded for changes made without authentication
+const anonymousCaller = "anonymous"
+
+// Who made a change, as recorded in its audit entry
+type Actor struct {
+	Caller    string `json:"caller" bson:"caller"`
+	Method    string `json:"method,omitempty" bson:"method,omitempty"`
+	RequestID string `json:"request_id,omitempty" bson:"request_id,omitempty"`
+}
+
+// Gets the actor of a caller making the request with the ID
+func callerActor(caller *Caller, requestID string) *Actor {
+	if caller == nil {
+		return &Actor{Caller: anonymousCaller, RequestID: requestID}
+	}
+	return &Actor{Caller: caller.ID, Method: caller.Method, RequestID: requestID}
+}
+
+// Gets the actor making the request
+func requestActor(r *http.Request) *Actor {
+	return callerActor(requestCaller(r), requestID(r))
+}
+
+// The header carrying the request ID
+const requestIDHeader = "X-Request-Id"
+
+// The request IDs accepted from clients.  Others are replaced, so they can't be used to
+// forge audit entries.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Generates a new request ID
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Gets the request ID the client sent, or a new one if it didn't send a valid one
+func incomingRequestID(id string) string {
+	if validRequestID.MatchString(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// Sets the ID of the request, echoing it in the response so clients can refer to it
+func setRequestID(w http.ResponseWriter, r *http.Request) {
+	id := incomingRequestID(r.Header.Get(requestIDHeader))
+	context.Set(r, "requestID", id)
+	w.Header().Set(requestIDHeader, id)
+}
+
+// Gets the ID of the request
+func requestID(r *http.Request) string {
+	id, _ := context.Get(r, "requestID").(string)
+	return id
+}
+
+/*
+An entry of the audit log, recording a change made to an ACL.  Before and After are the
+privileges of the ACL before and after the change, and are null when it didn't exist.
+Entries are only ever inserted, and are chained: Seq numbers them from 1 and Hash covers
+the entry and PrevHash, the hash of the entry before it.
+*/
+type AuditEntry struct {
+	ID       bson.ObjectId `json:"id" bson:"_id"`
+	Seq      int64         `json:"seq" bson:"seq,omitempty"`
+	PrevHash string        `json:"prev_hash" bson:"prev_hash"`
+	Hash     string        `json:"hash" bson:"hash"`
+	Time     time.Time     `json:"time" bson:"time"`
+	Actor    `bson:",inline"`
+	Action   string                 `json:"action" bson:"action"`
+	Service  string                 `json:"service" bson:"service"`
+	Object   string                 `json:"object" bson:"object"`
+	Key      string                 `json:"key" bson:"key"`
+	User     string                 `json:"user" bson:"user"`
+	Before   map[string]interface{} `json:"before" bson:"before"`
+	After    map[string]interface{} `json:"after" bson:"after"`
+}
+
+// Gets the audit log collection in the database of the ACL collection
+func auditCollection(c *mgo.Collection) *mgo.Collection {
+	mongo, _ := Application.Config["mongo"].(map[string]interface{})
+	name, ok := mongo["audit_collection"].(string)
+	if !ok {
+		name = "audit_log"
+	}
+	return c.Database.C(name)
+}
+
+// Creates the indexes of the audit log collection.  seq is unique so concurrent appends
+// can't fork the chain, and sparse since entries from before it was chained have none.
+func (e AuditEntry) EnsureIndex(c *mgo.Collection) error {
+	err := auditCollection(c).EnsureIndex(mgo.Index{Key: []string{"seq"}, Unique: true, Sparse: true})
+	if err != nil {
+		return err
+	}
+	for _, key := range [][]string{{"time"}, {"service", "object", "key"}, {"user"}, {"caller"}} {
+		err := auditCollection(c).EnsureIndex(mgo.Index{Key: key})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Appends an entry for a change to the audit log.  Changes made without an actor are
+// recorded as the system's.
+func recordAudit(c *mgo.Collection, actor *Actor, action string, service string, object string, key string,
+	user string, before map[string]interface{}, after map[string]interface{}) error {
+	if actor == nil {
+		actor = &Actor{Caller: "system"}
+	}
+
+	entry := AuditEntry{
+		ID:      bson.NewObjectId(),
+		Time:    time.Now().UTC().Truncate(time.Millisecond),
+		Actor:   *actor,
+		Action:  action,
+		Service: service,
+		Object:  object,
+		Key:     key,
+		User:    user,
+		Before:  before,
+		After:   after,
+	}
+
+	log.Finest("Recording audit entry: %+v", entry)
+	return appendAudit(c, &entry)
+}
+
+// Filters of an audit log query.  Zero values don't filter.
+type AuditQuery struct {
+	From    time.Time
+	To      time.Time
+	Service string
+	Object  string
+	Key     string
+	User    string
+	Caller  string
+}
+
+// Reads the audit log entries matching the query, oldest first, after the cursor.  Returns
+// the cursor of the next page, which is empty on the last page.
+func (e AuditEntry) List(c *mgo.Collection, query AuditQuery, cursor string, limit int) ([]AuditEntry, string, error) {
+	selector := bson.M{}
+	for field, value := range map[string]string{"service": query.Service, "object": query.Object,
+		"key": query.Key, "user": query.User, "caller": query.Caller} {
+		if value != "" {
+			selector[field] = value
+		}
+	}
+
+	times := bson.M{}
+	if !query.From.IsZero() {
+		times["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		times["$lt"] = query.To
+	}
+	if len(times) > 0 {
+		selector["time"] = times
+	}
+
+	if cursor != "" {
+		if !bson.IsObjectIdHex(cursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		selector["_id"] = bson.M{"$gt": bson.ObjectIdHex(cursor)}
+	}
+
+	result := []AuditEntry{}
+	err := auditCollection(c).Find(selector).Sort("_id").Limit(limit + 1).All(&result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(result) > limit {
+		result = result[:limit]
+		next = result[limit-1].ID.Hex()
+	}
+	return result, next, nil
+}
+
+// The most audit entries returned at once
+const maxAuditLimit = 1000
+
+// Parses an optional RFC 3339 time parameter
+func timeParam(w http.ResponseWriter, r *http.Request, name string) (time.Time, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Debug("Invalid %s time: %s", name, value)
+		writeError(w, 400, ErrCodeInvalidParameter, name+" must be an RFC 3339 time")
+		return time.Time{}, false
+	}
+	return result, true
+}
+
+// This is a URL handler that queries the audit log
+func auditLogHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+	params := r.URL.Query()
+
+	query := AuditQuery{
+		Service: params.Get("service"),
+		Object:  params.Get("object"),
+		Key:     params.Get("key"),
+		User:    params.Get("user"),
+		Caller:  params.Get("caller"),
+	}
+	var ok bool
+	if query.From, ok = timeParam(w, r, "from"); !ok {
+		return
+	}
+	if query.To, ok = timeParam(w, r, "to"); !ok {
+		return
+	}
+
+	limit := 100
+	if value := params.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			writeError(w, 400, ErrCodeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxAuditLimit))
+			return
+		}
+	}
+
+	entries, next, err := AuditEntry{}.List(c, query, params.Get("cursor"), limit)
+	if err == ErrInvalidCursor {
+		log.Debug("Invalid audit cursor: %s", params.Get("cursor"))
+		writeError(w, 400, ErrCodeInvalidCursor, "Invalid cursor")
+		return
+	} else if err != nil {
+		log.Error("An error occurred querying audit log. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred querying audit log")
+		return
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"entries": entries, "next_cursor": next})
+	if err != nil {
+		log.Error(err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred querying audit log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The key audit checkpoints are signed with, or empty to not write checkpoints
+var auditCheckpointKey = ""
+
+// How often a checkpoint of the audit chain is written
+var auditCheckpointInterval = time.Hour
+
+// Reads the checkpoint settings from the "audit" section of the config
+func configureAudit() {
+	audit, ok := Application.Config["audit"].(map[string]interface{})
+	if !ok {
+		log.Info("No audit settings in config, not writing audit checkpoints")
+		return
+	}
+
+	auditCheckpointKey, _ = audit["checkpoint_key"].(string)
+	if seconds, ok := audit["checkpoint_seconds"].(float64); ok && seconds > 0 {
+		auditCheckpointInterval = time.Duration(seconds) * time.Second
+	}
+
+	if auditCheckpointKey == "" {
+		log.Info("No audit checkpoint key in config, not writing audit checkpoints")
+		return
+	}
+	log.Info("Writing audit checkpoints every %s", auditCheckpointInterval)
+}
+
+// Hashes the entry's contents and the hash of the entry before it.  Mongo stores times to
+// the millisecond in UTC, so entries are hashed that way.
+func (e AuditEntry) chainHash() string {
+	e.Hash = ""
+	e.Time = e.Time.UTC().Truncate(time.Millisecond)
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// The sequence number of the last entry stored in the audit chain.  Entries are only ever
+// appended, so entries missing from before it were removed.
+type auditHead struct {
+	Seq int64 `bson:"seq"`
+}
+
+// Selects the head of the audit chain in the counters collection
+func auditHeadSelector(c *mgo.Collection) bson.M {
+	return bson.M{"counter": auditCollection(c).Name}
+}
+
+// Gets the head of the audit chain, which is empty before the first entry
+func getAuditHead(c *mgo.Collection) (auditHead, error) {
+	head := auditHead{}
+	err := c.Database.C("counters").Find(auditHeadSelector(c)).One(&head)
+	if err == mgo.ErrNotFound {
+		return head, nil
+	}
+	return head, err
+}
+
+// Gets the last entry of the audit chain, which is empty before the first entry
+func lastAuditEntry(c *mgo.Collection) (AuditEntry, error) {
+	last := AuditEntry{}
+	err := auditCollection(c).Find(bson.M{"seq": bson.M{"$gt": 0}}).Sort("-seq").One(&last)
+	if err == mgo.ErrNotFound {
+		return AuditEntry{}, nil
+	}
+	return last, err
+}
+
+// Serializes the appends of this process, so they don't retry on each other.  Appends from
+// other processes are kept in order by the unique index on seq.
+var auditLock sync.Mutex
+
+/*
+Appends the entry to the audit chain.  The entry takes the next sequence number and the
+hash of the last entry, and is inserted under the unique index on seq, so if another writer
+appended first the insert fails and is retried after its entry, and the chain never forks.
+The head only moves once the entry is stored, so it never points past the end of the log
+unless entries were removed from its end.  Appending then would reuse their sequence
+numbers and hide the removal once the log grew back past the head, so it's refused.
+*/
+func appendAudit(c *mgo.Collection, entry *AuditEntry) error {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+
+	for {
+		// The head is read first, so entries appended by other writers since can only put
+		// the last entry past it
+		head, err := getAuditHead(c)
+		if err != nil {
+			return err
+		}
+		last, err := lastAuditEntry(c)
+		if err != nil {
+			return err
+		}
+		if head.Seq > last.Seq {
+			log.Error("Audit log ends at entry %d but its head is at %d, entries were removed. "+
+				"Not appending to it until it's restored", last.Seq, head.Seq)
+			return fmt.Errorf("Audit log is missing entries %d to %d", last.Seq+1, head.Seq)
+		}
+
+		entry.Seq = last.Seq + 1
+		entry.PrevHash = last.Hash
+		entry.Hash = entry.chainHash()
+
+		err = auditCollection(c).Insert(entry)
+		if mgo.IsDup(err) {
+			log.Debug("Audit entry %d was appended by another writer, retrying", entry.Seq)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		_, err = c.Database.C("counters").Upsert(auditHeadSelector(c), bson.M{"$max": bson.M{"seq": entry.Seq}})
+		return err
+	}
+}
+
+/*
+A signed record of the audit chain's hash at an entry.  Checkpoints kept apart from the
+log, or handed to an auditor, prove the chain up to them hasn't been rewritten since.
+*/
+type AuditCheckpoint struct {
+	ID        bson.ObjectId `json:"id" bson:"_id"`
+	Seq       int64         `json:"seq" bson:"seq"`
+	Hash      string        `json:"hash" bson:"hash"`
+	Time      time.Time     `json:"time" bson:"time"`
+	Signature string        `json:"signature" bson:"signature"`
+}
+
+// Gets the checkpoint collection of the audit log collection
+func checkpointCollection(c *mgo.Collection) *mgo.Collection {
+	return c.Database.C(auditCollection(c).Name + "_checkpoints")
+}
+
+// Signs the checkpoint's sequence number, hash and time with the checkpoint key
+func (p AuditCheckpoint) sign() string {
+	mac := hmac.New(sha256.New, []byte(auditCheckpointKey))
+	fmt.Fprintf(mac, "%d\n%s\n%s", p.Seq, p.Hash, p.Time.UTC().Format(time.RFC3339Nano))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Writes a checkpoint at the last entry of the audit log, unless there's already one there
+func writeAuditCheckpoint(c *mgo.Collection) error {
+	last, err := lastAuditEntry(c)
+	if err != nil || last.Seq == 0 {
+		return err
+	}
+
+	count, err := checkpointCollection(c).Find(bson.M{"seq": last.Seq}).Count()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	checkpoint := AuditCheckpoint{
+		ID:   bson.NewObjectId(),
+		Seq:  last.Seq,
+		Hash: last.Hash,
+		Time: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	checkpoint.Signature = checkpoint.sign()
+
+	log.Info("Writing audit checkpoint at entry %d", checkpoint.Seq)
+	return checkpointCollection(c).Insert(checkpoint)
+}
+
+// Writes audit checkpoints every checkpoint interval
+func runAuditCheckpoints() {
+	for {
+		time.Sleep(auditCheckpointInterval)
+
+		session, _, c, err := getMongo()
+		if err != nil {
+			log.Error("Error connecting to database for audit checkpoints: %s", err)
+			continue
+		}
+
+		err = writeAuditCheckpoint(c)
+		if err != nil {
+			log.Error("An error occurred writing an audit checkpoint: %s", err)
+		}
+		session.Close()
+	}
+}
+
+// The first link of the audit chain that doesn't hold
+type AuditBreak struct {
+	Seq    int64  `json:"seq"`
+	ID     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// The result of verifying the audit chain.  Entries and Checkpoints count those verified
+// before the first break, if there is one.
+type AuditVerification struct {
+	Valid       bool        `json:"valid"`
+	Entries     int64       `json:"entries"`
+	Checkpoints int         `json:"checkpoints"`
+	Broken      *AuditBreak `json:"broken,omitempty"`
+}
+
+/*
+Walks the audit chain from its first entry, checking each entry follows the one before it
+and hashes to its recorded hash, and that each checkpoint is signed and matches the entry
+it was written at.  Checkpoint signatures are only checked when a checkpoint key is set.
+Entries recorded before the log was chained have no sequence number and are skipped.
+*/
+func verifyAudit(c *mgo.Collection) (*AuditVerification, error) {
+	checkpoints := []AuditCheckpoint{}
+	err := checkpointCollection(c).Find(nil).Sort("seq").All(&checkpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AuditVerification{}
+	broken := func(seq int64, id bson.ObjectId, reason string) (*AuditVerification, error) {
+		result.Broken = &AuditBreak{Seq: seq, Reason: reason}
+		if id != "" {
+			result.Broken.ID = id.Hex()
+		}
+		log.Warn("Audit chain is broken at entry %d: %s", seq, reason)
+		return result, nil
+	}
+
+	prev := ""
+	iter := auditCollection(c).Find(bson.M{"seq": bson.M{"$gt": 0}}).Sort("seq").Iter()
+	entry := AuditEntry{}
+	for iter.Next(&entry) {
+		expected := result.Entries + 1
+		if entry.Seq != expected {
+			iter.Close()
+			return broken(expected, "", "Entry "+strconv.FormatInt(expected, 10)+" is missing")
+		}
+		if entry.PrevHash != prev {
+			iter.Close()
+			return broken(entry.Seq, entry.ID, "Previous hash doesn't match the entry before")
+		}
+		if entry.chainHash() != entry.Hash {
+			iter.Close()
+			return broken(entry.Seq, entry.ID, "Hash doesn't match the entry's contents")
+		}
+
+		for len(checkpoints) > 0 && checkpoints[0].Seq == entry.Seq {
+			checkpoint := checkpoints[0]
+			if auditCheckpointKey != "" && !hmac.Equal([]byte(checkpoint.sign()), []byte(checkpoint.Signature)) {
+				iter.Close()
+				return broken(entry.Seq, entry.ID, "Checkpoint "+checkpoint.ID.Hex()+" has an invalid signature")
+			}
+			if checkpoint.Hash != entry.Hash {
+				iter.Close()
+				return broken(entry.Seq, entry.ID, "Hash doesn't match checkpoint "+checkpoint.ID.Hex())
+			}
+			result.Checkpoints++
+			checkpoints = checkpoints[1:]
+		}
+
+		prev = entry.Hash
+		result.Entries++
+		entry = AuditEntry{}
+	}
+	err = iter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries removed from the end of the log still leave the head and checkpoints past it
+	head, err := getAuditHead(c)
+	if err != nil {
+		return nil, err
+	}
+	if head.Seq > result.Entries {
+		return broken(result.Entries+1, "", "Entries after "+strconv.FormatInt(result.Entries, 10)+" are missing")
+	}
+	if len(checkpoints) > 0 {
+		return broken(checkpoints[0].Seq, "", "Checkpoint "+checkpoints[0].ID.Hex()+" is past the end of the log")
+	}
+
+	result.Valid = true
+	return result, nil
+}
+
+// This is a URL handler that verifies the audit chain
+func verifyAuditHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+
+	result, err := verifyAudit(c)
+	if err != nil {
+		log.Error("An error occurred verifying audit log: %s", err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred verifying audit log")
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred verifying audit log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// Verifies the audit chain from the command line, printing the result and exiting with
+// status 1 when it's broken
+func verifyAuditCommand() {
+	session, _, c, err := getMongo()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error connecting to database:", err)
+		os.Exit(2)
+	}
+	defer session.Close()
+
+	result, err := verifyAudit(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error verifying audit log:", err)
+		os.Exit(2)
+	}
+
+	data, _ := json.MarshalIndent(result, "", "    ")
+	fmt.Println(string(data))
+	if !result.Valid {
+		session.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"crypto/sha256"
+	"crypto/subtle"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"labix.org/v2/mgo"
+	"net/http"
+	"time"
+)
+
+// When true, every request other than public ones must authenticate
+var authEnabled = false
+
+// A key from the config that may use every route, for creating the first API keys
+var bootstrapKey = ""
+
+// Reads the authentication settings from the "auth" section of the config
+func configureAuth() {
+	auth, ok := Application.Config["auth"].(map[string]interface{})
+	if !ok {
+		log.Info("No auth settings in config, authentication is disabled")
+		return
+	}
+
+	if enabled, ok := auth["enabled"].(bool); ok {
+		authEnabled = enabled
+	}
+	if key, ok := auth["bootstrap_key"].(string); ok {
+		bootstrapKey = key
+	}
+	if service, ok := auth["admin_service"].(string); ok {
+		adminService = service
+	}
+	if seconds, ok := auth["signature_window_seconds"].(float64); ok && seconds > 0 {
+		signatureWindow = time.Duration(seconds) * time.Second
+	}
+	if bytes, ok := auth["max_signed_stream_bytes"].(float64); ok && bytes > 0 {
+		maxSignedStreamBytes = int64(bytes)
+	}
+	if key, ok := auth["signing_encryption_key"].(string); ok && key != "" {
+		sum := sha256.Sum256([]byte(key))
+		signingEncryptionKey = sum[:]
+	}
+
+	configureJWT(auth)
+	configureClientCerts(auth)
+
+	log.Info("Using auth settings enabled: %t, bootstrap key set: %t, admin service: '%s', signature window: %s, "+
+		"signing enabled: %t", authEnabled, bootstrapKey != "", adminService, signatureWindow, len(signingEncryptionKey) > 0)
+}
+
+// The operations a caller may be allowed.  Each includes the ones before it, so a caller
+// allowed to write may also read.
+const (
+	OperationRead  = "read"
+	OperationWrite = "write"
+	OperationAdmin = "admin"
+)
+
+// Routes anyone may use, even with authentication enabled
+const operationPublic = "public"
+
+// The rank of each operation, for checking one includes another
+var operationRank = map[string]int{OperationRead: 1, OperationWrite: 2, OperationAdmin: 3}
+
+// The operation each named route performs.  Routes missing from here need admin access.
+var routeOperations = map[string]string{
+	"OpenAPI":               operationPublic,
+	"ListServices":          OperationRead,
+	"ListObjects":           OperationRead,
+	"GrantACL":              OperationWrite,
+	"DenyACL":               OperationWrite,
+	"RevokeACL":             OperationWrite,
+	"SetACL":                OperationWrite,
+	"HasACL":                OperationRead,
+	"GetACL":                OperationRead,
+	"ListACL":               OperationRead,
+	"MatchACL":              OperationRead,
+	"WatchACL":              OperationRead,
+	"CopyACL":               OperationWrite,
+	"MoveACL":               OperationWrite,
+	"CloneACL":              OperationWrite,
+	"HistoryACL":            OperationRead,
+	"RestoreACL":            OperationWrite,
+	"ImportACL":             OperationWrite,
+	"UserACL":               OperationRead,
+	"CreateWebhook":         OperationAdmin,
+	"ListWebhooks":          OperationAdmin,
+	"DeleteWebhook":         OperationAdmin,
+	"ListWebhookDeliveries": OperationAdmin,
+	"RetryWebhookDelivery":  OperationAdmin,
+	"CreateAPIKey":          OperationAdmin,
+	"ListAPIKeys":           OperationAdmin,
+	"RotateAPIKey":          OperationAdmin,
+	"RevokeAPIKey":          OperationAdmin,
+	"AuditLog":              OperationAdmin,
+	"VerifyAudit":           OperationAdmin,
+	"ExportSnapshot":        OperationAdmin,
+	"RestoreSnapshot":       OperationAdmin,
+}
+
+// The routes across services that take the service to report on as a parameter
+var serviceParamRoutes = map[string]bool{"UserACL": true, "AuditLog": true, "ImportACL": true,
+	"ExportSnapshot": true, "RestoreSnapshot": true}
+
+// The prefixes of caller IDs, naming where the identity comes from, so an API key, a JWT
+// subject and a client certificate name can never be taken for one another
+const (
+	callerKeyPrefix  = "key:"
+	callerJWTPrefix  = "jwt:"
+	callerCertPrefix = "cert:"
+)
+
+/*
+The authenticated identity making a request.  ID is prefixed with where the identity comes
+from, and is the user the admin service, audit log and idempotency keys know the caller by.
+Services are the services the caller may use, with "*" meaning all of them, and Scopes the
+operations it may perform on them.  Method is how the caller authenticated.
+*/
+type Caller struct {
+	ID       string   `json:"id"`
+	Method   string   `json:"method"`
+	Services []string `json:"services"`
+	Scopes   []string `json:"scopes"`
+}
+
+// Checks if the caller may perform the operation on the service.  An empty service is a
+// request spanning every service, which needs access to all of them.
+func (c *Caller) allows(operation string, service string) bool {
+	serviceAllowed := false
+	for _, allowed := range c.Services {
+		if allowed == "*" || (service != "" && allowed == service) {
+			serviceAllowed = true
+			break
+		}
+	}
+	if !serviceAllowed {
+		return false
+	}
+
+	for _, scope := range c.Scopes {
+		if operationRank[scope] >= operationRank[operation] {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Finds the caller from the credentials a request carries.  An authenticator returns nil and
+no error when the request doesn't carry its kind of credentials, so the next one can try.
+*/
+type authenticator func(r *http.Request, c *mgo.Collection) (*Caller, *APIError)
+
+// The authenticators tried in order for each request
+var authenticators = []authenticator{
+	authenticateBootstrapKey,
+	authenticateAPIKey,
+	authenticateSignature,
+	authenticateJWT,
+	authenticateClientCert,
+}
+
+// Authenticates with the bootstrap key from the config
+func authenticateBootstrapKey(r *http.Request, c *mgo.Collection) (*Caller, *APIError) {
+	key := requestAPIKey(r)
+	if bootstrapKey == "" || key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(bootstrapKey)) != 1 {
+		return nil, nil
+	}
+	return &Caller{ID: "bootstrap", Method: "bootstrap_key", Services: []string{"*"}, Scopes: []string{OperationAdmin}}, nil
+}
+
+// Finds the caller of the request with the first authenticator that recognises its credentials
+func authenticate(r *http.Request, c *mgo.Collection) (*Caller, *APIError) {
+	for _, auth := range authenticators {
+		caller, apiErr := auth(r, c)
+		if apiErr != nil || caller != nil {
+			return caller, apiErr
+		}
+	}
+	return nil, &APIError{Code: ErrCodeUnauthenticated, Message: "Request has no credentials"}
+}
+
+// Gets the caller of an authenticated request.  When authentication is disabled it's the
+// caller of a verified client certificate, which is recorded but not limited, or nil.
+func requestCaller(r *http.Request) *Caller {
+	caller, _ := context.Get(r, "caller").(*Caller)
+	return caller
+}
+
+// The statuses of authentication errors that aren't about the credentials
+var authErrorStatus = map[string]int{
+	ErrCodeUnreadableBody: 400,
+	ErrCodeBodyTooLarge:   413,
+	ErrCodeStorage:        500,
+	ErrCodeInternal:       500,
+}
+
+// Authenticates the request and checks the caller may use the route it matches.  Returns
+// false if the response was already written because the request isn't allowed.
+func authorize(w http.ResponseWriter, r *http.Request, c *mgo.Collection) bool {
+	if !authEnabled {
+		if caller := clientCertCaller(r); caller != nil {
+			context.Set(r, "caller", caller)
+		}
+		return true
+	}
+
+	match := mux.RouteMatch{}
+	if !Application.Router.Match(r, &match) || match.Route == nil {
+		// The router responds that nothing matched
+		return true
+	}
+
+	operation, ok := routeOperations[match.Route.GetName()]
+	if !ok {
+		operation = OperationAdmin
+	}
+	if operation == operationPublic {
+		return true
+	}
+
+	caller, apiErr := authenticate(r, c)
+	if apiErr != nil {
+		status, ok := authErrorStatus[apiErr.Code]
+		if !ok {
+			log.Debug("Unauthenticated request to %s: %s", r.URL.RequestURI(), apiErr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="authorizer"`)
+			status = 401
+		}
+		apiErr.Write(w, status)
+		return false
+	}
+
+	// Reports across services may be limited to one with the service parameter
+	service := match.Vars["service"]
+	if serviceParamRoutes[match.Route.GetName()] {
+		service = r.URL.Query().Get("service")
+	}
+	if !caller.allows(operation, service) {
+		log.Debug("Caller %s may not %s service '%s'", caller.ID, operation, service)
+		writeError(w, 403, ErrCodeForbidden, "Caller may not "+operation+" this service")
+		return false
+	}
+
+	apiErr = authorizeAdmin(c, caller, match.Route.GetName(), service)
+	if apiErr != nil && apiErr.Code == ErrCodeStorage {
+		apiErr.Write(w, 500)
+		return false
+	} else if apiErr != nil {
+		apiErr.Write(w, 403)
+		return false
+	}
+
+	context.Set(r, "caller", caller)
+	return true
+}
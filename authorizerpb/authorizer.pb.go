@@ -0,0 +1,2630 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: authorizer.proto
+
+package authorizerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListServicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListServicesRequest) Reset() {
+	*x = ListServicesRequest{}
+	mi := &file_authorizer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListServicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListServicesRequest) ProtoMessage() {}
+
+func (x *ListServicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListServicesRequest.ProtoReflect.Descriptor instead.
+func (*ListServicesRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{0}
+}
+
+type ListServicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Services      []string               `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListServicesResponse) Reset() {
+	*x = ListServicesResponse{}
+	mi := &file_authorizer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListServicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListServicesResponse) ProtoMessage() {}
+
+func (x *ListServicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListServicesResponse.ProtoReflect.Descriptor instead.
+func (*ListServicesResponse) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListServicesResponse) GetServices() []string {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+type ListObjectsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListObjectsRequest) Reset() {
+	*x = ListObjectsRequest{}
+	mi := &file_authorizer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListObjectsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListObjectsRequest) ProtoMessage() {}
+
+func (x *ListObjectsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListObjectsRequest.ProtoReflect.Descriptor instead.
+func (*ListObjectsRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListObjectsRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+type ListObjectsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Objects       []string               `protobuf:"bytes,1,rep,name=objects,proto3" json:"objects,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListObjectsResponse) Reset() {
+	*x = ListObjectsResponse{}
+	mi := &file_authorizer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListObjectsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListObjectsResponse) ProtoMessage() {}
+
+func (x *ListObjectsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListObjectsResponse.ProtoReflect.Descriptor instead.
+func (*ListObjectsResponse) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListObjectsResponse) GetObjects() []string {
+	if x != nil {
+		return x.Objects
+	}
+	return nil
+}
+
+type PrivilegeItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	User          string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	Privileges    []string               `protobuf:"bytes,3,rep,name=privileges,proto3" json:"privileges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrivilegeItem) Reset() {
+	*x = PrivilegeItem{}
+	mi := &file_authorizer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrivilegeItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrivilegeItem) ProtoMessage() {}
+
+func (x *PrivilegeItem) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrivilegeItem.ProtoReflect.Descriptor instead.
+func (*PrivilegeItem) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PrivilegeItem) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *PrivilegeItem) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *PrivilegeItem) GetPrivileges() []string {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+type PrivilegesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Items         []*PrivilegeItem       `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrivilegesRequest) Reset() {
+	*x = PrivilegesRequest{}
+	mi := &file_authorizer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrivilegesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrivilegesRequest) ProtoMessage() {}
+
+func (x *PrivilegesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrivilegesRequest.ProtoReflect.Descriptor instead.
+func (*PrivilegesRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PrivilegesRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *PrivilegesRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *PrivilegesRequest) GetItems() []*PrivilegeItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type SetItem struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	User  string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	// Privilege names mapped to "allow" or "deny"
+	Privileges    map[string]string `protobuf:"bytes,3,rep,name=privileges,proto3" json:"privileges,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetItem) Reset() {
+	*x = SetItem{}
+	mi := &file_authorizer_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetItem) ProtoMessage() {}
+
+func (x *SetItem) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetItem.ProtoReflect.Descriptor instead.
+func (*SetItem) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SetItem) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetItem) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *SetItem) GetPrivileges() map[string]string {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+type SetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Items         []*SetItem             `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRequest) Reset() {
+	*x = SetRequest{}
+	mi := &file_authorizer_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRequest) ProtoMessage() {}
+
+func (x *SetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRequest.ProtoReflect.Descriptor instead.
+func (*SetRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SetRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *SetRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *SetRequest) GetItems() []*SetItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type WriteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteResponse) Reset() {
+	*x = WriteResponse{}
+	mi := &file_authorizer_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteResponse) ProtoMessage() {}
+
+func (x *WriteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteResponse.ProtoReflect.Descriptor instead.
+func (*WriteResponse) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{8}
+}
+
+type CheckRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Service string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object  string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Items   []*PrivilegeItem       `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	// Explain how each decision was reached
+	Explain       bool `protobuf:"varint,4,opt,name=explain,proto3" json:"explain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	mi := &file_authorizer_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CheckRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetItems() []*PrivilegeItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetExplain() bool {
+	if x != nil {
+		return x.Explain
+	}
+	return false
+}
+
+type ExplanationSource struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	User          string                 `protobuf:"bytes,4,opt,name=user,proto3" json:"user,omitempty"`
+	Found         bool                   `protobuf:"varint,5,opt,name=found,proto3" json:"found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplanationSource) Reset() {
+	*x = ExplanationSource{}
+	mi := &file_authorizer_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplanationSource) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplanationSource) ProtoMessage() {}
+
+func (x *ExplanationSource) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplanationSource.ProtoReflect.Descriptor instead.
+func (*ExplanationSource) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ExplanationSource) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ExplanationSource) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *ExplanationSource) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ExplanationSource) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ExplanationSource) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+type PrivilegeExplanation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Privilege     string                 `protobuf:"bytes,1,opt,name=privilege,proto3" json:"privilege,omitempty"`
+	Decision      string                 `protobuf:"bytes,2,opt,name=decision,proto3" json:"decision,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Value         string                 `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrivilegeExplanation) Reset() {
+	*x = PrivilegeExplanation{}
+	mi := &file_authorizer_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrivilegeExplanation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrivilegeExplanation) ProtoMessage() {}
+
+func (x *PrivilegeExplanation) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrivilegeExplanation.ProtoReflect.Descriptor instead.
+func (*PrivilegeExplanation) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PrivilegeExplanation) GetPrivilege() string {
+	if x != nil {
+		return x.Privilege
+	}
+	return ""
+}
+
+func (x *PrivilegeExplanation) GetDecision() string {
+	if x != nil {
+		return x.Decision
+	}
+	return ""
+}
+
+func (x *PrivilegeExplanation) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *PrivilegeExplanation) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type Explanation struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Decision      string                  `protobuf:"bytes,1,opt,name=decision,proto3" json:"decision,omitempty"`
+	Reason        string                  `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Sources       []*ExplanationSource    `protobuf:"bytes,3,rep,name=sources,proto3" json:"sources,omitempty"`
+	Privileges    []*PrivilegeExplanation `protobuf:"bytes,4,rep,name=privileges,proto3" json:"privileges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Explanation) Reset() {
+	*x = Explanation{}
+	mi := &file_authorizer_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Explanation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Explanation) ProtoMessage() {}
+
+func (x *Explanation) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Explanation.ProtoReflect.Descriptor instead.
+func (*Explanation) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Explanation) GetDecision() string {
+	if x != nil {
+		return x.Decision
+	}
+	return ""
+}
+
+func (x *Explanation) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Explanation) GetSources() []*ExplanationSource {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *Explanation) GetPrivileges() []*PrivilegeExplanation {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+type Decision struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	User  string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	// "allow" or "deny"
+	Privilege     string       `protobuf:"bytes,3,opt,name=privilege,proto3" json:"privilege,omitempty"`
+	Explanation   *Explanation `protobuf:"bytes,4,opt,name=explanation,proto3" json:"explanation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Decision) Reset() {
+	*x = Decision{}
+	mi := &file_authorizer_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Decision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Decision) ProtoMessage() {}
+
+func (x *Decision) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Decision.ProtoReflect.Descriptor instead.
+func (*Decision) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Decision) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Decision) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *Decision) GetPrivilege() string {
+	if x != nil {
+		return x.Privilege
+	}
+	return ""
+}
+
+func (x *Decision) GetExplanation() *Explanation {
+	if x != nil {
+		return x.Explanation
+	}
+	return nil
+}
+
+type CheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Decisions     []*Decision            `protobuf:"bytes,1,rep,name=decisions,proto3" json:"decisions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckResponse) Reset() {
+	*x = CheckResponse{}
+	mi := &file_authorizer_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResponse) ProtoMessage() {}
+
+func (x *CheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResponse.ProtoReflect.Descriptor instead.
+func (*CheckResponse) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CheckResponse) GetDecisions() []*Decision {
+	if x != nil {
+		return x.Decisions
+	}
+	return nil
+}
+
+type KeyUser struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	User          string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyUser) Reset() {
+	*x = KeyUser{}
+	mi := &file_authorizer_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyUser) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyUser) ProtoMessage() {}
+
+func (x *KeyUser) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyUser.ProtoReflect.Descriptor instead.
+func (*KeyUser) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *KeyUser) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KeyUser) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+type GetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Items         []*KeyUser             `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_authorizer_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *GetRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *GetRequest) GetItems() []*KeyUser {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type Privileges struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	User          string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	Privileges    map[string]string      `protobuf:"bytes,3,rep,name=privileges,proto3" json:"privileges,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Privileges) Reset() {
+	*x = Privileges{}
+	mi := &file_authorizer_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Privileges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Privileges) ProtoMessage() {}
+
+func (x *Privileges) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Privileges.ProtoReflect.Descriptor instead.
+func (*Privileges) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Privileges) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Privileges) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *Privileges) GetPrivileges() map[string]string {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+type GetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Privileges    []*Privileges          `protobuf:"bytes,1,rep,name=privileges,proto3" json:"privileges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	mi := &file_authorizer_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetResponse) GetPrivileges() []*Privileges {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+type ACL struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	User          string                 `protobuf:"bytes,4,opt,name=user,proto3" json:"user,omitempty"`
+	Privileges    map[string]string      `protobuf:"bytes,5,rep,name=privileges,proto3" json:"privileges,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ACL) Reset() {
+	*x = ACL{}
+	mi := &file_authorizer_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ACL) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ACL) ProtoMessage() {}
+
+func (x *ACL) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ACL.ProtoReflect.Descriptor instead.
+func (*ACL) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ACL) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ACL) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *ACL) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ACL) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ACL) GetPrivileges() map[string]string {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+type ListRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Service        string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object         string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	User           string                 `protobuf:"bytes,4,opt,name=user,proto3" json:"user,omitempty"`
+	Privileges     []string               `protobuf:"bytes,5,rep,name=privileges,proto3" json:"privileges,omitempty"`
+	PrivilegeValue string                 `protobuf:"bytes,6,opt,name=privilege_value,json=privilegeValue,proto3" json:"privilege_value,omitempty"`
+	Limit          int32                  `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	Cursor         string                 `protobuf:"bytes,8,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_authorizer_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ListRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ListRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *ListRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ListRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ListRequest) GetPrivileges() []string {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+func (x *ListRequest) GetPrivilegeValue() string {
+	if x != nil {
+		return x.PrivilegeValue
+	}
+	return ""
+}
+
+func (x *ListRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type ListResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Acls  []*ACL                 `protobuf:"bytes,1,rep,name=acls,proto3" json:"acls,omitempty"`
+	// Cursor of the next page, empty on the last page or without a limit
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_authorizer_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListResponse) GetAcls() []*ACL {
+	if x != nil {
+		return x.Acls
+	}
+	return nil
+}
+
+func (x *ListResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type MatchItem struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	User       string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Privileges []string               `protobuf:"bytes,2,rep,name=privileges,proto3" json:"privileges,omitempty"`
+	// Privilege value to match, defaults to "allow"
+	Value         string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Limit         int32  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	Cursor        string `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MatchItem) Reset() {
+	*x = MatchItem{}
+	mi := &file_authorizer_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MatchItem) ProtoMessage() {}
+
+func (x *MatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MatchItem.ProtoReflect.Descriptor instead.
+func (*MatchItem) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *MatchItem) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *MatchItem) GetPrivileges() []string {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+func (x *MatchItem) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *MatchItem) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *MatchItem) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type MatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Items         []*MatchItem           `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MatchRequest) Reset() {
+	*x = MatchRequest{}
+	mi := &file_authorizer_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MatchRequest) ProtoMessage() {}
+
+func (x *MatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MatchRequest.ProtoReflect.Descriptor instead.
+func (*MatchRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *MatchRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *MatchRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *MatchRequest) GetItems() []*MatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// A matched key, or the cursor of the next page when a paged item's results continue
+type MatchResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MatchResult) Reset() {
+	*x = MatchResult{}
+	mi := &file_authorizer_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MatchResult) ProtoMessage() {}
+
+func (x *MatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MatchResult.ProtoReflect.Descriptor instead.
+func (*MatchResult) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *MatchResult) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *MatchResult) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *MatchResult) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type ListUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Service       string                 `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+	Privileges    []string               `protobuf:"bytes,4,rep,name=privileges,proto3" json:"privileges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserRequest) Reset() {
+	*x = ListUserRequest{}
+	mi := &file_authorizer_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserRequest) ProtoMessage() {}
+
+func (x *ListUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserRequest.ProtoReflect.Descriptor instead.
+func (*ListUserRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListUserRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ListUserRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ListUserRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *ListUserRequest) GetPrivileges() []string {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+type ListUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Acls          []*ACL                 `protobuf:"bytes,1,rep,name=acls,proto3" json:"acls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserResponse) Reset() {
+	*x = ListUserResponse{}
+	mi := &file_authorizer_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserResponse) ProtoMessage() {}
+
+func (x *ListUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserResponse.ProtoReflect.Descriptor instead.
+func (*ListUserResponse) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ListUserResponse) GetAcls() []*ACL {
+	if x != nil {
+		return x.Acls
+	}
+	return nil
+}
+
+type CopyItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromKey       string                 `protobuf:"bytes,1,opt,name=from_key,json=fromKey,proto3" json:"from_key,omitempty"`
+	ToKey         string                 `protobuf:"bytes,2,opt,name=to_key,json=toKey,proto3" json:"to_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyItem) Reset() {
+	*x = CopyItem{}
+	mi := &file_authorizer_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyItem) ProtoMessage() {}
+
+func (x *CopyItem) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyItem.ProtoReflect.Descriptor instead.
+func (*CopyItem) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *CopyItem) GetFromKey() string {
+	if x != nil {
+		return x.FromKey
+	}
+	return ""
+}
+
+func (x *CopyItem) GetToKey() string {
+	if x != nil {
+		return x.ToKey
+	}
+	return ""
+}
+
+type CopyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Items         []*CopyItem            `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	DryRun        bool                   `protobuf:"varint,4,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyRequest) Reset() {
+	*x = CopyRequest{}
+	mi := &file_authorizer_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyRequest) ProtoMessage() {}
+
+func (x *CopyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyRequest.ProtoReflect.Descriptor instead.
+func (*CopyRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *CopyRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *CopyRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *CopyRequest) GetItems() []*CopyItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *CopyRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type CloneItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromUser      string                 `protobuf:"bytes,1,opt,name=from_user,json=fromUser,proto3" json:"from_user,omitempty"`
+	ToUser        string                 `protobuf:"bytes,2,opt,name=to_user,json=toUser,proto3" json:"to_user,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloneItem) Reset() {
+	*x = CloneItem{}
+	mi := &file_authorizer_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloneItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloneItem) ProtoMessage() {}
+
+func (x *CloneItem) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloneItem.ProtoReflect.Descriptor instead.
+func (*CloneItem) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *CloneItem) GetFromUser() string {
+	if x != nil {
+		return x.FromUser
+	}
+	return ""
+}
+
+func (x *CloneItem) GetToUser() string {
+	if x != nil {
+		return x.ToUser
+	}
+	return ""
+}
+
+func (x *CloneItem) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type CloneRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Items         []*CloneItem           `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	DryRun        bool                   `protobuf:"varint,4,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloneRequest) Reset() {
+	*x = CloneRequest{}
+	mi := &file_authorizer_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloneRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloneRequest) ProtoMessage() {}
+
+func (x *CloneRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloneRequest.ProtoReflect.Descriptor instead.
+func (*CloneRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *CloneRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *CloneRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *CloneRequest) GetItems() []*CloneItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *CloneRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type ACLChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	User  string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	// "set" or "delete"
+	Action string            `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	Before map[string]string `protobuf:"bytes,4,rep,name=before,proto3" json:"before,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	After  map[string]string `protobuf:"bytes,5,rep,name=after,proto3" json:"after,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Whether the ACL existed before the change
+	Existed       bool `protobuf:"varint,6,opt,name=existed,proto3" json:"existed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ACLChange) Reset() {
+	*x = ACLChange{}
+	mi := &file_authorizer_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ACLChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ACLChange) ProtoMessage() {}
+
+func (x *ACLChange) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ACLChange.ProtoReflect.Descriptor instead.
+func (*ACLChange) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ACLChange) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ACLChange) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ACLChange) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ACLChange) GetBefore() map[string]string {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *ACLChange) GetAfter() map[string]string {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+func (x *ACLChange) GetExisted() bool {
+	if x != nil {
+		return x.Existed
+	}
+	return false
+}
+
+type ChangeResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromKey       string                 `protobuf:"bytes,1,opt,name=from_key,json=fromKey,proto3" json:"from_key,omitempty"`
+	ToKey         string                 `protobuf:"bytes,2,opt,name=to_key,json=toKey,proto3" json:"to_key,omitempty"`
+	FromUser      string                 `protobuf:"bytes,3,opt,name=from_user,json=fromUser,proto3" json:"from_user,omitempty"`
+	ToUser        string                 `protobuf:"bytes,4,opt,name=to_user,json=toUser,proto3" json:"to_user,omitempty"`
+	Key           string                 `protobuf:"bytes,5,opt,name=key,proto3" json:"key,omitempty"`
+	DryRun        bool                   `protobuf:"varint,6,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	Changes       []*ACLChange           `protobuf:"bytes,7,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeResult) Reset() {
+	*x = ChangeResult{}
+	mi := &file_authorizer_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeResult) ProtoMessage() {}
+
+func (x *ChangeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeResult.ProtoReflect.Descriptor instead.
+func (*ChangeResult) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ChangeResult) GetFromKey() string {
+	if x != nil {
+		return x.FromKey
+	}
+	return ""
+}
+
+func (x *ChangeResult) GetToKey() string {
+	if x != nil {
+		return x.ToKey
+	}
+	return ""
+}
+
+func (x *ChangeResult) GetFromUser() string {
+	if x != nil {
+		return x.FromUser
+	}
+	return ""
+}
+
+func (x *ChangeResult) GetToUser() string {
+	if x != nil {
+		return x.ToUser
+	}
+	return ""
+}
+
+func (x *ChangeResult) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ChangeResult) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *ChangeResult) GetChanges() []*ACLChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type ChangesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*ChangeResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangesResponse) Reset() {
+	*x = ChangesResponse{}
+	mi := &file_authorizer_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangesResponse) ProtoMessage() {}
+
+func (x *ChangesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangesResponse.ProtoReflect.Descriptor instead.
+func (*ChangesResponse) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ChangesResponse) GetResults() []*ChangeResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Service string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Object  string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Key     string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	// Sequence token to send the events after, defaults to now
+	Since         string `protobuf:"bytes,4,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_authorizer_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *WatchRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetSince() string {
+	if x != nil {
+		return x.Since
+	}
+	return ""
+}
+
+type ACLEvent struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Seq     int64                  `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Service string                 `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Object  string                 `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+	Key     string                 `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	User    string                 `protobuf:"bytes,5,opt,name=user,proto3" json:"user,omitempty"`
+	// "grant", "deny", "revoke", "set" or "delete"
+	Action string `protobuf:"bytes,6,opt,name=action,proto3" json:"action,omitempty"`
+	// Privilege names of a grant, deny or revoke
+	PrivilegeNames []string `protobuf:"bytes,7,rep,name=privilege_names,json=privilegeNames,proto3" json:"privilege_names,omitempty"`
+	// Privileges of a set
+	Privileges    map[string]string      `protobuf:"bytes,8,rep,name=privileges,proto3" json:"privileges,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ACLEvent) Reset() {
+	*x = ACLEvent{}
+	mi := &file_authorizer_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ACLEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ACLEvent) ProtoMessage() {}
+
+func (x *ACLEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_authorizer_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ACLEvent.ProtoReflect.Descriptor instead.
+func (*ACLEvent) Descriptor() ([]byte, []int) {
+	return file_authorizer_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ACLEvent) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ACLEvent) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ACLEvent) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *ACLEvent) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ACLEvent) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ACLEvent) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ACLEvent) GetPrivilegeNames() []string {
+	if x != nil {
+		return x.PrivilegeNames
+	}
+	return nil
+}
+
+func (x *ACLEvent) GetPrivileges() map[string]string {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+func (x *ACLEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+var File_authorizer_proto protoreflect.FileDescriptor
+
+const file_authorizer_proto_rawDesc = "" +
+	"\n" +
+	"\x10authorizer.proto\x12\rauthorizer.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x15\n" +
+	"\x13ListServicesRequest\"2\n" +
+	"\x14ListServicesResponse\x12\x1a\n" +
+	"\bservices\x18\x01 \x03(\tR\bservices\".\n" +
+	"\x12ListObjectsRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\"/\n" +
+	"\x13ListObjectsResponse\x12\x18\n" +
+	"\aobjects\x18\x01 \x03(\tR\aobjects\"U\n" +
+	"\rPrivilegeItem\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12\x1e\n" +
+	"\n" +
+	"privileges\x18\x03 \x03(\tR\n" +
+	"privileges\"y\n" +
+	"\x11PrivilegesRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x122\n" +
+	"\x05items\x18\x03 \x03(\v2\x1c.authorizer.v1.PrivilegeItemR\x05items\"\xb6\x01\n" +
+	"\aSetItem\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12F\n" +
+	"\n" +
+	"privileges\x18\x03 \x03(\v2&.authorizer.v1.SetItem.PrivilegesEntryR\n" +
+	"privileges\x1a=\n" +
+	"\x0fPrivilegesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"l\n" +
+	"\n" +
+	"SetRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12,\n" +
+	"\x05items\x18\x03 \x03(\v2\x16.authorizer.v1.SetItemR\x05items\"\x0f\n" +
+	"\rWriteResponse\"\x8e\x01\n" +
+	"\fCheckRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x122\n" +
+	"\x05items\x18\x03 \x03(\v2\x1c.authorizer.v1.PrivilegeItemR\x05items\x12\x18\n" +
+	"\aexplain\x18\x04 \x01(\bR\aexplain\"\x81\x01\n" +
+	"\x11ExplanationSource\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x04 \x01(\tR\x04user\x12\x14\n" +
+	"\x05found\x18\x05 \x01(\bR\x05found\"~\n" +
+	"\x14PrivilegeExplanation\x12\x1c\n" +
+	"\tprivilege\x18\x01 \x01(\tR\tprivilege\x12\x1a\n" +
+	"\bdecision\x18\x02 \x01(\tR\bdecision\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\"\xc2\x01\n" +
+	"\vExplanation\x12\x1a\n" +
+	"\bdecision\x18\x01 \x01(\tR\bdecision\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12:\n" +
+	"\asources\x18\x03 \x03(\v2 .authorizer.v1.ExplanationSourceR\asources\x12C\n" +
+	"\n" +
+	"privileges\x18\x04 \x03(\v2#.authorizer.v1.PrivilegeExplanationR\n" +
+	"privileges\"\x8c\x01\n" +
+	"\bDecision\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12\x1c\n" +
+	"\tprivilege\x18\x03 \x01(\tR\tprivilege\x12<\n" +
+	"\vexplanation\x18\x04 \x01(\v2\x1a.authorizer.v1.ExplanationR\vexplanation\"F\n" +
+	"\rCheckResponse\x125\n" +
+	"\tdecisions\x18\x01 \x03(\v2\x17.authorizer.v1.DecisionR\tdecisions\"/\n" +
+	"\aKeyUser\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\"l\n" +
+	"\n" +
+	"GetRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12,\n" +
+	"\x05items\x18\x03 \x03(\v2\x16.authorizer.v1.KeyUserR\x05items\"\xbc\x01\n" +
+	"\n" +
+	"Privileges\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12I\n" +
+	"\n" +
+	"privileges\x18\x03 \x03(\v2).authorizer.v1.Privileges.PrivilegesEntryR\n" +
+	"privileges\x1a=\n" +
+	"\x0fPrivilegesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"H\n" +
+	"\vGetResponse\x129\n" +
+	"\n" +
+	"privileges\x18\x01 \x03(\v2\x19.authorizer.v1.PrivilegesR\n" +
+	"privileges\"\xe0\x01\n" +
+	"\x03ACL\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x04 \x01(\tR\x04user\x12B\n" +
+	"\n" +
+	"privileges\x18\x05 \x03(\v2\".authorizer.v1.ACL.PrivilegesEntryR\n" +
+	"privileges\x1a=\n" +
+	"\x0fPrivilegesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xdc\x01\n" +
+	"\vListRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x04 \x01(\tR\x04user\x12\x1e\n" +
+	"\n" +
+	"privileges\x18\x05 \x03(\tR\n" +
+	"privileges\x12'\n" +
+	"\x0fprivilege_value\x18\x06 \x01(\tR\x0eprivilegeValue\x12\x14\n" +
+	"\x05limit\x18\a \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\b \x01(\tR\x06cursor\"W\n" +
+	"\fListResponse\x12&\n" +
+	"\x04acls\x18\x01 \x03(\v2\x12.authorizer.v1.ACLR\x04acls\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"\x83\x01\n" +
+	"\tMatchItem\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x1e\n" +
+	"\n" +
+	"privileges\x18\x02 \x03(\tR\n" +
+	"privileges\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\x05 \x01(\tR\x06cursor\"p\n" +
+	"\fMatchRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12.\n" +
+	"\x05items\x18\x03 \x03(\v2\x18.authorizer.v1.MatchItemR\x05items\"T\n" +
+	"\vMatchResult\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x1f\n" +
+	"\vnext_cursor\x18\x03 \x01(\tR\n" +
+	"nextCursor\"w\n" +
+	"\x0fListUserRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x18\n" +
+	"\aservice\x18\x02 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x03 \x01(\tR\x06object\x12\x1e\n" +
+	"\n" +
+	"privileges\x18\x04 \x03(\tR\n" +
+	"privileges\":\n" +
+	"\x10ListUserResponse\x12&\n" +
+	"\x04acls\x18\x01 \x03(\v2\x12.authorizer.v1.ACLR\x04acls\"<\n" +
+	"\bCopyItem\x12\x19\n" +
+	"\bfrom_key\x18\x01 \x01(\tR\afromKey\x12\x15\n" +
+	"\x06to_key\x18\x02 \x01(\tR\x05toKey\"\x87\x01\n" +
+	"\vCopyRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12-\n" +
+	"\x05items\x18\x03 \x03(\v2\x17.authorizer.v1.CopyItemR\x05items\x12\x17\n" +
+	"\adry_run\x18\x04 \x01(\bR\x06dryRun\"S\n" +
+	"\tCloneItem\x12\x1b\n" +
+	"\tfrom_user\x18\x01 \x01(\tR\bfromUser\x12\x17\n" +
+	"\ato_user\x18\x02 \x01(\tR\x06toUser\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\"\x89\x01\n" +
+	"\fCloneRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12.\n" +
+	"\x05items\x18\x03 \x03(\v2\x18.authorizer.v1.CloneItemR\x05items\x12\x17\n" +
+	"\adry_run\x18\x04 \x01(\bR\x06dryRun\"\xd1\x02\n" +
+	"\tACLChange\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12<\n" +
+	"\x06before\x18\x04 \x03(\v2$.authorizer.v1.ACLChange.BeforeEntryR\x06before\x129\n" +
+	"\x05after\x18\x05 \x03(\v2#.authorizer.v1.ACLChange.AfterEntryR\x05after\x12\x18\n" +
+	"\aexisted\x18\x06 \x01(\bR\aexisted\x1a9\n" +
+	"\vBeforeEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a8\n" +
+	"\n" +
+	"AfterEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd5\x01\n" +
+	"\fChangeResult\x12\x19\n" +
+	"\bfrom_key\x18\x01 \x01(\tR\afromKey\x12\x15\n" +
+	"\x06to_key\x18\x02 \x01(\tR\x05toKey\x12\x1b\n" +
+	"\tfrom_user\x18\x03 \x01(\tR\bfromUser\x12\x17\n" +
+	"\ato_user\x18\x04 \x01(\tR\x06toUser\x12\x10\n" +
+	"\x03key\x18\x05 \x01(\tR\x03key\x12\x17\n" +
+	"\adry_run\x18\x06 \x01(\bR\x06dryRun\x122\n" +
+	"\achanges\x18\a \x03(\v2\x18.authorizer.v1.ACLChangeR\achanges\"H\n" +
+	"\x0fChangesResponse\x125\n" +
+	"\aresults\x18\x01 \x03(\v2\x1b.authorizer.v1.ChangeResultR\aresults\"h\n" +
+	"\fWatchRequest\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05since\x18\x04 \x01(\tR\x05since\"\xed\x02\n" +
+	"\bACLEvent\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x03R\x03seq\x12\x18\n" +
+	"\aservice\x18\x02 \x01(\tR\aservice\x12\x16\n" +
+	"\x06object\x18\x03 \x01(\tR\x06object\x12\x10\n" +
+	"\x03key\x18\x04 \x01(\tR\x03key\x12\x12\n" +
+	"\x04user\x18\x05 \x01(\tR\x04user\x12\x16\n" +
+	"\x06action\x18\x06 \x01(\tR\x06action\x12'\n" +
+	"\x0fprivilege_names\x18\a \x03(\tR\x0eprivilegeNames\x12G\n" +
+	"\n" +
+	"privileges\x18\b \x03(\v2'.authorizer.v1.ACLEvent.PrivilegesEntryR\n" +
+	"privileges\x12.\n" +
+	"\x04time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x1a=\n" +
+	"\x0fPrivilegesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\xb9\b\n" +
+	"\n" +
+	"Authorizer\x12W\n" +
+	"\fListServices\x12\".authorizer.v1.ListServicesRequest\x1a#.authorizer.v1.ListServicesResponse\x12T\n" +
+	"\vListObjects\x12!.authorizer.v1.ListObjectsRequest\x1a\".authorizer.v1.ListObjectsResponse\x12G\n" +
+	"\x05Grant\x12 .authorizer.v1.PrivilegesRequest\x1a\x1c.authorizer.v1.WriteResponse\x12F\n" +
+	"\x04Deny\x12 .authorizer.v1.PrivilegesRequest\x1a\x1c.authorizer.v1.WriteResponse\x12H\n" +
+	"\x06Revoke\x12 .authorizer.v1.PrivilegesRequest\x1a\x1c.authorizer.v1.WriteResponse\x12>\n" +
+	"\x03Set\x12\x19.authorizer.v1.SetRequest\x1a\x1c.authorizer.v1.WriteResponse\x12B\n" +
+	"\x05Check\x12\x1b.authorizer.v1.CheckRequest\x1a\x1c.authorizer.v1.CheckResponse\x12<\n" +
+	"\x03Get\x12\x19.authorizer.v1.GetRequest\x1a\x1a.authorizer.v1.GetResponse\x12?\n" +
+	"\x04List\x12\x1a.authorizer.v1.ListRequest\x1a\x1b.authorizer.v1.ListResponse\x12B\n" +
+	"\x05Match\x12\x1b.authorizer.v1.MatchRequest\x1a\x1a.authorizer.v1.MatchResult0\x01\x12K\n" +
+	"\bListUser\x12\x1e.authorizer.v1.ListUserRequest\x1a\x1f.authorizer.v1.ListUserResponse\x12B\n" +
+	"\x04Copy\x12\x1a.authorizer.v1.CopyRequest\x1a\x1e.authorizer.v1.ChangesResponse\x12B\n" +
+	"\x04Move\x12\x1a.authorizer.v1.CopyRequest\x1a\x1e.authorizer.v1.ChangesResponse\x12D\n" +
+	"\x05Clone\x12\x1b.authorizer.v1.CloneRequest\x1a\x1e.authorizer.v1.ChangesResponse\x12?\n" +
+	"\x05Watch\x12\x1b.authorizer.v1.WatchRequest\x1a\x17.authorizer.v1.ACLEvent0\x01B4Z2github.com/johnnadratowski/authorizer/authorizerpbb\x06proto3"
+
+var (
+	file_authorizer_proto_rawDescOnce sync.Once
+	file_authorizer_proto_rawDescData []byte
+)
+
+func file_authorizer_proto_rawDescGZIP() []byte {
+	file_authorizer_proto_rawDescOnce.Do(func() {
+		file_authorizer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_authorizer_proto_rawDesc), len(file_authorizer_proto_rawDesc)))
+	})
+	return file_authorizer_proto_rawDescData
+}
+
+var file_authorizer_proto_msgTypes = make([]protoimpl.MessageInfo, 42)
+var file_authorizer_proto_goTypes = []any{
+	(*ListServicesRequest)(nil),   // 0: authorizer.v1.ListServicesRequest
+	(*ListServicesResponse)(nil),  // 1: authorizer.v1.ListServicesResponse
+	(*ListObjectsRequest)(nil),    // 2: authorizer.v1.ListObjectsRequest
+	(*ListObjectsResponse)(nil),   // 3: authorizer.v1.ListObjectsResponse
+	(*PrivilegeItem)(nil),         // 4: authorizer.v1.PrivilegeItem
+	(*PrivilegesRequest)(nil),     // 5: authorizer.v1.PrivilegesRequest
+	(*SetItem)(nil),               // 6: authorizer.v1.SetItem
+	(*SetRequest)(nil),            // 7: authorizer.v1.SetRequest
+	(*WriteResponse)(nil),         // 8: authorizer.v1.WriteResponse
+	(*CheckRequest)(nil),          // 9: authorizer.v1.CheckRequest
+	(*ExplanationSource)(nil),     // 10: authorizer.v1.ExplanationSource
+	(*PrivilegeExplanation)(nil),  // 11: authorizer.v1.PrivilegeExplanation
+	(*Explanation)(nil),           // 12: authorizer.v1.Explanation
+	(*Decision)(nil),              // 13: authorizer.v1.Decision
+	(*CheckResponse)(nil),         // 14: authorizer.v1.CheckResponse
+	(*KeyUser)(nil),               // 15: authorizer.v1.KeyUser
+	(*GetRequest)(nil),            // 16: authorizer.v1.GetRequest
+	(*Privileges)(nil),            // 17: authorizer.v1.Privileges
+	(*GetResponse)(nil),           // 18: authorizer.v1.GetResponse
+	(*ACL)(nil),                   // 19: authorizer.v1.ACL
+	(*ListRequest)(nil),           // 20: authorizer.v1.ListRequest
+	(*ListResponse)(nil),          // 21: authorizer.v1.ListResponse
+	(*MatchItem)(nil),             // 22: authorizer.v1.MatchItem
+	(*MatchRequest)(nil),          // 23: authorizer.v1.MatchRequest
+	(*MatchResult)(nil),           // 24: authorizer.v1.MatchResult
+	(*ListUserRequest)(nil),       // 25: authorizer.v1.ListUserRequest
+	(*ListUserResponse)(nil),      // 26: authorizer.v1.ListUserResponse
+	(*CopyItem)(nil),              // 27: authorizer.v1.CopyItem
+	(*CopyRequest)(nil),           // 28: authorizer.v1.CopyRequest
+	(*CloneItem)(nil),             // 29: authorizer.v1.CloneItem
+	(*CloneRequest)(nil),          // 30: authorizer.v1.CloneRequest
+	(*ACLChange)(nil),             // 31: authorizer.v1.ACLChange
+	(*ChangeResult)(nil),          // 32: authorizer.v1.ChangeResult
+	(*ChangesResponse)(nil),       // 33: authorizer.v1.ChangesResponse
+	(*WatchRequest)(nil),          // 34: authorizer.v1.WatchRequest
+	(*ACLEvent)(nil),              // 35: authorizer.v1.ACLEvent
+	nil,                           // 36: authorizer.v1.SetItem.PrivilegesEntry
+	nil,                           // 37: authorizer.v1.Privileges.PrivilegesEntry
+	nil,                           // 38: authorizer.v1.ACL.PrivilegesEntry
+	nil,                           // 39: authorizer.v1.ACLChange.BeforeEntry
+	nil,                           // 40: authorizer.v1.ACLChange.AfterEntry
+	nil,                           // 41: authorizer.v1.ACLEvent.PrivilegesEntry
+	(*timestamppb.Timestamp)(nil), // 42: google.protobuf.Timestamp
+}
+var file_authorizer_proto_depIdxs = []int32{
+	4,  // 0: authorizer.v1.PrivilegesRequest.items:type_name -> authorizer.v1.PrivilegeItem
+	36, // 1: authorizer.v1.SetItem.privileges:type_name -> authorizer.v1.SetItem.PrivilegesEntry
+	6,  // 2: authorizer.v1.SetRequest.items:type_name -> authorizer.v1.SetItem
+	4,  // 3: authorizer.v1.CheckRequest.items:type_name -> authorizer.v1.PrivilegeItem
+	10, // 4: authorizer.v1.Explanation.sources:type_name -> authorizer.v1.ExplanationSource
+	11, // 5: authorizer.v1.Explanation.privileges:type_name -> authorizer.v1.PrivilegeExplanation
+	12, // 6: authorizer.v1.Decision.explanation:type_name -> authorizer.v1.Explanation
+	13, // 7: authorizer.v1.CheckResponse.decisions:type_name -> authorizer.v1.Decision
+	15, // 8: authorizer.v1.GetRequest.items:type_name -> authorizer.v1.KeyUser
+	37, // 9: authorizer.v1.Privileges.privileges:type_name -> authorizer.v1.Privileges.PrivilegesEntry
+	17, // 10: authorizer.v1.GetResponse.privileges:type_name -> authorizer.v1.Privileges
+	38, // 11: authorizer.v1.ACL.privileges:type_name -> authorizer.v1.ACL.PrivilegesEntry
+	19, // 12: authorizer.v1.ListResponse.acls:type_name -> authorizer.v1.ACL
+	22, // 13: authorizer.v1.MatchRequest.items:type_name -> authorizer.v1.MatchItem
+	19, // 14: authorizer.v1.ListUserResponse.acls:type_name -> authorizer.v1.ACL
+	27, // 15: authorizer.v1.CopyRequest.items:type_name -> authorizer.v1.CopyItem
+	29, // 16: authorizer.v1.CloneRequest.items:type_name -> authorizer.v1.CloneItem
+	39, // 17: authorizer.v1.ACLChange.before:type_name -> authorizer.v1.ACLChange.BeforeEntry
+	40, // 18: authorizer.v1.ACLChange.after:type_name -> authorizer.v1.ACLChange.AfterEntry
+	31, // 19: authorizer.v1.ChangeResult.changes:type_name -> authorizer.v1.ACLChange
+	32, // 20: authorizer.v1.ChangesResponse.results:type_name -> authorizer.v1.ChangeResult
+	41, // 21: authorizer.v1.ACLEvent.privileges:type_name -> authorizer.v1.ACLEvent.PrivilegesEntry
+	42, // 22: authorizer.v1.ACLEvent.time:type_name -> google.protobuf.Timestamp
+	0,  // 23: authorizer.v1.Authorizer.ListServices:input_type -> authorizer.v1.ListServicesRequest
+	2,  // 24: authorizer.v1.Authorizer.ListObjects:input_type -> authorizer.v1.ListObjectsRequest
+	5,  // 25: authorizer.v1.Authorizer.Grant:input_type -> authorizer.v1.PrivilegesRequest
+	5,  // 26: authorizer.v1.Authorizer.Deny:input_type -> authorizer.v1.PrivilegesRequest
+	5,  // 27: authorizer.v1.Authorizer.Revoke:input_type -> authorizer.v1.PrivilegesRequest
+	7,  // 28: authorizer.v1.Authorizer.Set:input_type -> authorizer.v1.SetRequest
+	9,  // 29: authorizer.v1.Authorizer.Check:input_type -> authorizer.v1.CheckRequest
+	16, // 30: authorizer.v1.Authorizer.Get:input_type -> authorizer.v1.GetRequest
+	20, // 31: authorizer.v1.Authorizer.List:input_type -> authorizer.v1.ListRequest
+	23, // 32: authorizer.v1.Authorizer.Match:input_type -> authorizer.v1.MatchRequest
+	25, // 33: authorizer.v1.Authorizer.ListUser:input_type -> authorizer.v1.ListUserRequest
+	28, // 34: authorizer.v1.Authorizer.Copy:input_type -> authorizer.v1.CopyRequest
+	28, // 35: authorizer.v1.Authorizer.Move:input_type -> authorizer.v1.CopyRequest
+	30, // 36: authorizer.v1.Authorizer.Clone:input_type -> authorizer.v1.CloneRequest
+	34, // 37: authorizer.v1.Authorizer.Watch:input_type -> authorizer.v1.WatchRequest
+	1,  // 38: authorizer.v1.Authorizer.ListServices:output_type -> authorizer.v1.ListServicesResponse
+	3,  // 39: authorizer.v1.Authorizer.ListObjects:output_type -> authorizer.v1.ListObjectsResponse
+	8,  // 40: authorizer.v1.Authorizer.Grant:output_type -> authorizer.v1.WriteResponse
+	8,  // 41: authorizer.v1.Authorizer.Deny:output_type -> authorizer.v1.WriteResponse
+	8,  // 42: authorizer.v1.Authorizer.Revoke:output_type -> authorizer.v1.WriteResponse
+	8,  // 43: authorizer.v1.Authorizer.Set:output_type -> authorizer.v1.WriteResponse
+	14, // 44: authorizer.v1.Authorizer.Check:output_type -> authorizer.v1.CheckResponse
+	18, // 45: authorizer.v1.Authorizer.Get:output_type -> authorizer.v1.GetResponse
+	21, // 46: authorizer.v1.Authorizer.List:output_type -> authorizer.v1.ListResponse
+	24, // 47: authorizer.v1.Authorizer.Match:output_type -> authorizer.v1.MatchResult
+	26, // 48: authorizer.v1.Authorizer.ListUser:output_type -> authorizer.v1.ListUserResponse
+	33, // 49: authorizer.v1.Authorizer.Copy:output_type -> authorizer.v1.ChangesResponse
+	33, // 50: authorizer.v1.Authorizer.Move:output_type -> authorizer.v1.ChangesResponse
+	33, // 51: authorizer.v1.Authorizer.Clone:output_type -> authorizer.v1.ChangesResponse
+	35, // 52: authorizer.v1.Authorizer.Watch:output_type -> authorizer.v1.ACLEvent
+	38, // [38:53] is the sub-list for method output_type
+	23, // [23:38] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
+}
+
+func init() { file_authorizer_proto_init() }
+func file_authorizer_proto_init() {
+	if File_authorizer_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_authorizer_proto_rawDesc), len(file_authorizer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   42,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_authorizer_proto_goTypes,
+		DependencyIndexes: file_authorizer_proto_depIdxs,
+		MessageInfos:      file_authorizer_proto_msgTypes,
+	}.Build()
+	File_authorizer_proto = out.File
+	file_authorizer_proto_goTypes = nil
+	file_authorizer_proto_depIdxs = nil
+}
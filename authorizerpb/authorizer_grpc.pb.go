@@ -0,0 +1,696 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: authorizer.proto
+
+package authorizerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Authorizer_ListServices_FullMethodName = "/authorizer.v1.Authorizer/ListServices"
+	Authorizer_ListObjects_FullMethodName  = "/authorizer.v1.Authorizer/ListObjects"
+	Authorizer_Grant_FullMethodName        = "/authorizer.v1.Authorizer/Grant"
+	Authorizer_Deny_FullMethodName         = "/authorizer.v1.Authorizer/Deny"
+	Authorizer_Revoke_FullMethodName       = "/authorizer.v1.Authorizer/Revoke"
+	Authorizer_Set_FullMethodName          = "/authorizer.v1.Authorizer/Set"
+	Authorizer_Check_FullMethodName        = "/authorizer.v1.Authorizer/Check"
+	Authorizer_Get_FullMethodName          = "/authorizer.v1.Authorizer/Get"
+	Authorizer_List_FullMethodName         = "/authorizer.v1.Authorizer/List"
+	Authorizer_Match_FullMethodName        = "/authorizer.v1.Authorizer/Match"
+	Authorizer_ListUser_FullMethodName     = "/authorizer.v1.Authorizer/ListUser"
+	Authorizer_Copy_FullMethodName         = "/authorizer.v1.Authorizer/Copy"
+	Authorizer_Move_FullMethodName         = "/authorizer.v1.Authorizer/Move"
+	Authorizer_Clone_FullMethodName        = "/authorizer.v1.Authorizer/Clone"
+	Authorizer_Watch_FullMethodName        = "/authorizer.v1.Authorizer/Watch"
+)
+
+// AuthorizerClient is the client API for Authorizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// The gRPC API of the authorizer.  It mirrors the REST API under /v1 and shares its
+// model layer, so every operation behaves the same way over either transport.
+type AuthorizerClient interface {
+	// Lists the services that have ACLs
+	ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (*ListServicesResponse, error)
+	// Lists the objects of a service that have ACLs
+	ListObjects(ctx context.Context, in *ListObjectsRequest, opts ...grpc.CallOption) (*ListObjectsResponse, error)
+	// Allows the privileges for the users on the keys
+	Grant(ctx context.Context, in *PrivilegesRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	// Denies the privileges for the users on the keys
+	Deny(ctx context.Context, in *PrivilegesRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	// Removes the privileges for the users on the keys
+	Revoke(ctx context.Context, in *PrivilegesRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	// Replaces the privileges for the users on the keys
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	// Checks the users are allowed all of the privileges on the keys, in one batch
+	Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error)
+	// Gets the privileges of the users on the keys
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Lists the ACLs of an object
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Streams the keys the users hold the privileges on
+	Match(ctx context.Context, in *MatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MatchResult], error)
+	// Lists every ACL of a user across services and objects
+	ListUser(ctx context.Context, in *ListUserRequest, opts ...grpc.CallOption) (*ListUserResponse, error)
+	// Copies every ACL on a key to another key
+	Copy(ctx context.Context, in *CopyRequest, opts ...grpc.CallOption) (*ChangesResponse, error)
+	// Moves every ACL on a key to another key
+	Move(ctx context.Context, in *CopyRequest, opts ...grpc.CallOption) (*ChangesResponse, error)
+	// Clones a user's ACLs onto another user
+	Clone(ctx context.Context, in *CloneRequest, opts ...grpc.CallOption) (*ChangesResponse, error)
+	// Streams the changes made to the ACLs of an object
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ACLEvent], error)
+}
+
+type authorizerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthorizerClient(cc grpc.ClientConnInterface) AuthorizerClient {
+	return &authorizerClient{cc}
+}
+
+func (c *authorizerClient) ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (*ListServicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListServicesResponse)
+	err := c.cc.Invoke(ctx, Authorizer_ListServices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) ListObjects(ctx context.Context, in *ListObjectsRequest, opts ...grpc.CallOption) (*ListObjectsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListObjectsResponse)
+	err := c.cc.Invoke(ctx, Authorizer_ListObjects_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Grant(ctx context.Context, in *PrivilegesRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Grant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Deny(ctx context.Context, in *PrivilegesRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Deny_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Revoke(ctx context.Context, in *PrivilegesRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Revoke_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Set_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Check_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, Authorizer_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Match(ctx context.Context, in *MatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MatchResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Authorizer_ServiceDesc.Streams[0], Authorizer_Match_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MatchRequest, MatchResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Authorizer_MatchClient = grpc.ServerStreamingClient[MatchResult]
+
+func (c *authorizerClient) ListUser(ctx context.Context, in *ListUserRequest, opts ...grpc.CallOption) (*ListUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUserResponse)
+	err := c.cc.Invoke(ctx, Authorizer_ListUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Copy(ctx context.Context, in *CopyRequest, opts ...grpc.CallOption) (*ChangesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChangesResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Copy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Move(ctx context.Context, in *CopyRequest, opts ...grpc.CallOption) (*ChangesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChangesResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Move_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Clone(ctx context.Context, in *CloneRequest, opts ...grpc.CallOption) (*ChangesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChangesResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Clone_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizerClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ACLEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Authorizer_ServiceDesc.Streams[1], Authorizer_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, ACLEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Authorizer_WatchClient = grpc.ServerStreamingClient[ACLEvent]
+
+// AuthorizerServer is the server API for Authorizer service.
+// All implementations must embed UnimplementedAuthorizerServer
+// for forward compatibility.
+//
+// The gRPC API of the authorizer.  It mirrors the REST API under /v1 and shares its
+// model layer, so every operation behaves the same way over either transport.
+type AuthorizerServer interface {
+	// Lists the services that have ACLs
+	ListServices(context.Context, *ListServicesRequest) (*ListServicesResponse, error)
+	// Lists the objects of a service that have ACLs
+	ListObjects(context.Context, *ListObjectsRequest) (*ListObjectsResponse, error)
+	// Allows the privileges for the users on the keys
+	Grant(context.Context, *PrivilegesRequest) (*WriteResponse, error)
+	// Denies the privileges for the users on the keys
+	Deny(context.Context, *PrivilegesRequest) (*WriteResponse, error)
+	// Removes the privileges for the users on the keys
+	Revoke(context.Context, *PrivilegesRequest) (*WriteResponse, error)
+	// Replaces the privileges for the users on the keys
+	Set(context.Context, *SetRequest) (*WriteResponse, error)
+	// Checks the users are allowed all of the privileges on the keys, in one batch
+	Check(context.Context, *CheckRequest) (*CheckResponse, error)
+	// Gets the privileges of the users on the keys
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// Lists the ACLs of an object
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Streams the keys the users hold the privileges on
+	Match(*MatchRequest, grpc.ServerStreamingServer[MatchResult]) error
+	// Lists every ACL of a user across services and objects
+	ListUser(context.Context, *ListUserRequest) (*ListUserResponse, error)
+	// Copies every ACL on a key to another key
+	Copy(context.Context, *CopyRequest) (*ChangesResponse, error)
+	// Moves every ACL on a key to another key
+	Move(context.Context, *CopyRequest) (*ChangesResponse, error)
+	// Clones a user's ACLs onto another user
+	Clone(context.Context, *CloneRequest) (*ChangesResponse, error)
+	// Streams the changes made to the ACLs of an object
+	Watch(*WatchRequest, grpc.ServerStreamingServer[ACLEvent]) error
+	mustEmbedUnimplementedAuthorizerServer()
+}
+
+// UnimplementedAuthorizerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAuthorizerServer struct{}
+
+func (UnimplementedAuthorizerServer) ListServices(context.Context, *ListServicesRequest) (*ListServicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServices not implemented")
+}
+func (UnimplementedAuthorizerServer) ListObjects(context.Context, *ListObjectsRequest) (*ListObjectsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListObjects not implemented")
+}
+func (UnimplementedAuthorizerServer) Grant(context.Context, *PrivilegesRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Grant not implemented")
+}
+func (UnimplementedAuthorizerServer) Deny(context.Context, *PrivilegesRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deny not implemented")
+}
+func (UnimplementedAuthorizerServer) Revoke(context.Context, *PrivilegesRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Revoke not implemented")
+}
+func (UnimplementedAuthorizerServer) Set(context.Context, *SetRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedAuthorizerServer) Check(context.Context, *CheckRequest) (*CheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedAuthorizerServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedAuthorizerServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedAuthorizerServer) Match(*MatchRequest, grpc.ServerStreamingServer[MatchResult]) error {
+	return status.Errorf(codes.Unimplemented, "method Match not implemented")
+}
+func (UnimplementedAuthorizerServer) ListUser(context.Context, *ListUserRequest) (*ListUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUser not implemented")
+}
+func (UnimplementedAuthorizerServer) Copy(context.Context, *CopyRequest) (*ChangesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Copy not implemented")
+}
+func (UnimplementedAuthorizerServer) Move(context.Context, *CopyRequest) (*ChangesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Move not implemented")
+}
+func (UnimplementedAuthorizerServer) Clone(context.Context, *CloneRequest) (*ChangesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clone not implemented")
+}
+func (UnimplementedAuthorizerServer) Watch(*WatchRequest, grpc.ServerStreamingServer[ACLEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedAuthorizerServer) mustEmbedUnimplementedAuthorizerServer() {}
+func (UnimplementedAuthorizerServer) testEmbeddedByValue()                    {}
+
+// UnsafeAuthorizerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthorizerServer will
+// result in compilation errors.
+type UnsafeAuthorizerServer interface {
+	mustEmbedUnimplementedAuthorizerServer()
+}
+
+func RegisterAuthorizerServer(s grpc.ServiceRegistrar, srv AuthorizerServer) {
+	// If the following call pancis, it indicates UnimplementedAuthorizerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Authorizer_ServiceDesc, srv)
+}
+
+func _Authorizer_ListServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).ListServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_ListServices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).ListServices(ctx, req.(*ListServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_ListObjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListObjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).ListObjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_ListObjects_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).ListObjects(ctx, req.(*ListObjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Grant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrivilegesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Grant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Grant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Grant(ctx, req.(*PrivilegesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Deny_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrivilegesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Deny(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Deny_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Deny(ctx, req.(*PrivilegesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrivilegesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Revoke_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Revoke(ctx, req.(*PrivilegesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Check_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Check(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Match_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AuthorizerServer).Match(m, &grpc.GenericServerStream[MatchRequest, MatchResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Authorizer_MatchServer = grpc.ServerStreamingServer[MatchResult]
+
+func _Authorizer_ListUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).ListUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_ListUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).ListUser(ctx, req.(*ListUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Copy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CopyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Copy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Copy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Copy(ctx, req.(*CopyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Move_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CopyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Move(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Move_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Move(ctx, req.(*CopyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Clone_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Clone(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Clone_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Clone(ctx, req.(*CloneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorizer_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AuthorizerServer).Watch(m, &grpc.GenericServerStream[WatchRequest, ACLEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Authorizer_WatchServer = grpc.ServerStreamingServer[ACLEvent]
+
+// Authorizer_ServiceDesc is the grpc.ServiceDesc for Authorizer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Authorizer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "authorizer.v1.Authorizer",
+	HandlerType: (*AuthorizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListServices",
+			Handler:    _Authorizer_ListServices_Handler,
+		},
+		{
+			MethodName: "ListObjects",
+			Handler:    _Authorizer_ListObjects_Handler,
+		},
+		{
+			MethodName: "Grant",
+			Handler:    _Authorizer_Grant_Handler,
+		},
+		{
+			MethodName: "Deny",
+			Handler:    _Authorizer_Deny_Handler,
+		},
+		{
+			MethodName: "Revoke",
+			Handler:    _Authorizer_Revoke_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _Authorizer_Set_Handler,
+		},
+		{
+			MethodName: "Check",
+			Handler:    _Authorizer_Check_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _Authorizer_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Authorizer_List_Handler,
+		},
+		{
+			MethodName: "ListUser",
+			Handler:    _Authorizer_ListUser_Handler,
+		},
+		{
+			MethodName: "Copy",
+			Handler:    _Authorizer_Copy_Handler,
+		},
+		{
+			MethodName: "Move",
+			Handler:    _Authorizer_Move_Handler,
+		},
+		{
+			MethodName: "Clone",
+			Handler:    _Authorizer_Clone_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Match",
+			Handler:       _Authorizer_Match_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Authorizer_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "authorizer.proto",
+}
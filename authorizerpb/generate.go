@@ -0,0 +1,5 @@
+// Package authorizerpb holds the protobuf messages and gRPC service of the authorizer's
+// gRPC API, generated from authorizer.proto.
+package authorizerpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative authorizer.proto
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+A local cache of has decisions, bounded in size and age.  Set a cache on a Client to have
+Has answer repeated checks without a round trip:
+
+	c := client.New("http://localhost:22220")
+	c.Cache = client.NewDecisionCache(30*time.Second, 10000)
+
+Decisions are dropped after the TTL, when the cache is full (least recently used first),
+or when they are invalidated because the ACLs they were read from changed.  Run WatchCache
+for each service/object the cache is used with to invalidate from the server's change feed:
+
+	go c.WatchCache(ctx, "docs", "document")
+
+A decision fetched while an invalidation happens is not stored, so an in-flight check
+can't put a stale decision back in the cache.
+*/
+type DecisionCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu         sync.Mutex
+	entries    map[decisionKey]*list.Element
+	lru        *list.List
+	generation uint64
+}
+
+// Identifies a cached decision
+type decisionKey struct {
+	service    string
+	object     string
+	key        string
+	user       string
+	privileges string
+}
+
+// A cached decision and when it expires
+type decisionEntry struct {
+	id        decisionKey
+	decision  Decision
+	expiresAt time.Time
+}
+
+// Creates a decision cache holding decisions for the TTL and at most maxEntries decisions
+func NewDecisionCache(ttl time.Duration, maxEntries int) *DecisionCache {
+	return &DecisionCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[decisionKey]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Builds the cache key for a check.  The privileges are sorted so their order doesn't matter.
+func newDecisionKey(service string, object string, item PrivilegeItem) decisionKey {
+	privileges := append([]string{}, item.Privileges...)
+	sort.Strings(privileges)
+	return decisionKey{service, object, item.Key, item.User, strings.Join(privileges, "\x00")}
+}
+
+// Gets an unexpired decision for the check
+func (c *DecisionCache) get(service string, object string, item PrivilegeItem) (Decision, bool) {
+	id := newDecisionKey(service, object, item)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		return Decision{}, false
+	}
+
+	entry := elem.Value.(*decisionEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		return Decision{}, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.decision, true
+}
+
+// The current generation, to pass to put once a decision is fetched
+func (c *DecisionCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Stores a decision fetched at the given generation, unless something was invalidated since
+func (c *DecisionCache) put(service string, object string, item PrivilegeItem, decision Decision,
+	generation uint64) {
+	id := newDecisionKey(service, object, item)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if elem, ok := c.entries[id]; ok {
+		c.remove(elem)
+	}
+
+	entry := &decisionEntry{id: id, decision: decision, expiresAt: time.Now().Add(c.ttl)}
+	c.entries[id] = c.lru.PushFront(entry)
+
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Removes an entry.  The lock must be held.
+func (c *DecisionCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*decisionEntry).id)
+}
+
+// Drops the decisions matching the filter.  The lock must be held.
+func (c *DecisionCache) invalidate(matches func(id decisionKey) bool) {
+	c.generation++
+	for id, elem := range c.entries {
+		if matches(id) {
+			c.remove(elem)
+		}
+	}
+}
+
+// Drops the decisions for a key of a service's object
+func (c *DecisionCache) InvalidateKey(service string, object string, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(func(id decisionKey) bool {
+		return id.service == service && id.object == object && id.key == key
+	})
+}
+
+// Drops the decisions for a service's object
+func (c *DecisionCache) InvalidateObject(service string, object string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(func(id decisionKey) bool {
+		return id.service == service && id.object == object
+	})
+}
+
+// Drops every decision
+func (c *DecisionCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(func(id decisionKey) bool { return true })
+}
+
+// Drops the decisions a change made stale
+func (c *DecisionCache) Apply(event ChangeEvent) {
+	c.InvalidateKey(event.Service, event.Object, event.Key)
+}
+
+// The number of decisions in the cache, including expired ones not yet dropped
+func (c *DecisionCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Returned by WatchCache when the client has no cache
+var ErrNoCache = errors.New("authorizer: client has no cache")
+
+// Keeps the client's cache in step with the ACLs of an object by watching the change feed
+// until the context is done.  Whenever the feed can't be followed, every decision for the
+// object is dropped, since changes may have been missed.
+func (c *Client) WatchCache(ctx context.Context, service string, object string) error {
+	if c.Cache == nil {
+		return ErrNoCache
+	}
+
+	since := ""
+	for retry := 0; ; {
+		if since == "" {
+			c.Cache.InvalidateObject(service, object)
+		}
+
+		result, err := c.Watch(ctx, service, object, WatchOptions{Since: since})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			since = ""
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.backoff(retry)):
+			}
+			retry++
+			continue
+		}
+
+		if since == "" {
+			// Decisions fetched before the feed was followed may predate its first event
+			c.Cache.InvalidateObject(service, object)
+		}
+		for _, event := range result.Events {
+			c.Cache.Apply(event)
+		}
+		since = result.NextSince
+		retry = 0
+	}
+}
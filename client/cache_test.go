@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDecisionCacheExpires(t *testing.T) {
+	cache := NewDecisionCache(10*time.Millisecond, 10)
+	item := PrivilegeItem{Key: "1", User: "john", Privileges: []string{"read"}}
+
+	cache.put("service1", "object1", item, Decision{Key: "1", User: "john", Privilege: "allow"}, 0)
+	if _, ok := cache.get("service1", "object1", item); !ok {
+		t.Fatal("Decision should be cached")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.get("service1", "object1", item); ok {
+		t.Fatal("Decision should have expired")
+	}
+}
+
+func TestDecisionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewDecisionCache(time.Minute, 2)
+	first := PrivilegeItem{Key: "1", User: "john", Privileges: []string{"read"}}
+	second := PrivilegeItem{Key: "2", User: "john", Privileges: []string{"read"}}
+	third := PrivilegeItem{Key: "3", User: "john", Privileges: []string{"read"}}
+
+	cache.put("service1", "object1", first, Decision{Privilege: "allow"}, 0)
+	cache.put("service1", "object1", second, Decision{Privilege: "allow"}, 0)
+	cache.get("service1", "object1", first)
+	cache.put("service1", "object1", third, Decision{Privilege: "allow"}, 0)
+
+	if cache.Len() != 2 {
+		t.Fatal("Incorrect number of cached decisions: ", cache.Len())
+	}
+	if _, ok := cache.get("service1", "object1", second); ok {
+		t.Fatal("Least recently used decision should have been evicted")
+	}
+	if _, ok := cache.get("service1", "object1", first); !ok {
+		t.Fatal("Recently used decision should not have been evicted")
+	}
+}
+
+func TestDecisionCacheInvalidation(t *testing.T) {
+	cache := NewDecisionCache(time.Minute, 10)
+	item := PrivilegeItem{Key: "1", User: "john", Privileges: []string{"read", "write"}}
+	other := PrivilegeItem{Key: "2", User: "john", Privileges: []string{"read"}}
+
+	cache.put("service1", "object1", item, Decision{Privilege: "allow"}, 0)
+	cache.put("service1", "object1", other, Decision{Privilege: "allow"}, 0)
+
+	reordered := PrivilegeItem{Key: "1", User: "john", Privileges: []string{"write", "read"}}
+	if _, ok := cache.get("service1", "object1", reordered); !ok {
+		t.Fatal("Privilege order should not matter to the cache")
+	}
+
+	generation := cache.currentGeneration()
+	cache.InvalidateKey("service1", "object1", "1")
+	if _, ok := cache.get("service1", "object1", item); ok {
+		t.Fatal("Decision on the invalidated key should have been dropped")
+	}
+	if _, ok := cache.get("service1", "object1", other); !ok {
+		t.Fatal("Decision on another key should not have been dropped")
+	}
+
+	cache.put("service1", "object1", item, Decision{Privilege: "allow"}, generation)
+	if _, ok := cache.get("service1", "object1", item); ok {
+		t.Fatal("Decision fetched before an invalidation should not be stored")
+	}
+}
+
+func TestHasUsesCache(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		items := []PrivilegeItem{}
+		json.NewDecoder(r.Body).Decode(&items)
+		decisions := []Decision{}
+		for _, item := range items {
+			decisions = append(decisions, Decision{Key: item.Key, User: item.User, Privilege: "allow"})
+		}
+		json.NewEncoder(w).Encode(decisions)
+	}))
+	defer ts.Close()
+
+	c := testClient(ts)
+	c.Cache = NewDecisionCache(time.Minute, 10)
+	items := []PrivilegeItem{{Key: "1", User: "john", Privileges: []string{"read"}}}
+
+	for i := 0; i < 3; i++ {
+		decisions, err := c.Has(context.Background(), "service1", "object1", items)
+		if err != nil {
+			t.Fatal("Unexpected error checking privileges: ", err)
+		}
+		if len(decisions) != 1 || !decisions[0].Allowed() {
+			t.Fatal("Incorrect decisions: ", decisions)
+		}
+	}
+	if calls != 1 {
+		t.Fatal("Cached decisions should not have been fetched again, calls: ", calls)
+	}
+
+	c.Cache.InvalidateObject("service1", "object1")
+	c.Has(context.Background(), "service1", "object1", items)
+	if calls != 2 {
+		t.Fatal("Invalidated decision should have been fetched again, calls: ", calls)
+	}
+}
+
+func TestWatchCacheInvalidatesChangedKeys(t *testing.T) {
+	watching := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/service/service1/object/object1/has/" {
+			json.NewEncoder(w).Encode([]Decision{{Key: "1", User: "john", Privilege: "allow"}})
+			return
+		}
+
+		switch r.URL.Query().Get("since") {
+		case "":
+			json.NewEncoder(w).Encode(WatchResult{Events: []ChangeEvent{}, NextSince: "5"})
+		case "5":
+			close(watching)
+			<-release
+			json.NewEncoder(w).Encode(WatchResult{
+				Events:    []ChangeEvent{{Seq: 6, Service: "service1", Object: "object1", Key: "1", Action: "revoke"}},
+				NextSince: "6",
+			})
+		default:
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	c := testClient(ts)
+	c.Cache = NewDecisionCache(time.Minute, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.WatchCache(ctx, "service1", "object1") }()
+
+	<-watching
+	items := []PrivilegeItem{{Key: "1", User: "john", Privileges: []string{"read"}}}
+	if _, err := c.Has(context.Background(), "service1", "object1", items); err != nil {
+		t.Fatal("Unexpected error checking privileges: ", err)
+	}
+	if c.Cache.Len() != 1 {
+		t.Fatal("Decision should be cached")
+	}
+
+	close(release)
+	for start := time.Now(); c.Cache.Len() != 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("Decision on the changed key should have been invalidated")
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("WatchCache should stop when the context is done, got: ", err)
+	}
+}
@@ -0,0 +1,482 @@
+/*
+Package client is a Go client for the authorizer API.
+
+	c := client.New("http://localhost:22220")
+	decisions, err := c.Has(ctx, "docs", "document", []client.PrivilegeItem{
+		{Key: "7", User: "alice", Privileges: []string{"read"}},
+	})
+
+Requests that fail with a network error or a 5xx status are retried with exponential
+backoff until MaxRetries is reached or the context is done.  Each write is sent with an
+Idempotency-Key header that stays the same across its retries, so a retried write the
+server already applied gets the original response back instead of being applied twice.
+
+Set APIKey when the server requires authentication.  With SignRequests set, requests are
+signed with the key instead of carrying it, for callers that shouldn't send bearer tokens.
+
+Has decisions can be cached locally by setting a DecisionCache on the client, and kept in
+step with the server by running WatchCache, which invalidates them from the change feed.
+*/
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A client for the authorizer API.  The zero value isn't usable; create one with New.
+type Client struct {
+	// Base URL of the authorizer, e.g. "http://localhost:22220"
+	BaseURL string
+	// HTTP client used to send requests
+	HTTPClient *http.Client
+	// Number of times a failed request is retried
+	MaxRetries int
+	// Delay before the first retry.  It doubles on each retry up to MaxBackoff.
+	Backoff time.Duration
+	// Longest delay between retries
+	MaxBackoff time.Duration
+	// Optional cache of has decisions.  See DecisionCache.
+	Cache *DecisionCache
+	// API key sent with every request, when the server requires authentication
+	APIKey string
+	// Sign requests with APIKey rather than sending it, so the key never leaves the client
+	SignRequests bool
+}
+
+// Creates a client for the authorizer at the base URL with the default retry policy
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		HTTPClient: http.DefaultClient,
+		MaxRetries: 3,
+		Backoff:    100 * time.Millisecond,
+		MaxBackoff: 2 * time.Second,
+	}
+}
+
+/*
+An error response from the authorizer.  Code is one of the documented error codes, and
+Item and Field point at the request item and field at fault when the error was about one.
+*/
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Item    *int   `json:"item,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Item != nil {
+		return fmt.Sprintf("authorizer: %d %s: %s (item %d, field %q)", e.Status, e.Code, e.Message, *e.Item, e.Field)
+	}
+	return fmt.Sprintf("authorizer: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// An item of a grant, deny, revoke or has request.  IfRevision optionally makes a write fail
+// with a conflict error unless the ACL is still at that revision.
+type PrivilegeItem struct {
+	Key        string   `json:"key"`
+	User       string   `json:"user"`
+	Privileges []string `json:"privileges"`
+	IfRevision int64    `json:"if_revision,omitempty"`
+}
+
+// An item of a set request.  Privileges map privilege names to "allow" or "deny".
+// IfRevision optionally makes the write conditional, as for PrivilegeItem.
+type SetItem struct {
+	Key        string            `json:"key"`
+	User       string            `json:"user"`
+	Privileges map[string]string `json:"privileges"`
+	IfRevision int64             `json:"if_revision,omitempty"`
+}
+
+// An item of a get request
+type KeyUser struct {
+	Key  string `json:"key"`
+	User string `json:"user"`
+}
+
+// The decision for an item of a has request.  Privilege is "allow" or "deny".
+type Decision struct {
+	Key       string `json:"key"`
+	User      string `json:"user"`
+	Privilege string `json:"privilege"`
+}
+
+// Whether the decision allowed the privileges
+func (d Decision) Allowed() bool {
+	return d.Privilege == "allow"
+}
+
+// The privileges of a user on a key.  Revision is 0 when the user has no ACL on the key.
+type Privileges struct {
+	Key        string                 `json:"key"`
+	User       string                 `json:"user"`
+	Privileges map[string]interface{} `json:"privileges"`
+	Revision   int64                  `json:"revision"`
+}
+
+// An ACL as returned by List
+type ACL struct {
+	Service    string
+	Object     string
+	Key        string
+	User       string
+	Privileges map[string]interface{}
+	Revision   int64
+}
+
+// Filters and paging for List.  All fields are optional.
+type ListOptions struct {
+	Key            string
+	User           string
+	Privileges     []string
+	PrivilegeValue string
+	Limit          int
+	Cursor         string
+}
+
+// A page of ACLs.  NextCursor is empty on the last page.
+type ACLPage struct {
+	ACLs       []ACL  `json:"acls"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// An item of a match request.  Value defaults to "allow" and Limit to no limit.
+type MatchItem struct {
+	User       string   `json:"user"`
+	Privileges []string `json:"privileges"`
+	Value      string   `json:"value,omitempty"`
+	Limit      int      `json:"limit,omitempty"`
+	Cursor     string   `json:"cursor,omitempty"`
+}
+
+// The keys matched for an item of a match request
+type MatchResult struct {
+	User       string   `json:"user"`
+	Keys       []string `json:"keys"`
+	NextCursor string   `json:"next_cursor"`
+}
+
+// A change made to an ACL.  Action is "grant", "deny", "revoke", "set" or "delete".
+type ChangeEvent struct {
+	Seq        int64       `json:"seq"`
+	Service    string      `json:"service"`
+	Object     string      `json:"object"`
+	Key        string      `json:"key"`
+	User       string      `json:"user"`
+	Action     string      `json:"action"`
+	Privileges interface{} `json:"privileges"`
+	Time       time.Time   `json:"time"`
+}
+
+// Options for Watch.  Without Since, only changes made after the call are returned.
+type WatchOptions struct {
+	Key     string
+	Since   string
+	Timeout time.Duration
+}
+
+// The changes returned by Watch.  Pass NextSince to the next call to resume after them.
+type WatchResult struct {
+	Events    []ChangeEvent `json:"events"`
+	NextSince string        `json:"next_since"`
+}
+
+// The path of an endpoint on a service's object
+func objectPath(service string, object string, endpoint string) string {
+	return "/v1/service/" + url.PathEscape(service) + "/object/" + url.PathEscape(object) + "/" + endpoint + "/"
+}
+
+// Lists the services that have ACLs
+func (c *Client) Services(ctx context.Context) ([]string, error) {
+	result := []string{}
+	err := c.do(ctx, "GET", "/v1/service/", nil, nil, &result)
+	return result, err
+}
+
+// Lists the objects of a service that have ACLs
+func (c *Client) Objects(ctx context.Context, service string) ([]string, error) {
+	result := []string{}
+	err := c.do(ctx, "GET", "/v1/service/"+url.PathEscape(service)+"/object/", nil, nil, &result)
+	return result, err
+}
+
+// Allows the privileges for the users on the keys
+func (c *Client) Grant(ctx context.Context, service string, object string, items []PrivilegeItem) error {
+	return c.do(ctx, "POST", objectPath(service, object, "grant"), nil, items, nil)
+}
+
+// Denies the privileges for the users on the keys
+func (c *Client) Deny(ctx context.Context, service string, object string, items []PrivilegeItem) error {
+	return c.do(ctx, "POST", objectPath(service, object, "deny"), nil, items, nil)
+}
+
+// Removes the privileges for the users on the keys
+func (c *Client) Revoke(ctx context.Context, service string, object string, items []PrivilegeItem) error {
+	return c.do(ctx, "POST", objectPath(service, object, "revoke"), nil, items, nil)
+}
+
+// Replaces the privileges for the users on the keys
+func (c *Client) Set(ctx context.Context, service string, object string, items []SetItem) error {
+	return c.do(ctx, "PUT", objectPath(service, object, "set"), nil, items, nil)
+}
+
+// Checks the users are allowed all of the privileges on the keys.  With a Cache, only the
+// checks without a cached decision are sent to the server.
+func (c *Client) Has(ctx context.Context, service string, object string, items []PrivilegeItem) ([]Decision, error) {
+	if c.Cache == nil {
+		result := []Decision{}
+		err := c.do(ctx, "GET", objectPath(service, object, "has"), nil, items, &result)
+		return result, err
+	}
+
+	result := make([]Decision, len(items))
+	missing := []PrivilegeItem{}
+	missingIdx := []int{}
+	for idx, item := range items {
+		if decision, ok := c.Cache.get(service, object, item); ok {
+			result[idx] = decision
+		} else {
+			missing = append(missing, item)
+			missingIdx = append(missingIdx, idx)
+		}
+	}
+
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	generation := c.Cache.currentGeneration()
+	fetched := []Decision{}
+	err := c.do(ctx, "GET", objectPath(service, object, "has"), nil, missing, &fetched)
+	if err != nil {
+		return nil, err
+	}
+	if len(fetched) != len(missing) {
+		return nil, fmt.Errorf("authorizer: expected %d decisions, got %d", len(missing), len(fetched))
+	}
+
+	for i, decision := range fetched {
+		result[missingIdx[i]] = decision
+		c.Cache.put(service, object, missing[i], decision, generation)
+	}
+	return result, nil
+}
+
+// Gets the privileges of the users on the keys
+func (c *Client) Get(ctx context.Context, service string, object string, items []KeyUser) ([]Privileges, error) {
+	result := []Privileges{}
+	err := c.do(ctx, "GET", objectPath(service, object, "get"), nil, items, &result)
+	return result, err
+}
+
+// Lists the ACLs of an object.  Without a limit every matching ACL is returned in one page.
+func (c *Client) List(ctx context.Context, service string, object string, opts ListOptions) (ACLPage, error) {
+	query := url.Values{}
+	if opts.Key != "" {
+		query.Set("key", opts.Key)
+	}
+	if opts.User != "" {
+		query.Set("user", opts.User)
+	}
+	for _, privilege := range opts.Privileges {
+		query.Add("privilege", privilege)
+	}
+	if opts.PrivilegeValue != "" {
+		query.Set("privilege_value", opts.PrivilegeValue)
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+
+	page := ACLPage{ACLs: []ACL{}}
+	if opts.Limit <= 0 {
+		err := c.do(ctx, "GET", objectPath(service, object, "list"), query, nil, &page.ACLs)
+		return page, err
+	}
+
+	query.Set("limit", strconv.Itoa(opts.Limit))
+	err := c.do(ctx, "GET", objectPath(service, object, "list"), query, nil, &page)
+	return page, err
+}
+
+// Finds the keys the users hold the privileges on
+func (c *Client) Match(ctx context.Context, service string, object string, items []MatchItem) ([]MatchResult, error) {
+	result := []MatchResult{}
+	err := c.do(ctx, "GET", objectPath(service, object, "match"), nil, items, &result)
+	return result, err
+}
+
+// Waits for changes to the ACLs of an object, returning as soon as there are any or with no
+// events once the timeout passes.  The HTTP client's timeout must be longer than the wait.
+func (c *Client) Watch(ctx context.Context, service string, object string, opts WatchOptions) (WatchResult, error) {
+	query := url.Values{}
+	if opts.Key != "" {
+		query.Set("key", opts.Key)
+	}
+	if opts.Since != "" {
+		query.Set("since", opts.Since)
+	}
+	if opts.Timeout > 0 {
+		query.Set("timeout", strconv.Itoa(int(opts.Timeout/time.Second)))
+	}
+
+	result := WatchResult{Events: []ChangeEvent{}}
+	err := c.do(ctx, "GET", objectPath(service, object, "watch"), query, nil, &result)
+	return result, err
+}
+
+// Checks if a failed request should be retried.  A status of 0 means no response arrived.
+func retryable(status int) bool {
+	return status == 0 || status >= 500
+}
+
+// The delay before the given retry, with jitter so clients don't retry in lockstep
+func (c *Client) backoff(retry int) time.Duration {
+	delay := c.Backoff << uint(retry)
+	if delay <= 0 || delay > c.MaxBackoff {
+		delay = c.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Generates a key identifying a write across its retries
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	crand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+// Sends a request, retrying failures, and decodes the JSON response into out when it isn't nil
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{},
+	out interface{}) error {
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	idempotencyKey := ""
+	if method != "GET" {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	var err error
+	for retry := 0; ; retry++ {
+		var status int
+		status, err = c.send(ctx, method, target, bodyBytes, idempotencyKey, out)
+		if err == nil || retry >= c.MaxRetries || !retryable(status) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.backoff(retry)):
+		}
+	}
+}
+
+// Signs a request with an API key.  The signature is the HMAC-SHA256, keyed with the
+// signing key, of the method, path with query, Unix timestamp and hex SHA-256 of the body,
+// one per line.  The signing key is the hex HMAC-SHA256 of "authorizer request signing"
+// keyed with the key's secret.
+func signRequest(req *http.Request, apiKey string, body []byte, now time.Time) error {
+	parts := strings.SplitN(strings.TrimPrefix(apiKey, "ak_"), ".", 2)
+	if !strings.HasPrefix(apiKey, "ak_") || len(parts) != 2 {
+		return fmt.Errorf("authorizer: can't sign requests with API key, it isn't of the form ak_<id>.<secret>")
+	}
+
+	derive := hmac.New(sha256.New, []byte(parts[1]))
+	derive.Write([]byte("authorizer request signing"))
+	bodySum := sha256.Sum256(body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(hex.EncodeToString(derive.Sum(nil))))
+	mac.Write([]byte(req.Method + "\n" + req.URL.RequestURI() + "\n" + timestamp + "\n" +
+		hex.EncodeToString(bodySum[:])))
+
+	req.Header.Set("X-Authorizer-Key-Id", parts[0])
+	req.Header.Set("X-Authorizer-Timestamp", timestamp)
+	req.Header.Set("X-Authorizer-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+// Sends a single request, returning the response status
+func (c *Client) send(ctx context.Context, method string, target string, body []byte, idempotencyKey string,
+	out interface{}) (int, error) {
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if c.SignRequests {
+		err = signRequest(req, c.APIKey, body, time.Now())
+		if err != nil {
+			return 0, err
+		}
+	} else if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, err
+	}
+
+	if res.StatusCode >= 400 {
+		envelope := struct {
+			Error *Error `json:"error"`
+		}{}
+		if json.Unmarshal(data, &envelope) != nil || envelope.Error == nil {
+			envelope.Error = &Error{Code: "unknown", Message: string(data)}
+		}
+		envelope.Error.Status = res.StatusCode
+		return res.StatusCode, envelope.Error
+	}
+
+	if out != nil && res.StatusCode != http.StatusNoContent {
+		err = json.Unmarshal(data, out)
+	}
+	return res.StatusCode, err
+}
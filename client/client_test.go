@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Creates a client for the test server that retries without waiting
+func testClient(ts *httptest.Server) *Client {
+	c := New(ts.URL)
+	c.Backoff = time.Millisecond
+	c.MaxBackoff = time.Millisecond
+	return c
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(503)
+			w.Write([]byte(`{"error": {"code": "unavailable", "message": "try again"}}`))
+			return
+		}
+		w.Write([]byte(`["service1"]`))
+	}))
+	defer ts.Close()
+
+	services, err := testClient(ts).Services(context.Background())
+	if err != nil {
+		t.Fatal("Unexpected error listing services: ", err)
+	}
+	if calls != 3 {
+		t.Fatal("Incorrect number of attempts: ", calls)
+	}
+	if len(services) != 1 || services[0] != "service1" {
+		t.Fatal("Incorrect services: ", services)
+	}
+}
+
+func TestRetriesWritesWithSameIdempotencyKey(t *testing.T) {
+	keys := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) < 2 {
+			w.WriteHeader(503)
+			return
+		}
+		w.WriteHeader(204)
+	}))
+	defer ts.Close()
+
+	c := testClient(ts)
+	err := c.Grant(context.Background(), "service1", "object1", []PrivilegeItem{
+		{Key: "1", User: "user1", Privileges: []string{"read"}},
+	})
+	if err != nil {
+		t.Fatal("Unexpected error granting: ", err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatal("Retries didn't send the same idempotency key: ", keys)
+	}
+
+	err = c.Grant(context.Background(), "service1", "object1", []PrivilegeItem{
+		{Key: "1", User: "user1", Privileges: []string{"read"}},
+	})
+	if err != nil {
+		t.Fatal("Unexpected error granting: ", err)
+	}
+	if keys[2] == keys[0] {
+		t.Fatal("Separate writes sent the same idempotency key: ", keys)
+	}
+}
+
+func TestSendsAPIKey(t *testing.T) {
+	key := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get("X-API-Key")
+		w.Write([]byte(`["service1"]`))
+	}))
+	defer ts.Close()
+
+	c := testClient(ts)
+	c.APIKey = "ak_1.secret"
+	_, err := c.Services(context.Background())
+	if err != nil {
+		t.Fatal("Unexpected error listing services: ", err)
+	}
+	if key != "ak_1.secret" {
+		t.Fatal("Incorrect API key sent: ", key)
+	}
+}
+
+func TestSignsRequests(t *testing.T) {
+	headers := http.Header{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.Write([]byte(`["service1"]`))
+	}))
+	defer ts.Close()
+
+	c := testClient(ts)
+	c.APIKey = "ak_1.secret"
+	c.SignRequests = true
+	_, err := c.Services(context.Background())
+	if err != nil {
+		t.Fatal("Unexpected error listing services: ", err)
+	}
+	if headers.Get("X-API-Key") != "" || headers.Get("X-Authorizer-Key-Id") != "1" ||
+		headers.Get("X-Authorizer-Timestamp") == "" || !strings.HasPrefix(headers.Get("X-Authorizer-Signature"), "sha256=") {
+		t.Fatal("Incorrect signature headers sent: ", headers)
+	}
+
+	c.APIKey = "not-a-key"
+	_, err = c.Services(context.Background())
+	if err == nil {
+		t.Fatal("Expected an error signing with a malformed API key")
+	}
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error": {"code": "missing_field", "message": "Missing user", "item": 0, "field": "user"}}`))
+	}))
+	defer ts.Close()
+
+	err := testClient(ts).Grant(context.Background(), "service1", "object1", []PrivilegeItem{{Key: "1"}})
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatal("Expected an API error, got: ", err)
+	}
+	if calls != 1 {
+		t.Fatal("Client error should not have been retried, attempts: ", calls)
+	}
+	if apiErr.Status != 400 || apiErr.Code != "missing_field" || apiErr.Field != "user" ||
+		apiErr.Item == nil || *apiErr.Item != 0 {
+		t.Fatal("Incorrect API error: ", apiErr)
+	}
+}
+
+func TestStopsRetryingWhenContextDone(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	c := testClient(ts)
+	c.MaxRetries = 100
+	c.Backoff = time.Hour
+	c.MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Services(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatal("Expected the context deadline error, got: ", err)
+	}
+	if calls != 1 {
+		t.Fatal("Incorrect number of attempts: ", calls)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/johnnadratowski/authorizer/client"
+	"labix.org/v2/mgo"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Checks requests signed by the Go client are accepted, and tampered or stale ones aren't
+func testSignedRequests(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	enabled, encryptionKey := authEnabled, signingEncryptionKey
+	authEnabled = true
+	defer func() {
+		authEnabled, signingEncryptionKey = enabled, encryptionKey
+	}()
+
+	unsigned, err := APIKey{}.Create(c, apiKeyItem{Name: "unsigned", Services: []string{"service5"}, Scopes: []string{"write"}})
+	if err != nil {
+		t.Fatal("Error creating API key: ", err)
+	}
+	if unsigned.SigningKey != "" {
+		t.Fatal("Expected no signing key without an encryption key configured: ", unsigned.SigningKey)
+	}
+
+	sum := sha256.Sum256([]byte("test signing encryption key"))
+	signingEncryptionKey = sum[:]
+	key, err := APIKey{}.Create(c, apiKeyItem{Name: "signer", Services: []string{"service5"}, Scopes: []string{"write"}})
+	if err != nil {
+		t.Fatal("Error creating API key: ", err)
+	}
+	_, secret, _ := parseAPIKey(key.Key)
+	if key.SigningKey == "" || strings.Contains(key.SigningKey, deriveSigningKey(secret)) {
+		t.Fatal("Expected the signing key to be stored encrypted: ", key.SigningKey)
+	}
+
+	fmt.Println("Testing signed requests against: ", ts.URL)
+	ctx := context.Background()
+	api := client.New(ts.URL)
+	api.APIKey = key.Key
+	api.SignRequests = true
+
+	err = api.Grant(ctx, "service5", "object2", []client.PrivilegeItem{
+		{Key: "1", User: "john", Privileges: []string{"read"}},
+	})
+	if err != nil {
+		t.Fatal("Error granting with signed request: ", err)
+	}
+	decisions, err := api.Has(ctx, "service5", "object2", []client.PrivilegeItem{
+		{Key: "1", User: "john", Privileges: []string{"read"}},
+	})
+	if err != nil {
+		t.Fatal("Error checking with signed request: ", err)
+	}
+	if len(decisions) != 1 || !decisions[0].Allowed() {
+		t.Fatal("Incorrect decisions from signed request: ", decisions)
+	}
+
+	url := fmt.Sprintf("%s/v1/service/%s/object/%s/grant/", ts.URL, "service5", "object2")
+	body := []byte(`[{"user": "john", "key": "1", "privileges": ["write"]}]`)
+	send := func(key APIKey, signingKey string, timestamp time.Time, signed []byte) int {
+		req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
+		stamp := strconv.FormatInt(timestamp.Unix(), 10)
+		req.Header.Set(signatureKeyHeader, key.ID.Hex())
+		req.Header.Set(signatureTimestampHeader, stamp)
+		req.Header.Set(signatureHeader, signRequest(signingKey, "POST", req.URL.RequestURI(), stamp, signed))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	signingKey := deriveSigningKey(secret)
+	if status := send(key, signingKey, time.Now(), body); status != 204 {
+		t.Fatal("Unexpected status code from signed grant. Got Status: ", status)
+	}
+	if status := send(key, signingKey, time.Now(), []byte(`[]`)); status != 401 {
+		t.Fatal("Unexpected status code from grant with tampered body. Got Status: ", status)
+	}
+	if status := send(key, signingKey, time.Now().Add(-signatureWindow-time.Minute), body); status != 401 {
+		t.Fatal("Unexpected status code from grant with stale signature. Got Status: ", status)
+	}
+	if status := send(key, key.Hash, time.Now(), body); status != 401 {
+		t.Fatal("Unexpected status code from grant signed with the stored hash. Got Status: ", status)
+	}
+	_, unsignedSecret, _ := parseAPIKey(unsigned.Key)
+	if status := send(unsigned, deriveSigningKey(unsignedSecret), time.Now(), body); status != 401 {
+		t.Fatal("Unexpected status code from grant signed with a key without a signing key. Got Status: ", status)
+	}
+
+	fmt.Println("Testing signed imports larger than the body limit")
+	bodyLimit, streamLimit := maxBodyBytes, maxSignedStreamBytes
+	maxBodyBytes = 64
+	defer func() { maxBodyBytes, maxSignedStreamBytes = bodyLimit, streamLimit }()
+	records := []byte(`{"op": "grant", "service": "service5", "object": "object3", "key": "1", "user": "ann", "privileges": ["read"]}
+{"op": "grant", "service": "service5", "object": "object3", "key": "2", "user": "ann", "privileges": ["read"]}
+`)
+	importURL := fmt.Sprintf("%s/v1/import/?service=%s", ts.URL, "service5")
+	sendImport := func(signed []byte) *http.Response {
+		req, _ := http.NewRequest("POST", importURL, bytes.NewReader(records))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		stamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(signatureKeyHeader, key.ID.Hex())
+		req.Header.Set(signatureTimestampHeader, stamp)
+		req.Header.Set(signatureHeader, signRequest(signingKey, "POST", req.URL.RequestURI(), stamp, signed))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := sendImport(records)
+	summary := ImportSummary{}
+	json.NewDecoder(res.Body).Decode(&summary)
+	res.Body.Close()
+	if res.StatusCode != 200 || summary.Applied != 2 || summary.Failed != 0 {
+		t.Fatal("Unexpected response to signed import. Got Status: ", res.StatusCode, summary)
+	}
+	res = sendImport(records[:len(records)-1])
+	res.Body.Close()
+	if res.StatusCode != 401 {
+		t.Fatal("Unexpected status code from import with tampered body. Got Status: ", res.StatusCode)
+	}
+	maxSignedStreamBytes = int64(len(records) - 1)
+	res = sendImport(records)
+	res.Body.Close()
+	if res.StatusCode != 413 {
+		t.Fatal("Unexpected status code from signed import over the spool limit. Got Status: ", res.StatusCode)
+	}
+
+	signingEncryptionKey = nil
+	if status := send(key, signingKey, time.Now(), body); status != 401 {
+		t.Fatal("Unexpected status code from signed grant without an encryption key. Got Status: ", status)
+	}
+}
+
+// Runs the Go client against the real router
+func testClient(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	fmt.Println("Testing client against: ", ts.URL)
+	ctx := context.Background()
+	api := client.New(ts.URL)
+
+	err := api.Grant(ctx, "service2", "object1", []client.PrivilegeItem{
+		{Key: "1", User: "john", Privileges: []string{"read", "write"}},
+		{Key: "2", User: "john", Privileges: []string{"read"}},
+	})
+	if err != nil {
+		t.Fatal("Error granting with client: ", err)
+	}
+
+	err = api.Deny(ctx, "service2", "object1", []client.PrivilegeItem{
+		{Key: "2", User: "john", Privileges: []string{"write"}},
+	})
+	if err != nil {
+		t.Fatal("Error denying with client: ", err)
+	}
+
+	err = api.Set(ctx, "service2", "object1", []client.SetItem{
+		{Key: "3", User: "john", Privileges: map[string]string{"read": "deny", "admin": "allow"}},
+	})
+	if err != nil {
+		t.Fatal("Error setting with client: ", err)
+	}
+
+	err = api.Revoke(ctx, "service2", "object1", []client.PrivilegeItem{
+		{Key: "3", User: "john", Privileges: []string{"admin"}},
+	})
+	if err != nil {
+		t.Fatal("Error revoking with client: ", err)
+	}
+
+	decisions, err := api.Has(ctx, "service2", "object1", []client.PrivilegeItem{
+		{Key: "1", User: "john", Privileges: []string{"read", "write"}},
+		{Key: "2", User: "john", Privileges: []string{"write"}},
+	})
+	if err != nil {
+		t.Fatal("Error checking with client: ", err)
+	}
+	if len(decisions) != 2 || !decisions[0].Allowed() || decisions[1].Allowed() {
+		t.Fatal("Incorrect decisions from client: ", decisions)
+	}
+
+	privileges, err := api.Get(ctx, "service2", "object1", []client.KeyUser{{Key: "3", User: "john"}})
+	if err != nil {
+		t.Fatal("Error getting with client: ", err)
+	}
+	if len(privileges) != 1 || len(privileges[0].Privileges) != 1 || privileges[0].Privileges["read"] != "deny" {
+		t.Fatal("Incorrect privileges from client: ", privileges)
+	}
+
+	keys := []string{}
+	opts := client.ListOptions{User: "john", Limit: 2}
+	for {
+		page, err := api.List(ctx, "service2", "object1", opts)
+		if err != nil {
+			t.Fatal("Error listing with client: ", err)
+		}
+		for _, acl := range page.ACLs {
+			keys = append(keys, acl.Key)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	if len(keys) != 3 {
+		t.Fatal("Incorrect keys listed with client: ", keys)
+	}
+
+	matches, err := api.Match(ctx, "service2", "object1", []client.MatchItem{
+		{User: "john", Privileges: []string{"read"}},
+	})
+	if err != nil {
+		t.Fatal("Error matching with client: ", err)
+	}
+	if len(matches) != 1 || len(matches[0].Keys) != 2 {
+		t.Fatal("Incorrect matches from client: ", matches)
+	}
+
+	services, err := api.Services(ctx)
+	if err != nil {
+		t.Fatal("Error listing services with client: ", err)
+	}
+	if len(services) != 2 {
+		t.Fatal("Incorrect services from client: ", services)
+	}
+
+	objects, err := api.Objects(ctx, "service2")
+	if err != nil {
+		t.Fatal("Error listing objects with client: ", err)
+	}
+	if len(objects) != 1 || objects[0] != "object1" {
+		t.Fatal("Incorrect objects from client: ", objects)
+	}
+
+	err = api.Grant(ctx, "service2", "object1", []client.PrivilegeItem{{Key: "1"}})
+	if apiErr, ok := err.(*client.Error); !ok || apiErr.Code != ErrCodeMissingField {
+		t.Fatal("Expected a missing field error from client, got: ", err)
+	}
+}
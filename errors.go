@@ -0,0 +1,126 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Error codes returned in error responses.  Clients should switch on these rather than the
+// message, which may change.  See the README for the full catalogue.
+const (
+	// The request body couldn't be read (400)
+	ErrCodeUnreadableBody = "unreadable_body"
+	// The request body is larger than the configured limit (413)
+	ErrCodeBodyTooLarge = "body_too_large"
+	// The request body has more items than the configured limit (400)
+	ErrCodeTooManyItems = "too_many_items"
+	// The request body isn't valid JSON (400)
+	ErrCodeMalformedJSON = "malformed_json"
+	// The request body is valid JSON but not a list of objects (400)
+	ErrCodeInvalidBody = "invalid_body"
+	// A request body item isn't an object (400)
+	ErrCodeInvalidItem = "invalid_item"
+	// A required field is missing from a request body item (400)
+	ErrCodeMissingField = "missing_field"
+	// A request body item has a field the endpoint doesn't know, in strict mode (400)
+	ErrCodeUnknownField = "unknown_field"
+	// A field of a request body item has the wrong type or value (400)
+	ErrCodeInvalidField = "invalid_field"
+	// A query string parameter has the wrong value (400)
+	ErrCodeInvalidParameter = "invalid_parameter"
+	// A page cursor couldn't be decoded (400)
+	ErrCodeInvalidCursor = "invalid_cursor"
+	// The request has no credentials, or they aren't valid (401)
+	ErrCodeUnauthenticated = "unauthenticated"
+	// The caller may not perform the request on its service (403)
+	ErrCodeForbidden = "forbidden"
+	// No route or resource matches the request URL (404)
+	ErrCodeNotFound = "not_found"
+	// A conditional write found the ACL at another revision, or a request with the same
+	// idempotency key is still in progress (409)
+	ErrCodeConflict = "conflict"
+	// An idempotency key was sent again with a different request (422)
+	ErrCodeIdempotencyKeyReused = "idempotency_key_reused"
+	// Reading or writing the ACL store failed (500)
+	ErrCodeStorage = "storage_error"
+	// An unexpected server error occurred (500)
+	ErrCodeInternal = "internal_error"
+	// The ACL store couldn't be reached (503)
+	ErrCodeUnavailable = "unavailable"
+	// A change was made, but recording it in the audit, event or history logs failed.  It's
+	// sent in the warning header of a successful response, since the change mustn't be retried.
+	ErrCodeChangeNotRecorded = "change_not_recorded"
+)
+
+// The header of a successful response warning about the changes it made
+const warningHeader = "X-Authorizer-Warning"
+
+/*
+The body of an error response.  Item is the index of the request body item the error
+refers to, and Field the name of the field within it, when the error is about a specific
+item or field.
+*/
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Item    *int   `json:"item,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (e APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Writes the error as a JSON error response with the given status code
+func (e APIError) Write(w http.ResponseWriter, status int) {
+	data, err := json.Marshal(map[string]interface{}{"error": e})
+	if err != nil {
+		log.Error("Error marshalling error response: %s", err)
+		http.Error(w, e.Message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// Writes an error response that doesn't refer to a request body item
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	APIError{Code: code, Message: message}.Write(w, status)
+}
+
+// Writes an error response about a field of a request body item.  The field may be empty
+// when the error is about the item as a whole.
+func writeItemError(w http.ResponseWriter, status int, code string, message string, item int, field string) {
+	APIError{Code: code, Message: message, Item: &item, Field: field}.Write(w, status)
+}
+
+// Writes an error response for a request that failed after changing some ACLs, with the
+// results of the items applied before it failed, the last of them possibly only in part
+func writePartialError(w http.ResponseWriter, status int, apiErr APIError, applied interface{}) {
+	data, err := json.Marshal(map[string]interface{}{"error": apiErr, "applied": applied})
+	if err != nil {
+		log.Error("Error marshalling error response: %s", err)
+		http.Error(w, apiErr.Message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// Warns in a successful response that the change of a request body item was made, but not
+// recorded in the audit, event or history logs
+func warnNotRecorded(w http.ResponseWriter, item int) {
+	w.Header().Add(warningHeader, fmt.Sprintf("%s; item=%d", ErrCodeChangeNotRecorded, item))
+}
+
+// Handles requests that don't match any route
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	log.Debug("No route for URL: %s", r.URL.RequestURI())
+	writeError(w, 404, ErrCodeNotFound, "No such endpoint")
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"sync"
+	"time"
+)
+
+// The actions recorded in the change feed
+const (
+	EventGrant  = "grant"
+	EventDeny   = "deny"
+	EventRevoke = "revoke"
+	EventSet    = "set"
+	EventDelete = "delete"
+)
+
+/*
+A change made to an ACL, as recorded in the event log and sent to watchers.  Seq orders
+the events and is the token watchers resume from.  Privileges holds the privilege names of
+a grant, deny or revoke, the new privileges of a set, and is empty for a delete.
+*/
+type ACLEvent struct {
+	Seq        int64       `json:"seq" bson:"seq"`
+	Service    string      `json:"service" bson:"service"`
+	Object     string      `json:"object" bson:"object"`
+	Key        string      `json:"key" bson:"key"`
+	User       string      `json:"user" bson:"user"`
+	Action     string      `json:"action" bson:"action"`
+	Privileges interface{} `json:"privileges,omitempty" bson:"privileges,omitempty"`
+	Time       time.Time   `json:"time" bson:"time"`
+}
+
+// Gets the event log collection in the database of the ACL collection
+func eventCollection(c *mgo.Collection) *mgo.Collection {
+	mongo, _ := Application.Config["mongo"].(map[string]interface{})
+	name, ok := mongo["events_collection"].(string)
+	if !ok {
+		name = "acl_events"
+	}
+	return c.Database.C(name)
+}
+
+// Creates an Index on the event log collection
+func (e ACLEvent) EnsureIndex(c *mgo.Collection) error {
+	index := mgo.Index{
+		Key:        []string{"service", "object", "seq"},
+		Unique:     false,
+		DropDups:   false,
+		Background: false,
+		Sparse:     false,
+	}
+	return eventCollection(c).EnsureIndex(index)
+}
+
+// How long a gap in the sequence numbers is waited on before the missing events are taken
+// as lost, e.g. because the ACL write they followed failed to record them
+const eventGapTimeout = 5 * time.Second
+
+// Selects the sequence counter of the event log of a service/object
+func counterSelector(c *mgo.Collection, service string, object string) bson.M {
+	return bson.M{"counter": eventCollection(c).Name, "service": service, "object": object}
+}
+
+// Allocates the next sequence number of the event log of a service/object
+func nextEventSeq(c *mgo.Collection, service string, object string) (int64, error) {
+	counter := struct {
+		Seq int64 `bson:"seq"`
+	}{}
+	change := mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"seq": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}
+	_, err := c.Database.C("counters").Find(counterSelector(c, service, object)).Apply(change, &counter)
+	return counter.Seq, err
+}
+
+// Gets the sequence number of the latest event on a service/object, or 0 if nothing was
+// recorded yet
+func latestEventSeq(c *mgo.Collection, service string, object string) (int64, error) {
+	counter := struct {
+		Seq int64 `bson:"seq"`
+	}{}
+	err := c.Database.C("counters").Find(counterSelector(c, service, object)).One(&counter)
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return counter.Seq, err
+}
+
+// Records an event in the event log, wakes the watchers of the service/object and queues
+// the event for its webhooks
+func recordEvent(c *mgo.Collection, service string, object string, key string, user string,
+	action string, privileges interface{}) error {
+	seq, err := nextEventSeq(c, service, object)
+	if err != nil {
+		return err
+	}
+
+	event := ACLEvent{
+		Seq:        seq,
+		Service:    service,
+		Object:     object,
+		Key:        key,
+		User:       user,
+		Action:     action,
+		Privileges: privileges,
+		Time:       time.Now().UTC(),
+	}
+
+	log.Finest("Recording ACL event: %+v", event)
+	err = eventCollection(c).Insert(event)
+	if err != nil {
+		return err
+	}
+
+	broker.publish(service, object)
+
+	// The event is in the log, so a failure to queue webhooks shouldn't fail the write
+	err = enqueueWebhooks(c, event)
+	if err != nil {
+		log.Error("An error occurred queueing webhooks for event %d on %s/%s: %s", seq, service, object, err)
+	}
+	return nil
+}
+
+// Reads up to limit events on a service/object after the sequence number, optionally only
+// returning those on a key.  Events are only read in sequence, so one still being recorded
+// is never skipped.  Returns the sequence number to read from next.
+func eventsSince(c *mgo.Collection, service string, object string, key string, seq int64,
+	limit int) ([]ACLEvent, int64, error) {
+	selector := bson.M{"service": service, "object": object, "seq": bson.M{"$gt": seq}}
+
+	logged := []ACLEvent{}
+	err := eventCollection(c).Find(selector).Sort("seq").Limit(limit).All(&logged)
+	if err != nil {
+		return nil, seq, err
+	}
+
+	result := []ACLEvent{}
+	for _, event := range logged {
+		if event.Seq != seq+1 && time.Since(event.Time) < eventGapTimeout {
+			break
+		}
+		seq = event.Seq
+		if key == "" || event.Key == key {
+			result = append(result, event)
+		}
+	}
+	return result, seq, nil
+}
+
+// A service/object watchers subscribe to
+type eventTopic struct {
+	service string
+	object  string
+}
+
+/*
+Wakes the watchers of a service/object in this process when an event is recorded on it.
+The event log is the source of truth: a notification only tells a watcher to read the log
+now rather than at its next poll, which is how it sees events recorded by other processes.
+*/
+type eventBroker struct {
+	mu       sync.Mutex
+	watchers map[chan struct{}]eventTopic
+}
+
+// The broker of this process
+var broker = &eventBroker{watchers: map[chan struct{}]eventTopic{}}
+
+// Subscribes to the events of a service/object.  The channel receives a value when new
+// events may be in the log.
+func (b *eventBroker) subscribe(service string, object string) chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	notify := make(chan struct{}, 1)
+	b.watchers[notify] = eventTopic{service, object}
+	return notify
+}
+
+// Stops notifying the channel
+func (b *eventBroker) unsubscribe(notify chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.watchers, notify)
+}
+
+// Notifies the watchers of a service/object without blocking.  A watcher that already has a
+// notification pending doesn't need another.
+func (b *eventBroker) publish(service string, object string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topic := eventTopic{service, object}
+	for notify, watched := range b.watchers {
+		if watched != topic {
+			continue
+		}
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,790 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"context"
+	"fmt"
+	pb "github.com/johnnadratowski/authorizer/authorizerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// The address the gRPC API listens on.  It is only served when the config sets a port.
+var grpcAddress = ""
+
+// Reads the gRPC listen address from the "grpc" section of the config
+func configureGRPC() {
+	config, ok := Application.Config["grpc"].(map[string]interface{})
+	if !ok {
+		log.Info("No gRPC settings in config, the gRPC API is disabled")
+		return
+	}
+
+	port, ok := config["port"].(string)
+	if !ok || port == "" {
+		log.Info("gRPC port not specified, the gRPC API is disabled")
+		return
+	}
+	host, _ := config["host"].(string)
+
+	grpcAddress = net.JoinHostPort(host, port)
+	log.Info("Using gRPC address '%s' from config", grpcAddress)
+}
+
+// Starts serving the gRPC API in the background, if it is enabled
+func startGRPC() error {
+	if grpcAddress == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		log.Error("An error occurred listening for gRPC on %s: %s", grpcAddress, err)
+		return err
+	}
+
+	server := newGRPCServer()
+
+	log.Info("Serving gRPC on %s", grpcAddress)
+	go func() {
+		err := server.Serve(listener)
+		if err != nil {
+			log.Error("gRPC server terminated: %s", err)
+		}
+	}()
+	return nil
+}
+
+// Creates the gRPC server, authenticating calls as the REST API does
+func newGRPCServer() *grpc.Server {
+	options := []grpc.ServerOption{grpc.UnaryInterceptor(grpcAuthUnary), grpc.StreamInterceptor(grpcAuthStream)}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(options...)
+	pb.RegisterAuthorizerServer(server, &grpcServer{})
+	return server
+}
+
+// The REST route of each RPC, whose operation and privileges the RPC needs
+var grpcRoutes = map[string]string{
+	"ListServices": "ListServices",
+	"ListObjects":  "ListObjects",
+	"Grant":        "GrantACL",
+	"Deny":         "DenyACL",
+	"Revoke":       "RevokeACL",
+	"Set":          "SetACL",
+	"Check":        "HasACL",
+	"Get":          "GetACL",
+	"List":         "ListACL",
+	"Match":        "MatchACL",
+	"ListUser":     "UserACL",
+	"Copy":         "CopyACL",
+	"Move":         "MoveACL",
+	"Clone":        "CloneACL",
+	"Watch":        "WatchACL",
+}
+
+// Authenticates a call from its metadata and checks the caller may perform the RPC on the
+// service of the request message.  Returns the caller, or nil when authentication is disabled.
+func grpcAuthorize(ctx context.Context, method string, req interface{}) (*Caller, error) {
+	if !authEnabled {
+		return nil, nil
+	}
+
+	route := grpcRoutes[path.Base(method)]
+	operation, ok := routeOperations[route]
+	if !ok {
+		operation = OperationAdmin
+	}
+	service := ""
+	if message, ok := req.(interface{ GetService() string }); ok {
+		service = message.GetService()
+	}
+
+	// The authenticators read credentials from a request, so the metadata is passed as its
+	// headers and the peer's TLS state as its own
+	r := &http.Request{Header: http.Header{}}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for name, values := range md {
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	caller, apiErr := authenticate(r, c)
+	if apiErr != nil {
+		log.Debug("Unauthenticated call to %s: %s", method, apiErr)
+		return nil, grpcError(apiErr)
+	}
+
+	if !caller.allows(operation, service) {
+		log.Debug("Caller %s may not %s service '%s'", caller.ID, operation, service)
+		return nil, grpcError(&APIError{Code: ErrCodeForbidden, Message: "Caller may not " + operation + " this service"})
+	}
+	if apiErr = authorizeAdmin(c, caller, route, service); apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+	return caller, nil
+}
+
+// The key of the actor of a call in its context
+type actorKey struct{}
+
+// Gets the actor making a call
+func grpcActor(ctx context.Context) *Actor {
+	if actor, ok := ctx.Value(actorKey{}).(*Actor); ok {
+		return actor
+	}
+	return callerActor(nil, "")
+}
+
+// Gets the request ID a call's metadata carries, or a new one
+func grpcRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := ""
+	if values := md.Get("x-request-id"); len(values) > 0 {
+		id = values[0]
+	}
+	return incomingRequestID(id)
+}
+
+// Authorizes unary calls, and sets their actor and request ID
+func grpcAuthUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	id := grpcRequestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", id))
+
+	caller, err := grpcAuthorize(ctx, info.FullMethod, req)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, actorKey{}, callerActor(caller, id)), req)
+}
+
+// Authorizes streaming calls once their request message is received
+func grpcAuthStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	id := grpcRequestID(stream.Context())
+	stream.SetHeader(metadata.Pairs("x-request-id", id))
+	return handler(srv, &authorizedStream{ServerStream: stream, method: info.FullMethod, requestID: id,
+		ctx: stream.Context()})
+}
+
+// A server stream that authorizes the call with its first request message, and then
+// carries its actor in its context
+type authorizedStream struct {
+	grpc.ServerStream
+	method     string
+	requestID  string
+	ctx        context.Context
+	authorized bool
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if !s.authorized {
+		caller, err := grpcAuthorize(s.ServerStream.Context(), s.method, m)
+		if err != nil {
+			return err
+		}
+		s.ctx = context.WithValue(s.ServerStream.Context(), actorKey{}, callerActor(caller, s.requestID))
+		s.authorized = true
+	}
+	return nil
+}
+
+// Implements the gRPC API on the same model layer as the REST handlers
+type grpcServer struct {
+	pb.UnimplementedAuthorizerServer
+}
+
+// Converts an API error to a gRPC status error, keeping its code in the message
+func grpcError(apiErr *APIError) error {
+	code := codes.InvalidArgument
+	switch apiErr.Code {
+	case ErrCodeNotFound:
+		code = codes.NotFound
+	case ErrCodeStorage, ErrCodeInternal:
+		code = codes.Internal
+	case ErrCodeUnavailable:
+		code = codes.Unavailable
+	case ErrCodeUnauthenticated:
+		code = codes.Unauthenticated
+	case ErrCodeForbidden:
+		code = codes.PermissionDenied
+	}
+
+	message := apiErr.Error()
+	if apiErr.Item != nil {
+		message = fmt.Sprintf("%s (item %d, field %q)", message, *apiErr.Item, apiErr.Field)
+	}
+	return status.Error(code, message)
+}
+
+// Warns in the header of a successful call that the change of a request item was made, but
+// not recorded in the audit, event or history logs
+func grpcWarnNotRecorded(ctx context.Context, idx int) {
+	grpc.SetHeader(ctx, metadata.Pairs("x-authorizer-warning",
+		fmt.Sprintf("%s; item=%d", ErrCodeChangeNotRecorded, idx)))
+}
+
+// A storage error for an item of a request
+func grpcStorageError(message string, idx int, err error) error {
+	log.Error("%s. Item: %d\nMessage: %s", message, idx, err)
+	return grpcError(&APIError{Code: ErrCodeStorage, Message: message, Item: &idx})
+}
+
+// Gets a Mongo session and the ACL collection for a call.  The session must be closed.
+func grpcCollection() (*mgo.Session, *mgo.Collection, error) {
+	session, _, c, err := getMongo()
+	if err != nil {
+		return nil, nil, grpcError(&APIError{Code: ErrCodeUnavailable, Message: "There was an error, please try again"})
+	}
+	return session, c, nil
+}
+
+// Checks the number of items in a request and validates each, as getItems does for REST
+// request bodies
+func validateItems(count int, item func(idx int) requestItem) error {
+	if maxBatchItems > 0 && count > maxBatchItems {
+		return grpcError(&APIError{Code: ErrCodeTooManyItems, Message: "Request has too many items"})
+	}
+	for idx := 0; idx < count; idx++ {
+		if apiErr := item(idx).validate(); apiErr != nil {
+			apiErr.Item = &idx
+			return grpcError(apiErr)
+		}
+	}
+	return nil
+}
+
+// Converts stored privileges to the string map of the gRPC messages
+func privilegeStrings(privileges map[string]interface{}) map[string]string {
+	result := map[string]string{}
+	for privilege, value := range privileges {
+		if str, ok := value.(string); ok {
+			result[privilege] = str
+		} else {
+			result[privilege] = fmt.Sprint(value)
+		}
+	}
+	return result
+}
+
+// Converts an ACL to its gRPC message
+func aclMessage(acl ACL) *pb.ACL {
+	return &pb.ACL{
+		Service:    acl.Service,
+		Object:     acl.Object,
+		Key:        acl.Key,
+		User:       acl.User,
+		Privileges: privilegeStrings(acl.Privileges),
+	}
+}
+
+// Converts a privilege list item to the item type REST requests are validated with.
+// Protobuf can't tell an empty list from a missing one, so privileges are never missing.
+func privilegeListItemOf(item *pb.PrivilegeItem) *privilegeListItem {
+	return &privilegeListItem{Key: item.Key, User: item.User, Privileges: append([]string{}, item.Privileges...)}
+}
+
+func (s *grpcServer) ListServices(ctx context.Context, req *pb.ListServicesRequest) (*pb.ListServicesResponse, error) {
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := ACL{}.ListServices(c)
+	if err != nil {
+		log.Error("An error occurred getting list of Services. Message: %s", err)
+		return nil, grpcError(&APIError{Code: ErrCodeStorage, Message: "An error occurred getting services list"})
+	}
+	return &pb.ListServicesResponse{Services: result}, nil
+}
+
+func (s *grpcServer) ListObjects(ctx context.Context, req *pb.ListObjectsRequest) (*pb.ListObjectsResponse, error) {
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := ACL{}.ListObjects(c, req.Service)
+	if err != nil {
+		log.Error("An error occurred getting list of Objects. Message: %s", err)
+		return nil, grpcError(&APIError{Code: ErrCodeStorage, Message: "An error occurred getting objects list"})
+	}
+	return &pb.ListObjectsResponse{Objects: result}, nil
+}
+
+// Applies a grant, deny or revoke to each item of the request
+func (s *grpcServer) writePrivileges(ctx context.Context, req *pb.PrivilegesRequest, action string,
+	write func(c *mgo.Collection, item *pb.PrivilegeItem) error) (*pb.WriteResponse, error) {
+	err := validateItems(len(req.Items), func(idx int) requestItem { return privilegeListItemOf(req.Items[idx]) })
+	if err != nil {
+		return nil, err
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	for idx, item := range req.Items {
+		err = write(c, item)
+		if err == ErrChangeNotRecorded {
+			grpcWarnNotRecorded(ctx, idx)
+		} else if err != nil {
+			return nil, grpcStorageError("An error occurred "+action+" privileges", idx, err)
+		}
+	}
+	return &pb.WriteResponse{}, nil
+}
+
+func (s *grpcServer) Grant(ctx context.Context, req *pb.PrivilegesRequest) (*pb.WriteResponse, error) {
+	return s.writePrivileges(ctx, req, "granting", func(c *mgo.Collection, item *pb.PrivilegeItem) error {
+		_, err := ACL{Actor: grpcActor(ctx)}.Grant(c, req.Service, req.Object, item.Key, item.User, item.Privileges)
+		return err
+	})
+}
+
+func (s *grpcServer) Deny(ctx context.Context, req *pb.PrivilegesRequest) (*pb.WriteResponse, error) {
+	return s.writePrivileges(ctx, req, "denying", func(c *mgo.Collection, item *pb.PrivilegeItem) error {
+		_, err := ACL{Actor: grpcActor(ctx)}.Deny(c, req.Service, req.Object, item.Key, item.User, item.Privileges)
+		return err
+	})
+}
+
+func (s *grpcServer) Revoke(ctx context.Context, req *pb.PrivilegesRequest) (*pb.WriteResponse, error) {
+	return s.writePrivileges(ctx, req, "revoking", func(c *mgo.Collection, item *pb.PrivilegeItem) error {
+		_, err := ACL{Actor: grpcActor(ctx)}.Revoke(c, req.Service, req.Object, item.Key, item.User, item.Privileges)
+		return err
+	})
+}
+
+func (s *grpcServer) Set(ctx context.Context, req *pb.SetRequest) (*pb.WriteResponse, error) {
+	items := make([]*privilegeMapItem, len(req.Items))
+	for idx, item := range req.Items {
+		privileges := map[string]interface{}{}
+		for privilege, value := range item.Privileges {
+			privileges[privilege] = value
+		}
+		items[idx] = &privilegeMapItem{Key: item.Key, User: item.User, Privileges: privileges}
+	}
+
+	err := validateItems(len(items), func(idx int) requestItem { return items[idx] })
+	if err != nil {
+		return nil, err
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	for idx, item := range items {
+		_, err = ACL{Actor: grpcActor(ctx)}.Set(c, req.Service, req.Object, item.Key, item.User, item.Privileges)
+		if err == ErrChangeNotRecorded {
+			grpcWarnNotRecorded(ctx, idx)
+		} else if err != nil {
+			return nil, grpcStorageError("An error occurred setting privileges", idx, err)
+		}
+	}
+	return &pb.WriteResponse{}, nil
+}
+
+// Converts an explanation to its gRPC message
+func explanationMessage(explanation Explanation) *pb.Explanation {
+	result := &pb.Explanation{Decision: explanation.Decision, Reason: explanation.Reason}
+	for _, source := range explanation.Sources {
+		result.Sources = append(result.Sources, &pb.ExplanationSource{
+			Service: source.Service,
+			Object:  source.Object,
+			Key:     source.Key,
+			User:    source.User,
+			Found:   source.Found,
+		})
+	}
+	for _, privilege := range explanation.Privileges {
+		value := ""
+		if privilege.Value != nil {
+			value = fmt.Sprint(privilege.Value)
+		}
+		result.Privileges = append(result.Privileges, &pb.PrivilegeExplanation{
+			Privilege: privilege.Privilege,
+			Decision:  privilege.Decision,
+			Reason:    privilege.Reason,
+			Value:     value,
+		})
+	}
+	return result
+}
+
+func (s *grpcServer) Check(ctx context.Context, req *pb.CheckRequest) (*pb.CheckResponse, error) {
+	err := validateItems(len(req.Items), func(idx int) requestItem { return privilegeListItemOf(req.Items[idx]) })
+	if err != nil {
+		return nil, err
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result := &pb.CheckResponse{Decisions: make([]*pb.Decision, len(req.Items))}
+	for idx, check := range req.Items {
+		decision := &pb.Decision{Key: check.Key, User: check.User}
+
+		if req.Explain {
+			explanation, err := ACL{}.Explain(c, req.Service, req.Object, check.Key, check.User, check.Privileges)
+			if err != nil {
+				return nil, grpcStorageError("An error occurred getting privileges", idx, err)
+			}
+			decision.Privilege = explanation.Decision
+			decision.Explanation = explanationMessage(explanation)
+		} else {
+			err := ACL{}.Has(c, req.Service, req.Object, check.Key, check.User, check.Privileges)
+			if err == nil {
+				decision.Privilege = "allow"
+			} else if err == mgo.ErrNotFound {
+				decision.Privilege = "deny"
+			} else {
+				return nil, grpcStorageError("An error occurred getting privileges", idx, err)
+			}
+		}
+
+		result.Decisions[idx] = decision
+	}
+	return result, nil
+}
+
+func (s *grpcServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	err := validateItems(len(req.Items), func(idx int) requestItem {
+		return &keyUserItem{Key: req.Items[idx].Key, User: req.Items[idx].User}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result := &pb.GetResponse{Privileges: make([]*pb.Privileges, len(req.Items))}
+	for idx, get := range req.Items {
+		acl, err := ACL{}.Get(c, req.Service, req.Object, get.Key, get.User)
+		if err != nil && err != mgo.ErrNotFound {
+			return nil, grpcStorageError("An error occurred getting privileges", idx, err)
+		}
+		result.Privileges[idx] = &pb.Privileges{Key: get.Key, User: get.User, Privileges: privilegeStrings(acl.Privileges)}
+	}
+	return result, nil
+}
+
+func (s *grpcServer) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	if req.PrivilegeValue != "" && len(req.Privileges) == 0 {
+		return nil, grpcError(&APIError{Code: ErrCodeInvalidParameter,
+			Message: "privilege_value requires a privilege to filter on"})
+	}
+	if req.Limit < 0 {
+		return nil, grpcError(&APIError{Code: ErrCodeInvalidParameter, Message: "limit must be a positive integer"})
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	page := Page{Limit: int(req.Limit), Cursor: req.Cursor}
+	acls, next, err := ACL{}.List(c, req.Service, req.Object, req.Key, req.User, req.Privileges, req.PrivilegeValue, page)
+	if err == ErrInvalidCursor {
+		return nil, grpcError(&APIError{Code: ErrCodeInvalidCursor, Message: "Invalid cursor"})
+	} else if err != nil && err != mgo.ErrNotFound {
+		log.Error("An error occurred getting list of ACLs. Message: %s", err)
+		return nil, grpcError(&APIError{Code: ErrCodeStorage, Message: "An error occurred getting privilege list"})
+	}
+
+	result := &pb.ListResponse{NextCursor: next}
+	for _, acl := range acls {
+		result.Acls = append(result.Acls, aclMessage(acl))
+	}
+	return result, nil
+}
+
+// Streams the matched keys for each item, followed by a result holding the next cursor when
+// a paged item's results continue, like a newline delimited JSON match
+func (s *grpcServer) Match(req *pb.MatchRequest, stream pb.Authorizer_MatchServer) error {
+	items := make([]*matchItem, len(req.Items))
+	for idx, item := range req.Items {
+		items[idx] = &matchItem{User: item.User, Privileges: append([]string{}, item.Privileges...), Value: item.Value,
+			Limit: int(item.Limit), Cursor: item.Cursor}
+	}
+
+	err := validateItems(len(items), func(idx int) requestItem { return items[idx] })
+	if err != nil {
+		return err
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	for idx, match := range items {
+		iter, err := ACL{}.MatchIter(c, req.Service, req.Object, match.User, match.Privileges, match.Value, match.page())
+		if err != nil {
+			return grpcStorageError("An error occurred matching privileges", idx, err)
+		}
+
+		count := 0
+		last := ""
+		next := ""
+		acl := ACL{}
+		for iter.Next(&acl) {
+			if match.Limit > 0 && count == match.Limit {
+				next = Cursor{Key: last}.String()
+				break
+			}
+
+			err = stream.Send(&pb.MatchResult{User: match.User, Key: acl.Key})
+			if err != nil {
+				break
+			}
+
+			count++
+			last = acl.Key
+			acl = ACL{}
+		}
+
+		if closeErr := iter.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return grpcStorageError("An error occurred matching privileges", idx, err)
+		}
+
+		if next != "" {
+			err = stream.Send(&pb.MatchResult{User: match.User, NextCursor: next})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *grpcServer) ListUser(ctx context.Context, req *pb.ListUserRequest) (*pb.ListUserResponse, error) {
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result := &pb.ListUserResponse{}
+	iter := ACL{}.ListUser(c, req.User, req.Service, req.Object, req.Privileges)
+	acl := ACL{}
+	for iter.Next(&acl) {
+		result.Acls = append(result.Acls, aclMessage(acl))
+		acl = ACL{}
+	}
+	if err := iter.Close(); err != nil {
+		log.Error("An error occurred getting user ACL report. Message: %s", err)
+		return nil, grpcError(&APIError{Code: ErrCodeStorage, Message: "An error occurred getting privilege list"})
+	}
+	return result, nil
+}
+
+// Converts planned or applied changes to their gRPC messages
+func changeMessages(changes []ACLChange) []*pb.ACLChange {
+	result := []*pb.ACLChange{}
+	for _, change := range changes {
+		result = append(result, &pb.ACLChange{
+			Key:     change.Key,
+			User:    change.User,
+			Action:  change.Action,
+			Before:  privilegeStrings(change.Before),
+			After:   privilegeStrings(change.After),
+			Existed: change.Before != nil,
+		})
+	}
+	return result
+}
+
+// Copies or moves all ACLs from one key to another for each item
+func (s *grpcServer) copyKeys(ctx context.Context, req *pb.CopyRequest, move bool) (*pb.ChangesResponse, error) {
+	err := validateItems(len(req.Items), func(idx int) requestItem {
+		return &copyItem{FromKey: req.Items[idx].FromKey, ToKey: req.Items[idx].ToKey}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result := &pb.ChangesResponse{}
+	for idx, item := range req.Items {
+		changes, err := ACL{Actor: grpcActor(ctx)}.CopyKey(c, req.Service, req.Object, item.FromKey, item.ToKey, move, req.DryRun)
+		if err == ErrChangeNotRecorded {
+			grpcWarnNotRecorded(ctx, idx)
+		} else if err != nil {
+			return nil, grpcStorageError("An error occurred copying privileges", idx, err)
+		}
+		result.Results = append(result.Results, &pb.ChangeResult{
+			FromKey: item.FromKey,
+			ToKey:   item.ToKey,
+			DryRun:  req.DryRun,
+			Changes: changeMessages(changes),
+		})
+	}
+	return result, nil
+}
+
+func (s *grpcServer) Copy(ctx context.Context, req *pb.CopyRequest) (*pb.ChangesResponse, error) {
+	return s.copyKeys(ctx, req, false)
+}
+
+func (s *grpcServer) Move(ctx context.Context, req *pb.CopyRequest) (*pb.ChangesResponse, error) {
+	return s.copyKeys(ctx, req, true)
+}
+
+func (s *grpcServer) Clone(ctx context.Context, req *pb.CloneRequest) (*pb.ChangesResponse, error) {
+	err := validateItems(len(req.Items), func(idx int) requestItem {
+		item := req.Items[idx]
+		return &cloneItem{FromUser: item.FromUser, ToUser: item.ToUser, Key: item.Key}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session, c, err := grpcCollection()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result := &pb.ChangesResponse{}
+	for idx, item := range req.Items {
+		changes, err := ACL{Actor: grpcActor(ctx)}.CloneUser(c, req.Service, req.Object, item.Key, item.FromUser, item.ToUser, req.DryRun)
+		if err == ErrChangeNotRecorded {
+			grpcWarnNotRecorded(ctx, idx)
+		} else if err != nil {
+			return nil, grpcStorageError("An error occurred cloning privileges", idx, err)
+		}
+		result.Results = append(result.Results, &pb.ChangeResult{
+			FromUser: item.FromUser,
+			ToUser:   item.ToUser,
+			Key:      item.Key,
+			DryRun:   req.DryRun,
+			Changes:  changeMessages(changes),
+		})
+	}
+	return result, nil
+}
+
+// Converts an event to its gRPC message
+func eventMessage(event ACLEvent) *pb.ACLEvent {
+	result := &pb.ACLEvent{
+		Seq:     event.Seq,
+		Service: event.Service,
+		Object:  event.Object,
+		Key:     event.Key,
+		User:    event.User,
+		Action:  event.Action,
+		Time:    timestamppb.New(event.Time),
+	}
+
+	switch privileges := event.Privileges.(type) {
+	case []string:
+		result.PrivilegeNames = privileges
+	case []interface{}:
+		for _, privilege := range privileges {
+			result.PrivilegeNames = append(result.PrivilegeNames, fmt.Sprint(privilege))
+		}
+	case map[string]interface{}:
+		result.Privileges = privilegeStrings(privileges)
+	case bson.M:
+		result.Privileges = privilegeStrings(privileges)
+	}
+	return result
+}
+
+// Streams the changes made to the ACLs of an object until the client cancels the call
+func (s *grpcServer) Watch(req *pb.WatchRequest, stream pb.Authorizer_WatchServer) error {
+	session, c, err := grpcCollection()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	var seq int64
+	if req.Since == "" {
+		seq, err = latestEventSeq(c, req.Service, req.Object)
+		if err != nil {
+			log.Error("An error occurred getting the latest event. Message: %s", err)
+			return grpcError(&APIError{Code: ErrCodeStorage, Message: "An error occurred watching privileges"})
+		}
+	} else {
+		seq, err = strconv.ParseInt(req.Since, 10, 64)
+		if err != nil || seq < 0 {
+			return grpcError(&APIError{Code: ErrCodeInvalidParameter,
+				Message: "since must be a sequence token returned by watch"})
+		}
+	}
+
+	send := func(events []ACLEvent) error {
+		for _, event := range events {
+			if err := stream.Send(eventMessage(event)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = followEvents(stream.Context(), c, req.Service, req.Object, req.Key, seq, send, nil)
+	if err != nil && stream.Context().Err() == nil {
+		log.Error("An error occurred watching privileges. Message: %s", err)
+		return grpcError(&APIError{Code: ErrCodeStorage, Message: "An error occurred watching privileges"})
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/johnnadratowski/authorizer/authorizerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"labix.org/v2/mgo"
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+// Runs the gRPC API over an in-memory connection
+func testGRPC(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	fmt.Println("Testing gRPC API")
+	ctx := context.Background()
+
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer()
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal("Error connecting to gRPC server: ", err)
+	}
+	defer conn.Close()
+	api := pb.NewAuthorizerClient(conn)
+
+	_, err = api.Grant(ctx, &pb.PrivilegesRequest{Service: "service4", Object: "object1", Items: []*pb.PrivilegeItem{
+		{Key: "1", User: "john", Privileges: []string{"read", "write"}},
+		{Key: "2", User: "john", Privileges: []string{"read"}},
+	}})
+	if err != nil {
+		t.Fatal("Error granting over gRPC: ", err)
+	}
+
+	_, err = api.Grant(ctx, &pb.PrivilegesRequest{Service: "service4", Object: "object1", Items: []*pb.PrivilegeItem{
+		{Key: "1", Privileges: []string{"read"}},
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("Grant without a user should be an invalid argument, got: ", err)
+	}
+
+	check, err := api.Check(ctx, &pb.CheckRequest{Service: "service4", Object: "object1", Items: []*pb.PrivilegeItem{
+		{Key: "1", User: "john", Privileges: []string{"write"}},
+		{Key: "2", User: "john", Privileges: []string{"write"}},
+	}, Explain: true})
+	if err != nil {
+		t.Fatal("Error checking over gRPC: ", err)
+	}
+	if len(check.Decisions) != 2 || check.Decisions[0].Privilege != "allow" || check.Decisions[1].Privilege != "deny" {
+		t.Fatal("Incorrect decisions over gRPC: ", check.Decisions)
+	}
+	if check.Decisions[1].Explanation.Reason != ReasonNotGranted {
+		t.Fatal("Incorrect explanation over gRPC: ", check.Decisions[1].Explanation)
+	}
+
+	stream, err := api.Match(ctx, &pb.MatchRequest{Service: "service4", Object: "object1", Items: []*pb.MatchItem{
+		{User: "john", Privileges: []string{"read"}, Limit: 1},
+	}})
+	if err != nil {
+		t.Fatal("Error matching over gRPC: ", err)
+	}
+	results := []*pb.MatchResult{}
+	for {
+		result, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("Error receiving matches over gRPC: ", err)
+		}
+		results = append(results, result)
+	}
+	if len(results) != 2 || results[0].Key != "1" || results[1].NextCursor == "" {
+		t.Fatal("Incorrect matches over gRPC: ", results)
+	}
+
+	list, err := api.List(ctx, &pb.ListRequest{Service: "service4", Object: "object1", Key: "1"})
+	if err != nil {
+		t.Fatal("Error listing over gRPC: ", err)
+	}
+	if len(list.Acls) != 1 || list.Acls[0].Privileges["write"] != "allow" {
+		t.Fatal("Incorrect ACLs listed over gRPC: ", list.Acls)
+	}
+
+	testGRPCAuth(t, api, c)
+}
+
+// Checks calls are authenticated with API keys from their metadata
+func testGRPCAuth(t *testing.T, api pb.AuthorizerClient, c *mgo.Collection) {
+	enabled := authEnabled
+	authEnabled = true
+	defer func() {
+		authEnabled = enabled
+	}()
+
+	key, err := APIKey{}.Create(c, apiKeyItem{Name: "grpc", Services: []string{"service4"}, Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal("Error creating API key: ", err)
+	}
+
+	_, err = api.Check(context.Background(), &pb.CheckRequest{Service: "service4", Object: "object1"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal("Expected unauthenticated gRPC call to fail. Got: ", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key.Key)
+	_, err = api.Check(ctx, &pb.CheckRequest{Service: "service4", Object: "object1"})
+	if err != nil {
+		t.Fatal("Error checking over gRPC with API key: ", err)
+	}
+
+	_, err = api.Grant(ctx, &pb.PrivilegesRequest{Service: "service4", Object: "object1", Items: []*pb.PrivilegeItem{
+		{User: "john", Key: "1", Privileges: []string{"read"}},
+	}})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected gRPC grant with read key to be denied. Got: ", err)
+	}
+
+	stream, err := api.Match(ctx, &pb.MatchRequest{Service: "service5", Object: "object1"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected gRPC match on another service to be denied. Got: ", err)
+	}
+}
@@ -5,37 +5,14 @@ import (
 	"encoding/json"
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
-	"io/ioutil"
 	"labix.org/v2/mgo"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
-// Gets the body of the request
-func getBody(w http.ResponseWriter, r *http.Request) ([]map[string]interface{}, error) {
-
-	bodyBytes, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		log.Error("An error occurred reading request body. Message: %s", err)
-		http.Error(w, "An error occurred reading request body", 500)
-		return nil, err
-	}
-
-	log.Debug("Request Body: %s", bodyBytes)
-
-	var body []map[string]interface{}
-	err = json.Unmarshal(bodyBytes, &body)
-	if err != nil {
-		log.Debug("An error occurrect parsing request body. Message: %s", err)
-		http.Error(w, "Could not parse request body.  Seems to be malformed JSON.", 500)
-		return nil, err
-	}
-
-	return body, nil
-}
-
 // Gets common data from a request for certain request handlers
-func getRequestData(w http.ResponseWriter, r *http.Request, processBody bool) (*mgo.Collection,
-	string, string, []map[string]interface{}, error) {
+func getRequestData(r *http.Request) (*mgo.Collection, string, string) {
 	c := context.Get(r, "mongoColl").(*mgo.Collection)
 	vars := mux.Vars(r)
 	service := vars["service"]
@@ -43,72 +20,7 @@ func getRequestData(w http.ResponseWriter, r *http.Request, processBody bool) (*
 
 	log.Finest("Request URL Info: service: %s object: %s", service, object)
 
-	if processBody {
-		body, err := getBody(w, r)
-		return c, service, object, body, err
-	} else {
-		return c, service, object, nil, nil
-	}
-}
-
-// Gets data from a request body for processing grant/revoke
-func getItemData(w http.ResponseWriter, val interface{},
-	parsePrivilege bool, parseKey bool) (map[string]interface{}, string, string, []string) {
-	values, ok := val.(map[string]interface{})
-	if !ok {
-		log.Debug("An error occurred getting body value data")
-		http.Error(w, "An error occurred getting body value data", 500)
-		return nil, "", "", nil
-	}
-
-	key := ""
-	if parseKey {
-		key, ok = values["key"].(string)
-		if !ok {
-			log.Debug("Missing key from an item")
-			http.Error(w, "Missing key from an item", 400)
-			return nil, "", "", nil
-		}
-	}
-
-	user, ok := values["user"].(string)
-	if !ok {
-		log.Debug("Missing user from an item")
-		http.Error(w, "Missing user from an item", 400)
-		return nil, "", "", nil
-	}
-
-	if !parsePrivilege {
-		log.Debug("Item Info: User: %s,  Key %s", user, key)
-		return values, key, user, nil
-	} else {
-		p, ok := values["privileges"].([]interface{})
-		if !ok {
-			log.Debug("Missing privileges from an item")
-			http.Error(w, "Missing privileges from an item", 400)
-			return nil, "", "", nil
-		}
-
-		privileges, ok := interfaceSliceToStr(p)
-		if !ok {
-			log.Debug("Missing privileges from an item")
-			http.Error(w, "Missing privileges from an item", 400)
-			return nil, "", "", nil
-		}
-		log.Debug("Item Info: User: %s,  Key: %s,  Privileges; %s", user, key, privileges)
-		return values, key, user, privileges
-	}
-}
-
-// Gets the privileges from the request body values when privilegs is a map and not a list
-func getPrivilegeMap(w http.ResponseWriter, values map[string]interface{}) map[string]interface{} {
-	privileges, ok := values["privileges"].(map[string]interface{})
-	if !ok {
-		log.Debug("Missing privileges from an item")
-		http.Error(w, "Missing privileges from an item", 400)
-		return nil
-	}
-	return privileges
+	return c, service, object
 }
 
 // This is a URL handler that handles updating permissions for a user on an object
@@ -116,28 +28,36 @@ func grantPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Finest("Inside grant privileges.")
 
-	c, service, object, body, err := getRequestData(w, r, true)
-	if err != nil {
-		// Already responded in getBody call
+	c, service, object := getRequestData(r)
+	actor := requestActor(r)
+
+	items := []privilegeListItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+	if revision, ok := ifMatch(w, r, len(items)); !ok {
+		return
+	} else if revision > 0 && !matchIfRevision(w, &items[0].IfRevision, revision) {
 		return
 	}
 
-	for _, val := range body {
-
-		_, key, user, privileges := getItemData(w, val, true, true)
-		if key == "" || user == "" {
-			// Couldn't get proper data from body.  Already responded in getItemData call
-			return
-		}
+	for idx, item := range items {
 
 		log.Finest("Granting privilege")
 
-		_, err = ACL{}.Grant(c, service, object, key, user, privileges)
+		_, err := ACL{Actor: actor, IfRevision: item.IfRevision}.Grant(c, service, object, item.Key, item.User,
+			item.Privileges)
 
-		if err != nil {
+		if err == ErrChangeNotRecorded {
+			warnNotRecorded(w, idx)
+		} else if err == ErrRevisionConflict {
+			writeItemError(w, 409, ErrCodeConflict, "The ACL isn't at the given revision", idx, "if_revision")
+			return
+		} else if err != nil {
 			log.Error("An error occurred granting ACL. Body: %s\n URL: %s\nMessage: %s",
 				r.Body, r.URL.RequestURI(), err)
-			http.Error(w, "An error occurred granting privileges", 500)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred granting privileges", idx, "")
 			return
 		}
 	}
@@ -150,28 +70,36 @@ func denyPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Finest("Inside deny privileges.")
 
-	c, service, object, body, err := getRequestData(w, r, true)
-	if err != nil {
-		// Already responded in getBody call
+	c, service, object := getRequestData(r)
+	actor := requestActor(r)
+
+	items := []privilegeListItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+	if revision, ok := ifMatch(w, r, len(items)); !ok {
+		return
+	} else if revision > 0 && !matchIfRevision(w, &items[0].IfRevision, revision) {
 		return
 	}
 
-	for _, val := range body {
-
-		_, key, user, privileges := getItemData(w, val, true, true)
-		if key == "" || user == "" {
-			// Couldn't get proper data from body.  Already responded in getItemData call
-			return
-		}
+	for idx, item := range items {
 
 		log.Finest("Denying privilege")
 
-		_, err = ACL{}.Deny(c, service, object, key, user, privileges)
+		_, err := ACL{Actor: actor, IfRevision: item.IfRevision}.Deny(c, service, object, item.Key, item.User,
+			item.Privileges)
 
-		if err != nil {
+		if err == ErrChangeNotRecorded {
+			warnNotRecorded(w, idx)
+		} else if err == ErrRevisionConflict {
+			writeItemError(w, 409, ErrCodeConflict, "The ACL isn't at the given revision", idx, "if_revision")
+			return
+		} else if err != nil {
 			log.Error("An error occurred denying privileges. Body: %s\n URL: %s\nMessage: %s",
 				r.Body, r.URL.RequestURI(), err)
-			http.Error(w, "An error occurred granting privileges", 500)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred denying privileges", idx, "")
 			return
 		}
 	}
@@ -182,31 +110,34 @@ func denyPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 // This is a URL handler that handles granting permissions for a user on an object
 func setPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 
-	c, service, object, body, err := getRequestData(w, r, true)
-	if err != nil {
-		// Already responded in getBody call
+	c, service, object := getRequestData(r)
+	actor := requestActor(r)
+
+	items := []privilegeMapItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+	if revision, ok := ifMatch(w, r, len(items)); !ok {
+		return
+	} else if revision > 0 && !matchIfRevision(w, &items[0].IfRevision, revision) {
 		return
 	}
 
-	for _, val := range body {
+	for idx, item := range items {
 
-		values, key, user, _ := getItemData(w, val, false, true)
-		if key == "" || user == "" {
-			// Couldn't get proper data from body.  Already responded in getItemData call
+		_, err := ACL{Actor: actor, IfRevision: item.IfRevision}.Set(c, service, object, item.Key, item.User,
+			item.Privileges)
+		if err == ErrChangeNotRecorded {
+			warnNotRecorded(w, idx)
+		} else if err == ErrRevisionConflict {
+			writeItemError(w, 409, ErrCodeConflict, "The ACL isn't at the given revision", idx, "if_revision")
 			return
-		}
-		privileges := getPrivilegeMap(w, values)
-		if privileges == nil {
-			// Already handled error in getPrivilegeMap
-			return
-		}
-
-		_, err = ACL{}.Set(c, service, object, key, user, privileges)
-		if err != nil {
+		} else if err != nil {
 			log.Error("An error occurred bulk creating/updating ACL in grant. "+
 				"Body: %s\n URL: %s\nMessage: %s",
 				r.Body, r.URL.RequestURI(), err)
-			http.Error(w, "An error occurred granting privileges", 500)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred setting privileges", idx, "")
 			return
 		}
 	}
@@ -216,26 +147,34 @@ func setPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 
 // This is a URL handler that handles revoking permissions for a user on an object
 func revokePrivilegesHandler(w http.ResponseWriter, r *http.Request) {
-	c, service, object, body, err := getRequestData(w, r, true)
-	if err != nil {
-		// Already responded in getBody call
+	c, service, object := getRequestData(r)
+	actor := requestActor(r)
+
+	items := []privilegeListItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+	if revision, ok := ifMatch(w, r, len(items)); !ok {
+		return
+	} else if revision > 0 && !matchIfRevision(w, &items[0].IfRevision, revision) {
 		return
 	}
 
-	for _, val := range body {
+	for idx, item := range items {
 
-		_, key, user, privileges := getItemData(w, val, true, true)
-		if key == "" || user == "" {
-			// Couldn't get proper data from body.  Already responded in getItemData call
+		_, err := ACL{Actor: actor, IfRevision: item.IfRevision}.Revoke(c, service, object, item.Key, item.User,
+			item.Privileges)
+		if err == ErrChangeNotRecorded {
+			warnNotRecorded(w, idx)
+		} else if err == ErrRevisionConflict {
+			writeItemError(w, 409, ErrCodeConflict, "The ACL isn't at the given revision", idx, "if_revision")
 			return
-		}
-
-		_, err = ACL{}.Revoke(c, service, object, key, user, privileges)
-		if err != nil {
+		} else if err != nil {
 			log.Error("An error occurred bulk creating/updating ACL in revoke. "+
 				"Body: %s\n URL: %s\nMessage: %s",
 				r.Body, r.URL.RequestURI(), err)
-			http.Error(w, "An error occurred revoking privileges", 500)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred revoking privileges", idx, "")
 			return
 		}
 	}
@@ -243,24 +182,49 @@ func revokePrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(204)
 }
 
-// This is a URL handler that handles checking multiple privileges for a user on an object
+// This is a URL handler that handles checking multiple privileges for a user on an object.
+// With explain=true each item also explains how its decision was reached.
 func hasPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
-	c, service, object, body, err := getRequestData(w, r, true)
-	if err != nil {
-		// Already responded in getBody call
+	c, service, object := getRequestData(r)
+
+	acl, ok := readACL(w, r)
+	if !ok {
 		return
 	}
 
-	output := make([]map[string]interface{}, len(body))
-	for idx, val := range body {
+	items := []privilegeListItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
 
-		_, key, user, privileges := getItemData(w, val, true, true)
-		if key == "" || user == "" {
-			// Couldn't get proper data from body.  Already responded in getItemData call
-			return
+	explain, _ := strconv.ParseBool(r.URL.Query().Get("explain"))
+
+	output := make([]map[string]interface{}, len(items))
+	for idx, check := range items {
+
+		item := map[string]interface{}{
+			"key":  check.Key,
+			"user": check.User,
+		}
+
+		if explain {
+			explanation, err := acl.Explain(c, service, object, check.Key, check.User, check.Privileges)
+			if err != nil {
+				log.Error("An error occurred explaining user ACLs. "+
+					"Body: %s\n URL: %s\nMessage: %s",
+					r.Body, r.URL.RequestURI(), err)
+				writeItemError(w, 500, ErrCodeStorage, "An error occurred getting privileges", idx, "")
+				return
+			}
+
+			item["privilege"] = explanation.Decision
+			item["explanation"] = explanation
+			output[idx] = item
+			continue
 		}
 
-		err := ACL{}.Has(c, service, object, key, user, privileges)
+		err := acl.Has(c, service, object, check.Key, check.User, check.Privileges)
 
 		var privilege string
 		if err == nil {
@@ -271,16 +235,12 @@ func hasPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 				log.Error("An error occurred in has user ACLs. "+
 					"Body: %s\n URL: %s\nMessage: %s",
 					r.Body, r.URL.RequestURI(), err)
-				http.Error(w, "An error occurred getting privileges", 500)
+				writeItemError(w, 500, ErrCodeStorage, "An error occurred getting privileges", idx, "")
 				return
 			}
 		}
 
-		item := map[string]interface{}{
-			"key":       key,
-			"user":      user,
-			"privilege": privilege,
-		}
+		item["privilege"] = privilege
 
 		output[idx] = item
 	}
@@ -288,7 +248,7 @@ func hasPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 	data, err := json.Marshal(output)
 	if err != nil {
 		log.Error(err)
-		http.Error(w, "An error occurred checking privileges", 500)
+		writeError(w, 500, ErrCodeInternal, "An error occurred checking privileges")
 		return
 	}
 
@@ -298,39 +258,47 @@ func hasPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 
 // This is a URL handler for getting an ACL object
 func getPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
-	c, service, object, body, err := getRequestData(w, r, true)
-	if err != nil {
-		// Already responded in getBody call
+	c, service, object := getRequestData(r)
+
+	acl, ok := readACL(w, r)
+	if !ok {
 		return
 	}
 
-	output := make([]map[string]interface{}, len(body))
-	for idx, val := range body {
+	items := []keyUserItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
 
-		_, key, user, _ := getItemData(w, val, false, true)
-		if key == "" || user == "" {
-			// Couldn't get proper data from body.  Already responded in getItemData call
-			return
-		}
+	output := make([]map[string]interface{}, len(items))
+	for idx, get := range items {
 
-		result, err := ACL{}.Get(c, service, object, key, user)
+		result, err := acl.Get(c, service, object, get.Key, get.User)
 		if err != nil && err.Error() != "not found" {
 			log.Error("An error occurred getting user ACLs. "+
 				"Body: %s\n URL: %s\nMessage: %s",
 				r.Body, r.URL.RequestURI(), err)
-			http.Error(w, "An error occurred getting privileges", 500)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred getting privileges", idx, "")
 			return
 		}
 
 		item := map[string]interface{}{
-			"key":  key,
-			"user": user,
+			"key":  get.Key,
+			"user": get.User,
 		}
 
 		if err == nil {
 			item["privileges"] = result.Privileges
+			item["revision"] = result.Revision
+
+			// A single ACL can be written back conditionally with its ETag in If-Match
+			if len(items) == 1 && result.Revision > 0 {
+				w.Header().Set("ETag", revisionETag(result.Revision))
+			}
 		} else {
 			item["privileges"] = map[string]interface{}{}
+			item["revision"] = 0
 		}
 
 		output[idx] = item
@@ -339,7 +307,7 @@ func getPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 	data, err := json.Marshal(output)
 	if err != nil {
 		log.Error("Error marshalling get acl data: %s", err)
-		http.Error(w, "An error occurred getting privileges", 500)
+		writeError(w, 500, ErrCodeInternal, "An error occurred getting privileges")
 		return
 	}
 
@@ -349,32 +317,33 @@ func getPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 
 // This is a URL handler for matching objects to an allowed ACL for a user
 func matchPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
-	c, service, object, body, err := getRequestData(w, r, true)
-	if err != nil {
-		// Already responded in getBody call
+	c, service, object := getRequestData(r)
+
+	items := []matchItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
 		return
 	}
 
-	output := make([]map[string]interface{}, len(body))
-	for idx, val := range body {
+	if wantsNDJSON(r) {
+		streamMatches(w, r, c, service, object, items)
+		return
+	}
 
-		_, _, user, privileges := getItemData(w, val, true, false)
-		if user == "" {
-			// Couldn't get proper data from body.  Already responded in getItemData call
-			return
-		}
+	output := make([]map[string]interface{}, len(items))
+	for idx, match := range items {
 
-		result, err := ACL{}.Match(c, service, object, user, privileges)
+		result, next, err := ACL{}.Match(c, service, object, match.User, match.Privileges, match.Value, match.page())
 		if err != nil && err.Error() != "not found" {
 			log.Error("An error occurred matching user ACLs. "+
 				"Body: %s\n URL: %s\nMessage: %s",
 				r.Body, r.URL.RequestURI(), err)
-			http.Error(w, "An error occurred matching privileges", 500)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred matching privileges", idx, "")
 			return
 		}
 
 		item := map[string]interface{}{
-			"user": user,
+			"user": match.User,
 		}
 
 		if err == nil {
@@ -383,13 +352,17 @@ func matchPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 			item["keys"] = []string{}
 		}
 
+		if match.Limit > 0 {
+			item["next_cursor"] = next
+		}
+
 		output[idx] = item
 	}
 
 	data, err := json.Marshal(output)
 	if err != nil {
 		log.Error("Error marshalling match return data: %s", err)
-		http.Error(w, "An error occurred getting privileges", 500)
+		writeError(w, 500, ErrCodeInternal, "An error occurred getting privileges")
 		return
 	}
 
@@ -397,10 +370,63 @@ func matchPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
+// Streams the matched keys for each item as newline delimited JSON, one {"user", "key"}
+// object per line.  A paged item whose results continue is followed by a
+// {"user", "next_cursor"} line.
+func streamMatches(w http.ResponseWriter, r *http.Request, c *mgo.Collection, service string,
+	object string, items []matchItem) {
+	stream := newStreamWriter(w, r)
+	for _, match := range items {
+
+		iter, err := ACL{}.MatchIter(c, service, object, match.User, match.Privileges, match.Value, match.page())
+		if err != nil {
+			log.Error("An error occurred matching user ACLs. URL: %s\nMessage: %s",
+				r.URL.RequestURI(), err)
+			stream.Abort()
+			return
+		}
+
+		count := 0
+		last := ""
+		next := ""
+		acl := ACL{}
+		for iter.Next(&acl) {
+			if match.Limit > 0 && count == match.Limit {
+				next = Cursor{Key: last}.String()
+				break
+			}
+
+			err = stream.Write(map[string]interface{}{"user": match.User, "key": acl.Key})
+			if err != nil {
+				break
+			}
+
+			count++
+			last = acl.Key
+			acl = ACL{}
+		}
+
+		if closeErr := iter.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.Error("An error occurred streaming matched ACLs. URL: %s\nMessage: %s",
+				r.URL.RequestURI(), err)
+			stream.Abort()
+			return
+		}
+
+		if next != "" {
+			stream.Write(map[string]interface{}{"user": match.User, "next_cursor": next})
+		}
+	}
+	stream.Close()
+}
+
 // This is a URL handler for getting ACL Lists
 func listPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 
-	c, service, object, _, _ := getRequestData(w, r, false)
+	c, service, object := getRequestData(r)
 
 	query := r.URL.Query()
 
@@ -418,19 +444,245 @@ func listPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
 		user = ""
 	}
 
-	result, err := ACL{}.List(c, service, object, key, user)
-	if err != nil && err.Error() != "not found" {
+	privileges := query["privilege"]
+	value := query.Get("privilege_value")
+	if value != "" && len(privileges) == 0 {
+		log.Debug("Privilege value given without a privilege to list")
+		writeError(w, 400, ErrCodeInvalidParameter, "privilege_value requires a privilege to filter on")
+		return
+	}
+
+	page := Page{Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		page.Limit, err = strconv.Atoi(limit)
+		if err != nil || page.Limit < 1 {
+			log.Debug("Invalid list limit: %s", limit)
+			writeError(w, 400, ErrCodeInvalidParameter, "limit must be a positive integer")
+			return
+		}
+	}
+
+	acl, ok := readACL(w, r)
+	if !ok {
+		return
+	}
+
+	if wantsNDJSON(r) {
+		iter, err := acl.ListIter(c, service, object, key, user, privileges, value, page)
+		if err == ErrInvalidCursor {
+			log.Debug("Invalid list cursor: %s", page.Cursor)
+			writeError(w, 400, ErrCodeInvalidCursor, "Invalid cursor")
+			return
+		}
+
+		err = writeACLStream(w, r, iter, page.Limit)
+		if err != nil {
+			log.Error("An error occurred streaming list of ACLs. URL: %s\nMessage: %s",
+				r.URL.RequestURI(), err)
+		}
+		return
+	}
+
+	result, next, err := acl.List(c, service, object, key, user, privileges, value, page)
+	if err == ErrInvalidCursor {
+		log.Debug("Invalid list cursor: %s", page.Cursor)
+		writeError(w, 400, ErrCodeInvalidCursor, "Invalid cursor")
+		return
+	} else if err != nil && err.Error() != "not found" {
 		log.Error("An error occurred getting list of ACLs. "+
 			"Body: %s\n URL: %s\nMessage: %s",
 			r.Body, r.URL.RequestURI(), err)
-		http.Error(w, "An error occurred getting privilege list", 500)
+		writeError(w, 500, ErrCodeStorage, "An error occurred getting privilege list")
 		return
 	}
 
-	data, err := json.Marshal(result)
+	// Paged requests get the cursor for the next page along with the ACLs
+	var data []byte
+	if page.Limit > 0 {
+		data, err = json.Marshal(map[string]interface{}{
+			"acls":        result,
+			"next_cursor": next,
+		})
+	} else {
+		data, err = json.Marshal(result)
+	}
 	if err != nil {
 		log.Error("Error marshalling list acl data: %s", err)
-		http.Error(w, "An error occurred getting privilege list", 500)
+		writeError(w, 500, ErrCodeInternal, "An error occurred getting privilege list")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// This is a URL handler for getting every ACL a user has across services and objects
+func userPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
+	c := context.Get(r, "mongoColl").(*mgo.Collection)
+	vars := mux.Vars(r)
+	user := vars["user"]
+
+	query := r.URL.Query()
+	service := query.Get("service")
+	object := query.Get("object")
+	privileges := query["privilege"]
+
+	log.Finest("User ACL report: user: %s service: %s object: %s privileges: %s",
+		user, service, object, privileges)
+
+	iter := ACL{}.ListUser(c, user, service, object, privileges)
+
+	err := writeACLStream(w, r, iter, 0)
+	if err != nil {
+		log.Error("An error occurred getting user ACL report. "+
+			"Body: %s\n URL: %s\nMessage: %s",
+			r.Body, r.URL.RequestURI(), err)
+	}
+}
+
+// The ETag of an ACL at a revision
+func revisionETag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// Gets the revision in the If-Match header, which makes a single item write conditional like
+// its if_revision, or 0 without one.  Returns false if the response was already written.
+func ifMatch(w http.ResponseWriter, r *http.Request, items int) (int64, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, true
+	}
+	if items != 1 {
+		log.Debug("If-Match sent with %d items", items)
+		writeError(w, 400, ErrCodeInvalidParameter, "If-Match needs a request with a single item")
+		return 0, false
+	}
+
+	revision, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil || revision < 1 {
+		log.Debug("Invalid If-Match header: %s", header)
+		writeError(w, 400, ErrCodeInvalidParameter, "If-Match must be an ETag from a get")
+		return 0, false
+	}
+	return revision, true
+}
+
+// Makes an item's write conditional on the If-Match revision.  Returns false if the response
+// was already written because the item's if_revision disagrees with it.
+func matchIfRevision(w http.ResponseWriter, ifRevision *int64, revision int64) bool {
+	if *ifRevision > 0 && *ifRevision != revision {
+		log.Debug("If-Match revision %d disagrees with if_revision %d", revision, *ifRevision)
+		writeError(w, 400, ErrCodeInvalidParameter, "If-Match and if_revision must be the same revision")
+		return false
+	}
+	*ifRevision = revision
+	return true
+}
+
+// Checks if the request only wants to see the changes it would make
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return dryRun
+}
+
+// Copies or moves all ACLs from one key to another for each body item
+func copyKeys(w http.ResponseWriter, r *http.Request, move bool) {
+	c, service, object := getRequestData(r)
+	actor := requestActor(r)
+
+	items := []copyItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+
+	dryRun := isDryRun(r)
+
+	output := make([]map[string]interface{}, len(items))
+	for idx, item := range items {
+
+		changes, err := ACL{Actor: actor}.CopyKey(c, service, object, item.FromKey, item.ToKey, move, dryRun)
+		output[idx] = map[string]interface{}{
+			"from_key": item.FromKey,
+			"to_key":   item.ToKey,
+			"dry_run":  dryRun,
+			"changes":  changes,
+		}
+		if err == ErrChangeNotRecorded {
+			warnNotRecorded(w, idx)
+		} else if err != nil {
+			log.Error("An error occurred copying ACLs. "+
+				"Body: %s\n URL: %s\nMessage: %s",
+				r.Body, r.URL.RequestURI(), err)
+			// Copies and moves aren't atomic, so say what was changed before the error
+			writePartialError(w, 500, APIError{Code: ErrCodeStorage, Message: "An error occurred copying privileges",
+				Item: &idx}, output[:idx+1])
+			return
+		}
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		log.Error("Error marshalling copy return data: %s", err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred copying privileges")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// This is a URL handler that copies all ACLs on a key to another key
+func copyPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
+	copyKeys(w, r, false)
+}
+
+// This is a URL handler that moves all ACLs on a key to another key
+func movePrivilegesHandler(w http.ResponseWriter, r *http.Request) {
+	copyKeys(w, r, true)
+}
+
+// This is a URL handler that clones one user's ACLs onto another user
+func clonePrivilegesHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, object := getRequestData(r)
+	actor := requestActor(r)
+
+	items := []cloneItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+
+	dryRun := isDryRun(r)
+
+	output := make([]map[string]interface{}, len(items))
+	for idx, item := range items {
+
+		changes, err := ACL{Actor: actor}.CloneUser(c, service, object, item.Key, item.FromUser, item.ToUser, dryRun)
+		if err == ErrChangeNotRecorded {
+			warnNotRecorded(w, idx)
+		} else if err != nil {
+			log.Error("An error occurred cloning ACLs. "+
+				"Body: %s\n URL: %s\nMessage: %s",
+				r.Body, r.URL.RequestURI(), err)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred cloning privileges", idx, "")
+			return
+		}
+
+		output[idx] = map[string]interface{}{
+			"from_user": item.FromUser,
+			"to_user":   item.ToUser,
+			"key":       item.Key,
+			"dry_run":   dryRun,
+			"changes":   changes,
+		}
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		log.Error("Error marshalling clone return data: %s", err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred cloning privileges")
 		return
 	}
 
@@ -447,14 +699,14 @@ func getServicesHandler(w http.ResponseWriter, r *http.Request) {
 		log.Error("An error occurred getting list of Services. "+
 			"Body: %s\n URL: %s\nMessage: %s",
 			r.Body, r.URL.RequestURI(), err)
-		http.Error(w, "An error occurred getting services list", 500)
+		writeError(w, 500, ErrCodeStorage, "An error occurred getting services list")
 		return
 	}
 
 	data, err := json.Marshal(result)
 	if err != nil {
 		log.Error(err)
-		http.Error(w, "There was an error getting services list.", 500)
+		writeError(w, 500, ErrCodeInternal, "There was an error getting services list.")
 		return
 	}
 
@@ -473,14 +725,14 @@ func getObjectsHandler(w http.ResponseWriter, r *http.Request) {
 		log.Error("An error occurred getting list of Objects. "+
 			"Body: %s\n URL: %s\nMessage: %s",
 			r.Body, r.URL.RequestURI(), err)
-		http.Error(w, "An error occurred getting objects list", 500)
+		writeError(w, 500, ErrCodeStorage, "An error occurred getting objects list")
 		return
 	}
 
 	data, err := json.Marshal(result)
 	if err != nil {
 		log.Error(err)
-		http.Error(w, "There was an error getting objects list.", 500)
+		writeError(w, 500, ErrCodeInternal, "There was an error getting objects list.")
 		return
 	}
 
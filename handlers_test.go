@@ -2,13 +2,18 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAuthorizer(t *testing.T) {
@@ -27,37 +32,1479 @@ func TestAuthorizer(t *testing.T) {
 		fmt.Println("Dropping test DB @ start")
 		db.DropDatabase()
 	}
+	err = ensureIndexes(c)
+	if err != nil {
+		t.Fatal("Error creating indexes: ", err)
+	}
+
+	defer releaseMongo(session, db)
+
+	ts := Application.StartUnitTest()
+	defer ts.Close()
+
+	testGrant(t, ts, c)
+	testGrantErrors(t, ts, c)
+	testRequestLimits(t, ts, c)
+
+	testDeny(t, ts, c)
+
+	testGrantOverwrite(t, ts, c)
+
+	testRevoke(t, ts, c)
+
+	testSet(t, ts, c)
+
+	testSetNew(t, ts, c)
+
+	testHas(t, ts, c)
+	testHasExplain(t, ts, c)
+
+	testGet(t, ts, c)
+
+	testMatch(t, ts, c)
+
+	testList(t, ts, c)
+	testListUser(t, ts, c)
+	testListKey(t, ts, c)
+	testListPaged(t, ts, c)
+	testListNDJSON(t, ts, c)
+
+	testListServices(t, ts, c)
+
+	testListObjects(t, ts, c)
+
+	testUserACL(t, ts, c)
+	testUserACLPrivilege(t, ts, c)
+
+	testCopyDryRun(t, ts, c)
+	testClone(t, ts, c)
+
+	testWatch(t, ts, c)
+	testWebhooks(t, ts, c)
+
+	testClient(t, ts, c)
+	testGRPC(t, ts, c)
+
+	testAPIKeys(t, ts, c)
+	testSignedRequests(t, ts, c)
+	testAdminService(t, ts, c)
+	testAudit(t, ts, c)
+	testAuditChain(t, ts, c)
+	testHistory(t, ts, c)
+	testRevisions(t, ts, c)
+	testIdempotency(t, ts, c)
+	testImport(t, ts, c)
+	testSnapshot(t, ts, c)
+	testHistoryConcurrent(t, ts, c)
+	testAuditRecordFailure(t, ts, c)
+	testStreamAbort(t, ts, c)
+	testMoveFailure(t, ts, c)
+	testHistoryBaseline(t, ts, c)
+}
+
+// Checks a move whose copy isn't recorded still finishes, and warns the change wasn't recorded
+func testMoveFailure(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	ACL{}.Set(c, "service18", "object1", "1", "ann", map[string]interface{}{"read": "allow"})
+	ACL{}.Set(c, "service18", "object1", "1", "bob", map[string]interface{}{"write": "allow"})
+
+	fmt.Println("Moving with the second copy's history insert failing")
+	// Takes the revision bob's copy will record, so recording it fails
+	err := historyCollection(c).Insert(ACLRevision{Service: "service18", Object: "object1", Key: "2", User: "bob",
+		Revision: 1})
+	if err != nil {
+		t.Fatal("Error inserting revision: ", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/service/%s/object/%s/move/", ts.URL, "service18", "object1")
+	res, err := http.Post(url, "application/json", strings.NewReader(`[{"from_key": "1", "to_key": "2"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []struct {
+		Changes []ACLChange `json:"changes"`
+	}{}
+	json.NewDecoder(res.Body).Decode(&body)
+	res.Body.Close()
+	if res.StatusCode != 200 || res.Header.Get(warningHeader) != ErrCodeChangeNotRecorded+"; item=0" ||
+		len(body) != 1 || len(body[0].Changes) != 4 {
+		t.Fatal("Unexpected response from move with a change not recorded. Got Status: ", res.StatusCode,
+			res.Header.Get(warningHeader), body)
+	}
+
+	for _, user := range []string{"ann", "bob"} {
+		if _, err = (ACL{}).Get(c, "service18", "object1", "1", user); err != mgo.ErrNotFound {
+			t.Fatal("Move didn't delete a source ACL: ", user, err)
+		}
+		if _, err = (ACL{}).Get(c, "service18", "object1", "2", user); err != nil {
+			t.Fatal("Move didn't copy an ACL: ", user, err)
+		}
+	}
+
+	fmt.Println("Granting with the history insert failing")
+	err = historyCollection(c).Insert(ACLRevision{Service: "service18", Object: "object1", Key: "3", User: "ann",
+		Revision: 1})
+	if err != nil {
+		t.Fatal("Error inserting revision: ", err)
+	}
+	url = fmt.Sprintf("%s/v1/service/%s/object/%s/grant/", ts.URL, "service18", "object1")
+	res, err = http.Post(url, "application/json", strings.NewReader(
+		`[{"key": "3", "user": "ann", "privileges": ["read"]}, {"key": "4", "user": "ann", "privileges": ["read"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 204 || res.Header.Get(warningHeader) != ErrCodeChangeNotRecorded+"; item=0" {
+		t.Fatal("Unexpected response from grant with a change not recorded. Got Status: ", res.StatusCode,
+			res.Header.Get(warningHeader))
+	}
+	if _, err = (ACL{}).Get(c, "service18", "object1", "4", "ann"); err != nil {
+		t.Fatal("Grant stopped at the change that wasn't recorded: ", err)
+	}
+}
+
+// Checks a stream failing after rows were written can't be read as a whole one
+func testStreamAbort(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	fmt.Println("Aborting a newline delimited stream after a row")
+	req, _ := http.NewRequest("GET", ts.URL+"/v1/service/service1/object/object1/list/", nil)
+	req.Header.Set("Accept", ndjsonContentType)
+	res := httptest.NewRecorder()
+	stream := newStreamWriter(res, req)
+	stream.Write(ACL{Key: "1", User: "john"})
+	stream.Abort()
+
+	lines := strings.Split(strings.TrimSpace(res.Body.String()), "\n")
+	last := map[string]APIError{}
+	err := json.Unmarshal([]byte(lines[len(lines)-1]), &last)
+	if len(lines) != 2 || err != nil || last["error"].Code != ErrCodeStorage {
+		t.Fatal("Aborted stream didn't end with an error line: ", res.Body.String(), err)
+	}
+
+	fmt.Println("Aborting a JSON array stream after a row")
+	req.Header.Del("Accept")
+	res = httptest.NewRecorder()
+	stream = newStreamWriter(res, req)
+	stream.Write(ACL{Key: "1", User: "john"})
+	stream.Abort()
+
+	acls := []ACL{}
+	if err := json.Unmarshal(res.Body.Bytes(), &acls); err == nil {
+		t.Fatal("Aborted JSON array stream could be parsed: ", res.Body.String())
+	}
+}
+
+func testAuditRecordFailure(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	ACL{}.Set(c, "service17", "object1", "1", "ann", map[string]interface{}{"read": "allow"})
+
+	fmt.Println("Granting with the history insert failing")
+	// Takes the revision the grant will record, so its history insert fails
+	err := historyCollection(c).Insert(ACLRevision{Service: "service17", Object: "object1", Key: "1", User: "ann",
+		Revision: 2})
+	if err != nil {
+		t.Fatal("Error inserting history: ", err)
+	}
+
+	_, err = ACL{}.Grant(c, "service17", "object1", "1", "ann", []string{"write"})
+	if err != ErrChangeNotRecorded {
+		t.Fatal("Grant didn't report the failed history insert: ", err)
+	}
+
+	entries, _, err := AuditEntry{}.List(c, AuditQuery{Service: "service17"}, "", 10)
+	if err != nil || len(entries) != 2 || entries[1].Action != EventGrant || entries[1].After["write"] != "allow" {
+		t.Fatal("Grant with a failed history insert wasn't audited: ", entries, err)
+	}
+}
+
+func testHistoryConcurrent(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	fmt.Println("Setting ann's ACL from concurrent writers")
+	done := make(chan error)
+	for i := 0; i < 20; i++ {
+		go func(i int) {
+			_, err := ACL{}.Set(c, "service16", "object1", "1", "ann",
+				map[string]interface{}{fmt.Sprintf("privilege%d", i): "allow"})
+			done <- err
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-done; err != nil {
+			t.Fatal("Error setting ACL concurrently: ", err)
+		}
+	}
+
+	live, err := ACL{}.Get(c, "service16", "object1", "1", "ann")
+	if err != nil || live.Revision != 20 {
+		t.Fatal("Concurrent writes didn't each take a revision: ", live, err)
+	}
+
+	revisions, err := ACL{}.History(c, "service16", "object1", "1", "ann")
+	if err != nil || len(revisions) != 20 || revisions[0].Revision != 20 {
+		t.Fatal("Incorrect history of concurrent writes: ", revisions, err)
+	}
+
+	asOf, err := ACL{AsOf: time.Now().Add(time.Second)}.Get(c, "service16", "object1", "1", "ann")
+	if err != nil || asOf.Revision != live.Revision || !reflect.DeepEqual(asOf.Privileges, live.Privileges) {
+		t.Fatal("ACL as of now doesn't match the live ACL: ", asOf, live, err)
+	}
+}
+
+func testHistoryBaseline(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	fmt.Println("Recording baseline history for an ACL from before history")
+	err := c.Insert(bson.M{"_id": bson.NewObjectId(), "service": "service19", "object": "object1", "key": "1",
+		"user": "ann", "privileges": bson.M{"read": "allow"}, "revision": 3})
+	if err != nil {
+		t.Fatal("Error inserting ACL without history: ", err)
+	}
+	if _, err = (ACL{AsOf: time.Now().Add(time.Second)}).Get(c, "service19", "object1", "1", "ann"); err != mgo.ErrNotFound {
+		t.Fatal("Expected no ACL as of now before the baseline is recorded: ", err)
+	}
+
+	c.Database.C("counters").Remove(bson.M{"counter": historyCollection(c).Name + "_baselines"})
+	if err = (ACLRevision{}).EnsureIndex(c); err != nil {
+		t.Fatal("Error recording baseline history: ", err)
+	}
+	revisions, err := ACL{}.History(c, "service19", "object1", "1", "ann")
+	if err != nil || len(revisions) != 1 || !revisions[0].Baseline || revisions[0].Revision != 3 {
+		t.Fatal("Incorrect baseline history: ", revisions, err)
+	}
+
+	asOf, err := ACL{AsOf: time.Now().Add(time.Second)}.Get(c, "service19", "object1", "1", "ann")
+	if err != nil || asOf.Revision != 3 || asOf.Privileges["read"] != "allow" {
+		t.Fatal("Incorrect ACL as of now from baseline: ", asOf, err)
+	}
+	if _, err = (ACL{AsOf: time.Now().Add(-time.Hour)}).Get(c, "service19", "object1", "1", "ann"); err != mgo.ErrNotFound {
+		t.Fatal("Expected no ACL as of before it was created: ", err)
+	}
+
+	if _, err = (ACL{}).Set(c, "service19", "object1", "1", "ann", map[string]interface{}{"write": "allow"}); err != nil {
+		t.Fatal("Error setting ACL after baseline: ", err)
+	}
+	revisions, err = ACL{}.History(c, "service19", "object1", "1", "ann")
+	if err != nil || len(revisions) != 2 || revisions[0].Revision != 4 {
+		t.Fatal("Incorrect history after baseline: ", revisions, err)
+	}
+}
+
+func testSnapshot(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	for _, key := range []string{"1", "2"} {
+		ACL{}.Set(c, "service15", "object1", key, "ann", map[string]interface{}{"read": "allow"})
+	}
+
+	fmt.Println("Exporting a snapshot of service15")
+	res, err := http.Get(ts.URL + "/v1/snapshot/?service=service15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || res.Header.Get("Content-Type") != "application/gzip" {
+		t.Fatal("Unexpected response from export. Got Status: ", res.StatusCode)
+	}
+
+	zipped, err := gzip.NewReader(bytes.NewReader(snapshot))
+	if err != nil {
+		t.Fatal("Snapshot isn't gzipped: ", err)
+	}
+	lines, _ := ioutil.ReadAll(zipped)
+	split := strings.Split(strings.TrimSpace(string(lines)), "\n")
+	header := SnapshotHeader{}
+	json.Unmarshal([]byte(split[0]), &header)
+	if len(split) != 4 || header.Format != snapshotFormat || header.Version != snapshotVersion ||
+		header.Service != "service15" || split[3] != `{"end":true,"count":2}` {
+		t.Fatal("Incorrect snapshot: ", string(lines))
+	}
+
+	ACL{}.Set(c, "service15", "object1", "1", "ann", map[string]interface{}{"write": "allow"})
+	ACL{}.Set(c, "service15", "object1", "3", "ann", map[string]interface{}{"read": "allow"})
+
+	restore := func(mode string, body []byte) *http.Response {
+		res, err := http.Post(ts.URL+"/v1/snapshot/?mode="+mode, "application/gzip", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	fmt.Println("Restoring the snapshot of service15, replacing its ACLs")
+	res = restore("replace", snapshot)
+	result := SnapshotRestore{}
+	json.NewDecoder(res.Body).Decode(&result)
+	res.Body.Close()
+	if res.StatusCode != 200 || result.ACLs != 2 || result.Written != 1 || result.Unchanged != 1 || result.Deleted != 1 {
+		t.Fatal("Incorrect restore result. Got Status: ", res.StatusCode, result)
+	}
+
+	acl, err := ACL{}.Get(c, "service15", "object1", "1", "ann")
+	if err != nil || len(acl.Privileges) != 1 || acl.Privileges["read"] != "allow" {
+		t.Fatal("ACL wasn't restored: ", acl, err)
+	}
+	if _, err = (ACL{}).Get(c, "service15", "object1", "3", "ann"); err != mgo.ErrNotFound {
+		t.Fatal("ACL missing from the snapshot wasn't deleted: ", err)
+	}
+
+	fmt.Println("Restoring a truncated snapshot, and one with the wrong count, replacing its ACLs")
+	ACL{}.Set(c, "service15", "object1", "1", "ann", map[string]interface{}{"write": "allow"})
+	ACL{}.Set(c, "service15", "object1", "3", "ann", map[string]interface{}{"read": "allow"})
+	truncated := strings.Join(split[:3], "\n") + "\n"
+	readAPIError(t, restore("replace", []byte(truncated)), 400)
+	miscounted := truncated + `{"end":true,"count":3}` + "\n"
+	readAPIError(t, restore("replace", []byte(miscounted)), 400)
+
+	acl, err = ACL{}.Get(c, "service15", "object1", "1", "ann")
+	if err != nil || len(acl.Privileges) != 1 || acl.Privileges["write"] != "allow" {
+		t.Fatal("Invalid snapshot changed an ACL: ", acl, err)
+	}
+	if _, err = (ACL{}).Get(c, "service15", "object1", "3", "ann"); err != nil {
+		t.Fatal("Invalid snapshot deleted an ACL: ", err)
+	}
+}
+
+func testImport(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	call := func(query string, contentType string, body string) ImportSummary {
+		url := fmt.Sprintf("%s/v1/import/?%s", ts.URL, query)
+		res, err := http.Post(url, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != 200 {
+			t.Fatal("Unexpected status code from import. Got Status: ", res.StatusCode)
+		}
+		summary := ImportSummary{}
+		json.NewDecoder(res.Body).Decode(&summary)
+		return summary
+	}
+
+	fmt.Println("Importing JSONL records")
+	records := `{"op": "set", "service": "service13", "object": "object1", "key": "1", "user": "ann", "privileges": {"read": "allow"}}
+{"op": "grant", "service": "service13", "object": "object1", "key": "1", "user": "ann", "privileges": ["write"]}
+
+{"op": "grant", "service": "service13", "object": "object1", "key": "1", "privileges": ["admin"]}
+{"op": "promote", "service": "service13", "object": "object1", "key": "1", "user": "ann", "privileges": ["admin"]}
+not json
+{"op": "deny", "service": "service13", "object": "object1", "key": "2", "user": "bob", "privileges": ["read"]}
+`
+	summary := call("id=import1&batch_size=2", "application/x-ndjson", records)
+	if summary.Rows != 6 || summary.Applied != 3 || summary.Failed != 3 || summary.Skipped != 0 || !summary.Done ||
+		summary.Checkpoint != 6 || len(summary.Failures) != 3 || summary.Failures[0].Row != 3 ||
+		summary.Failures[0].Error.Code != ErrCodeMissingField || summary.Failures[1].Error.Code != ErrCodeInvalidField {
+		t.Fatal("Incorrect import summary: ", summary)
+	}
+
+	acl, err := ACL{}.Get(c, "service13", "object1", "1", "ann")
+	if err != nil || acl.Privileges["read"] != "allow" || acl.Privileges["write"] != "allow" || acl.Privileges["admin"] != nil {
+		t.Fatal("Incorrect imported ACL: ", acl, err)
+	}
+
+	fmt.Println("Resuming the finished import")
+	summary = call("id=import1", "application/x-ndjson", records)
+	if summary.Rows != 6 || summary.Skipped != 3 || summary.Applied != 0 || summary.Failed != 3 ||
+		summary.Retried != 3 || summary.Failures[0].Row != 3 {
+		t.Fatal("Resumed import didn't skip its applied rows and retry its failed ones: ", summary)
+	}
+
+	fmt.Println("Resuming an import with a row that failed, once it can be applied")
+	retried := `{"op": "grant", "service": "service13", "object": "object1", "key": "3", "user": "ann", "privileges": ["read"]}
+{"op": "grant", "service": "service14", "object": "object1", "key": "3", "user": "ann", "privileges": ["read"]}
+{"op": "grant", "service": "service13", "object": "object1", "key": "4", "user": "ann", "privileges": ["read"]}
+`
+	summary = call("id=import2&service=service13&batch_size=1", "application/x-ndjson", retried)
+	if summary.Applied != 2 || summary.Failed != 1 || summary.Failures[0].Row != 2 {
+		t.Fatal("Incorrect import summary: ", summary)
+	}
+	checkpoint, err := getImportCheckpoint(c, "import2")
+	if err != nil || checkpoint.Row != 3 || len(checkpoint.Failed) != 1 || checkpoint.Failed[0] != 2 {
+		t.Fatal("Import checkpoint didn't keep its failed row: ", checkpoint, err)
+	}
+	summary = call("id=import2", "application/x-ndjson", retried)
+	if summary.Skipped != 2 || summary.Retried != 1 || summary.Applied != 1 || summary.Failed != 0 {
+		t.Fatal("Resumed import didn't retry its failed row: ", summary)
+	}
+	if _, err = (ACL{}).Get(c, "service14", "object1", "3", "ann"); err != nil {
+		t.Fatal("Retried row wasn't applied: ", err)
+	}
+	checkpoint, err = getImportCheckpoint(c, "import2")
+	if err != nil || len(checkpoint.Failed) != 0 {
+		t.Fatal("Import checkpoint kept a row applied on retry: ", checkpoint, err)
+	}
+
+	fmt.Println("Importing CSV records for one service")
+	summary = call("service=service13", "text/csv", `user,key,object,service,op,privileges
+bob,2,object1,service13,set,read=allow;write=deny
+bob,2,object1,service14,grant,read
+`)
+	if summary.Rows != 2 || summary.Applied != 1 || summary.Failed != 1 ||
+		summary.Failures[0].Error.Code != ErrCodeForbidden {
+		t.Fatal("Incorrect CSV import summary: ", summary)
+	}
+	acl, err = ACL{}.Get(c, "service13", "object1", "2", "bob")
+	if err != nil || acl.Privileges["read"] != "allow" || acl.Privileges["write"] != "deny" {
+		t.Fatal("Incorrect ACL imported from CSV: ", acl, err)
+	}
+
+	fmt.Println("Importing a record longer than the longest line")
+	summary = call("", "application/x-ndjson", `{"op": "grant", "service": "service13", "object": "object1", "key": "5", "user": "`+
+		strings.Repeat("a", maxImportLineBytes)+`", "privileges": ["read"]}
+{"op": "grant", "service": "service13", "object": "object1", "key": "5", "user": "ann", "privileges": ["read"]}
+`)
+	if summary.Rows != 2 || summary.Applied != 1 || summary.Failed != 1 || summary.Failures[0].Row != 1 ||
+		summary.Failures[0].Error.Code != ErrCodeBodyTooLarge {
+		t.Fatal("Over-long record didn't fail on its own: ", summary)
+	}
+
+	res, err := http.Post(ts.URL+"/v1/import/?format=csv", "text/csv", strings.NewReader("user,key\nbob,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAPIError(t, res, 400)
+}
+
+func testIdempotency(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	call := func(action string, key string, body string) *http.Response {
+		url := fmt.Sprintf("%s/v1/service/%s/object/%s/%s/", ts.URL, "service12", "object1", action)
+		req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	fmt.Println("Granting ann read with an idempotency key")
+	grant := `[{"user": "ann", "key": "1", "privileges": ["read"]}]`
+	res := call("grant", "grant-1", grant)
+	res.Body.Close()
+	if res.StatusCode != 204 || res.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatal("Unexpected response from first grant. Got Status: ", res.StatusCode, res.Header)
+	}
+
+	res = call("revoke", "", `[{"user": "ann", "key": "1", "privileges": ["read"]}]`)
+	res.Body.Close()
+
+	fmt.Println("Repeating the grant with the same idempotency key")
+	res = call("grant", "grant-1", grant)
+	res.Body.Close()
+	if res.StatusCode != 204 || res.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatal("Repeated grant wasn't replayed. Got Status: ", res.StatusCode, res.Header)
+	}
+	acl, err := ACL{}.Get(c, "service12", "object1", "1", "ann")
+	if err != mgo.ErrNotFound && (err != nil || len(acl.Privileges) != 0) {
+		t.Fatal("Repeated grant was applied again: ", acl, err)
+	}
+
+	fmt.Println("Reusing the idempotency key for a different grant")
+	res = call("grant", "grant-1", `[{"user": "ann", "key": "1", "privileges": ["write"]}]`)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 422 || !strings.Contains(string(body), `"code":"idempotency_key_reused"`) {
+		t.Fatal("Unexpected response reusing idempotency key. Got Status: ", res.StatusCode, string(body))
+	}
+
+	fmt.Println("Reusing the idempotency key with a different If-Match")
+	url := fmt.Sprintf("%s/v1/service/%s/object/%s/grant/", ts.URL, "service12", "object1")
+	req, _ := http.NewRequest("POST", url, strings.NewReader(grant))
+	req.Header.Set("Idempotency-Key", "grant-1")
+	req.Header.Set("If-Match", `"1"`)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 422 {
+		t.Fatal("Unexpected response reusing idempotency key with If-Match. Got Status: ", res.StatusCode)
+	}
+
+	fmt.Println("Replaying the headers of a grant that wasn't recorded")
+	// Takes the revision the grant will record, so recording it fails
+	err = historyCollection(c).Insert(ACLRevision{Service: "service12", Object: "object1", Key: "2", User: "ann",
+		Revision: 1})
+	if err != nil {
+		t.Fatal("Error inserting revision: ", err)
+	}
+	unrecorded := `[{"user": "ann", "key": "2", "privileges": ["read"]}]`
+	for _, replayed := range []string{"", "true"} {
+		res = call("grant", "grant-2", unrecorded)
+		res.Body.Close()
+		if res.StatusCode != 204 || res.Header.Get("Idempotent-Replayed") != replayed ||
+			res.Header.Get(warningHeader) != ErrCodeChangeNotRecorded+"; item=0" {
+			t.Fatal("Unexpected response to unrecorded grant. Got Status: ", res.StatusCode, res.Header)
+		}
+	}
+
+	fmt.Println("Importing with an idempotency key")
+	req, _ = http.NewRequest("POST", ts.URL+"/v1/import/", strings.NewReader(""))
+	req.Header.Set("Idempotency-Key", "import-1")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 400 || !strings.Contains(string(body), `"code":"invalid_parameter"`) {
+		t.Fatal("Unexpected response importing with an idempotency key. Got Status: ", res.StatusCode, string(body))
+	}
+
+	fmt.Println("Claiming a key in the memory idempotency store")
+	store := newMemoryIdempotencyStore()
+	response := IdempotentResponse{Key: "key", Fingerprint: "a", Expires: time.Now().Add(time.Minute)}
+	existing, err := store.Claim(nil, response)
+	if existing != nil || err != nil {
+		t.Fatal("Unexpected claim of a new key: ", existing, err)
+	}
+	existing, _ = store.Claim(nil, response)
+	if existing == nil || existing.Status != 0 {
+		t.Fatal("Claimed key wasn't in progress: ", existing)
+	}
+	store.Release(nil, "key")
+	response.Expires = time.Now().Add(-time.Second)
+	store.Claim(nil, response)
+	existing, _ = store.Claim(nil, response)
+	if existing != nil {
+		t.Fatal("Expired key wasn't claimed again: ", existing)
+	}
+
+	fmt.Println("Releasing the key of a request whose handler panics")
+	previous := idempotency
+	idempotency = newMemoryIdempotencyStore()
+	defer func() {
+		idempotency = previous
+	}()
+	req, _ = http.NewRequest("POST", ts.URL+"/v1/service/service12/object/object1/grant/", strings.NewReader(grant))
+	req.Header.Set("Idempotency-Key", "panic-1")
+	func() {
+		defer func() {
+			recover()
+		}()
+		serveIdempotent(httptest.NewRecorder(), req, c, func(w http.ResponseWriter, r *http.Request) {
+			panic("handler failed")
+		})
+	}()
+	existing, err = idempotency.Claim(nil, IdempotentResponse{Key: anonymousCaller + "\npanic-1",
+		Fingerprint: "b", Expires: time.Now().Add(time.Minute)})
+	if existing != nil || err != nil {
+		t.Fatal("Key was still claimed after the handler panicked: ", existing, err)
+	}
+}
+
+func testRevisions(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	call := func(method string, action string, ifMatch string, body string) *http.Response {
+		url := fmt.Sprintf("%s/v1/service/%s/object/%s/%s/", ts.URL, "service11", "object1", action)
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := call("PUT", "set", "", `[{"user": "ann", "key": "1", "privileges": {"read": "allow"}}]`)
+	res.Body.Close()
+
+	fmt.Println("Getting the revision of ann's ACL")
+	res = call("GET", "get", "", `[{"user": "ann", "key": "1"}]`)
+	output := []map[string]interface{}{}
+	json.NewDecoder(res.Body).Decode(&output)
+	res.Body.Close()
+	etag := res.Header.Get("ETag")
+	if len(output) != 1 || output[0]["revision"] == float64(0) || etag != fmt.Sprintf(`"%v"`, output[0]["revision"]) {
+		t.Fatal("Incorrect revision from get: ", output, etag)
+	}
+	revision := int64(output[0]["revision"].(float64))
+
+	fmt.Println("Setting ann's ACL at revision ", revision)
+	res = call("PUT", "set", "", fmt.Sprintf(
+		`[{"user": "ann", "key": "1", "privileges": {"write": "allow"}, "if_revision": %d}]`, revision))
+	res.Body.Close()
+	if res.StatusCode != 204 {
+		t.Fatal("Unexpected status code from set at the current revision. Got Status: ", res.StatusCode)
+	}
+
+	fmt.Println("Setting ann's ACL at the stale revision ", revision)
+	res = call("PUT", "set", "", fmt.Sprintf(
+		`[{"user": "ann", "key": "1", "privileges": {"admin": "allow"}, "if_revision": %d}]`, revision))
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 409 || !strings.Contains(string(body), `"code":"conflict"`) {
+		t.Fatal("Unexpected response from set at a stale revision. Got Status: ", res.StatusCode, string(body))
+	}
+	res = call("POST", "grant", etag, `[{"user": "ann", "key": "1", "privileges": ["admin"]}]`)
+	res.Body.Close()
+	if res.StatusCode != 409 {
+		t.Fatal("Unexpected status code from grant with a stale ETag. Got Status: ", res.StatusCode)
+	}
+
+	acl, err := ACL{}.Get(c, "service11", "object1", "1", "ann")
+	if err != nil || len(acl.Privileges) != 1 || acl.Privileges["write"] != "allow" || acl.Revision <= revision {
+		t.Fatal("Conflicting writes changed the ACL: ", acl, err)
+	}
+
+	res = call("POST", "revoke", fmt.Sprintf(`"%d"`, acl.Revision),
+		fmt.Sprintf(`[{"user": "ann", "key": "1", "privileges": ["write"], "if_revision": %d}]`, revision))
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 400 || !strings.Contains(string(body), `"code":"invalid_parameter"`) {
+		t.Fatal("Unexpected response from revoke with If-Match and a different if_revision. Got Status: ",
+			res.StatusCode, string(body))
+	}
+
+	res = call("POST", "revoke", fmt.Sprintf(`"%d"`, acl.Revision),
+		fmt.Sprintf(`[{"user": "ann", "key": "1", "privileges": ["write"], "if_revision": %d}]`, acl.Revision))
+	res.Body.Close()
+	if res.StatusCode != 204 {
+		t.Fatal("Unexpected status code from revoke with the current ETag. Got Status: ", res.StatusCode)
+	}
+
+	fmt.Println("Deleting ann's ACL and creating it again")
+	err = ACL{}.Delete(c, "service11", "object1", "1", "ann")
+	if err != nil {
+		t.Fatal("Error deleting ACL: ", err)
+	}
+	ACL{}.Set(c, "service11", "object1", "1", "ann", map[string]interface{}{"read": "allow"})
+	recreated, err := ACL{}.Get(c, "service11", "object1", "1", "ann")
+	if err != nil || recreated.Revision != acl.Revision+3 {
+		t.Fatal("Recreated ACL didn't carry on counting revisions from ", acl.Revision, ": ", recreated, err)
+	}
+}
+
+func testHistory(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	call := func(method string, action string, query string, body string) []byte {
+		url := fmt.Sprintf("%s/v1/service/%s/object/%s/%s/?%s", ts.URL, "service10", "object1", action, query)
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != 200 && res.StatusCode != 204 {
+			t.Fatal("Unexpected status code from ", action, ". Got Status: ", res.StatusCode, string(data))
+		}
+		return data
+	}
+
+	call("POST", "grant", "", `[{"user": "bob", "key": "7", "privileges": ["read"]}]`)
+	time.Sleep(20 * time.Millisecond)
+	then := time.Now().UTC().Format(time.RFC3339Nano)
+	time.Sleep(20 * time.Millisecond)
+	call("POST", "revoke", "", `[{"user": "bob", "key": "7", "privileges": ["read"]}]`)
+	call("POST", "grant", "", `[{"user": "bob", "key": "7", "privileges": ["write"]}]`)
+
+	fmt.Println("Checking privileges as of ", then)
+	check := `[{"user": "bob", "key": "7", "privileges": ["read"]}]`
+	if body := string(call("GET", "has", "as_of="+then, check)); !strings.Contains(body, `"privilege":"allow"`) {
+		t.Fatal("Incorrect has as of before the revoke: ", body)
+	}
+	if body := string(call("GET", "has", "", check)); !strings.Contains(body, `"privilege":"deny"`) {
+		t.Fatal("Incorrect has after the revoke: ", body)
+	}
+	body := string(call("GET", "get", "as_of="+then, `[{"user": "bob", "key": "7"}]`))
+	if !strings.Contains(body, `"privileges":{"read":"allow"}`) {
+		t.Fatal("Incorrect get as of before the revoke: ", body)
+	}
+	acls := []ACL{}
+	json.Unmarshal(call("GET", "list", "as_of="+then, ""), &acls)
+	if len(acls) != 1 || acls[0].User != "bob" || acls[0].Privileges["read"] != "allow" || len(acls[0].Privileges) != 1 {
+		t.Fatal("Incorrect list as of before the revoke: ", acls)
+	}
+
+	revisions := []ACLRevision{}
+	json.Unmarshal(call("GET", "history", "key=7&user=bob", ""), &revisions)
+	fmt.Println("ACL history: ", revisions)
+	if len(revisions) != 3 || revisions[0].Revision != 3 || revisions[0].Privileges["write"] != "allow" ||
+		len(revisions[1].Privileges) != 0 {
+		t.Fatal("Incorrect ACL history: ", revisions)
+	}
+
+	fmt.Println("Restoring revision 1")
+	call("POST", "restore", "", `[{"user": "bob", "key": "7", "revision": 1}]`)
+	acl, err := ACL{}.Get(c, "service10", "object1", "7", "bob")
+	if err != nil || len(acl.Privileges) != 1 || acl.Privileges["read"] != "allow" {
+		t.Fatal("Incorrect ACL after restoring revision 1: ", acl, err)
+	}
+}
+
+func testAuditChain(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	key := auditCheckpointKey
+	auditCheckpointKey = "checkpoint-test-key"
+	defer func() {
+		auditCheckpointKey = key
+	}()
+
+	verify := func() AuditVerification {
+		res, err := http.Get(ts.URL + "/v1/audit/verify/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != 200 {
+			t.Fatal("Unexpected status code from audit verify. Got Status: ", res.StatusCode, string(body))
+		}
+		fmt.Println("Audit verification: ", string(body))
+		result := AuditVerification{}
+		json.Unmarshal(body, &result)
+		return result
+	}
+
+	fmt.Println("Verifying the audit chain")
+	err := writeAuditCheckpoint(c)
+	if err != nil {
+		t.Fatal("Error writing audit checkpoint: ", err)
+	}
+	result := verify()
+	if !result.Valid || result.Entries < 2 || result.Checkpoints != 1 {
+		t.Fatal("Incorrect verification of untouched audit chain: ", result)
+	}
+
+	fmt.Println("Verifying the audit chain after editing entry 2")
+	entry := AuditEntry{}
+	err = auditCollection(c).Find(bson.M{"seq": 2}).One(&entry)
+	if err != nil {
+		t.Fatal("Error reading audit entry: ", err)
+	}
+	auditCollection(c).UpdateId(entry.ID, bson.M{"$set": bson.M{"user": "mallory"}})
+	result = verify()
+	if result.Valid || result.Broken == nil || result.Broken.Seq != 2 || result.Broken.ID != entry.ID.Hex() {
+		t.Fatal("Incorrect verification of edited audit chain: ", result)
+	}
+	auditCollection(c).UpdateId(entry.ID, bson.M{"$set": bson.M{"user": entry.User}})
+
+	fmt.Println("Verifying the audit chain with a forged checkpoint")
+	checkpointCollection(c).UpdateAll(bson.M{}, bson.M{"$set": bson.M{"signature": "sha256=00"}})
+	result = verify()
+	if result.Valid || result.Broken == nil || result.Checkpoints != 0 {
+		t.Fatal("Incorrect verification of forged checkpoint: ", result)
+	}
+	checkpointCollection(c).RemoveAll(bson.M{})
+
+	if result = verify(); !result.Valid {
+		t.Fatal("Incorrect verification of restored audit chain: ", result)
+	}
+
+	fmt.Println("Appending an entry with a taken sequence number")
+	entry.ID = bson.NewObjectId()
+	if err = auditCollection(c).Insert(entry); !mgo.IsDup(err) {
+		t.Fatal("Audit entry with a taken sequence number was inserted: ", err)
+	}
+
+	fmt.Println("Verifying the audit chain when the head wasn't moved after an append")
+	head, _ := getAuditHead(c)
+	c.Database.C("counters").Update(auditHeadSelector(c), bson.M{"$set": bson.M{"seq": head.Seq - 1}})
+	if result = verify(); !result.Valid {
+		t.Fatal("Incorrect verification of audit chain with a lagging head: ", result)
+	}
+	c.Database.C("counters").Update(auditHeadSelector(c), bson.M{"$set": bson.M{"seq": head.Seq}})
+
+	fmt.Println("Appending past the old head after removing the last audit entries")
+	removed := []AuditEntry{}
+	auditCollection(c).Find(bson.M{"seq": bson.M{"$gt": head.Seq - 2}}).All(&removed)
+	auditCollection(c).RemoveAll(bson.M{"seq": bson.M{"$gt": head.Seq - 2}})
+	for i := 0; i < 3; i++ {
+		err = appendAudit(c, &AuditEntry{ID: bson.NewObjectId(), Action: EventGrant, Service: "service1", Object: "object1", Key: "1",
+			User: "mallory"})
+		if err == nil {
+			t.Fatal("Appended to an audit log missing its last entries")
+		}
+	}
+	if result = verify(); result.Valid || result.Broken == nil {
+		t.Fatal("Incorrect verification of truncated audit chain: ", result)
+	}
+	for _, entry := range removed {
+		auditCollection(c).Insert(entry)
+	}
+	if result = verify(); !result.Valid {
+		t.Fatal("Incorrect verification of restored audit chain: ", result)
+	}
+}
+
+func testAudit(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	call := func(method string, url string, body string) *http.Response {
+		req, _ := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		req.Header.Set("X-Request-Id", "audit-test-1")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	fmt.Println("Auditing grant and revoke on service9")
+	res := call("POST", "/v1/service/service9/object/object1/grant/",
+		`[{"user": "john", "key": "1", "privileges": ["read", "write"]}]`)
+	res.Body.Close()
+	if res.StatusCode != 204 || res.Header.Get("X-Request-Id") != "audit-test-1" {
+		t.Fatal("Unexpected response from grant. Got Status: ", res.StatusCode, res.Header.Get("X-Request-Id"))
+	}
+	res = call("POST", "/v1/service/service9/object/object1/revoke/",
+		`[{"user": "john", "key": "1", "privileges": ["write"]}]`)
+	res.Body.Close()
+
+	res = call("GET", "/v1/audit/?service=service9&limit=1", "")
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from audit log. Got Status: ", res.StatusCode, string(body))
+	}
+	page := struct {
+		Entries    []AuditEntry `json:"entries"`
+		NextCursor string       `json:"next_cursor"`
+	}{}
+	json.Unmarshal(body, &page)
+	fmt.Println("Audit page: ", string(body))
+	if len(page.Entries) != 1 || page.NextCursor == "" {
+		t.Fatal("Incorrect first audit page: ", string(body))
+	}
+	entry := page.Entries[0]
+	if entry.Action != EventGrant || entry.Caller != anonymousCaller || entry.RequestID != "audit-test-1" ||
+		entry.Before != nil || entry.After["read"] != "allow" || entry.After["write"] != "allow" {
+		t.Fatal("Incorrect grant audit entry: ", entry)
+	}
+
+	res = call("GET", "/v1/audit/?service=service9&limit=1&cursor="+page.NextCursor, "")
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	page.Entries = nil
+	json.Unmarshal(body, &page)
+	if len(page.Entries) != 1 {
+		t.Fatal("Incorrect second audit page: ", string(body))
+	}
+	entry = page.Entries[0]
+	if entry.Action != EventRevoke || entry.Before["write"] != "allow" || len(entry.After) != 1 {
+		t.Fatal("Incorrect revoke audit entry: ", entry)
+	}
+
+	res = call("GET", "/v1/audit/?from=yesterday", "")
+	res.Body.Close()
+	if res.StatusCode != 400 {
+		t.Fatal("Unexpected status code from audit log with invalid time. Got Status: ", res.StatusCode)
+	}
+}
+
+func testAdminService(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	enabled, bootstrap, service := authEnabled, bootstrapKey, adminService
+	authEnabled, bootstrapKey, adminService = true, "bootstrap-test-key", "_authorizer"
+	defer func() {
+		authEnabled, bootstrapKey, adminService = enabled, bootstrap, service
+	}()
+
+	key, err := APIKey{}.Create(c, apiKeyItem{Name: "manager", Services: []string{"*"}, Scopes: []string{"write"}})
+	if err != nil {
+		t.Fatal("Error creating API key: ", err)
+	}
+
+	call := func(method string, service string, action string, key string) int {
+		url := fmt.Sprintf("%s/v1/service/%s/object/%s/%s/", ts.URL, service, "object1", action)
+		body := `[{"user": "john", "key": "1", "privileges": ["read"]}]`
+		if action == "set" {
+			body = `[{"user": "john", "key": "1", "privileges": {"read": "allow"}}]`
+		}
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	fmt.Println("Granting without admin privileges on service7")
+	if status := call("POST", "service7", "grant", key.Key); status != 403 {
+		t.Fatal("Unexpected status code from grant without admin privileges. Got Status: ", status)
+	}
+	if status := call("POST", "service7", "grant", bootstrapKey); status != 204 {
+		t.Fatal("Unexpected status code from grant with bootstrap key. Got Status: ", status)
+	}
+
+	_, err = ACL{}.Grant(c, "_authorizer", "services", "service7", "key:"+key.ID.Hex(), []string{"grant"})
+	if err != nil {
+		t.Fatal("Error granting admin privileges: ", err)
+	}
+	if status := call("POST", "service7", "grant", key.Key); status != 204 {
+		t.Fatal("Unexpected status code from grant with grant privilege. Got Status: ", status)
+	}
+	if status := call("POST", "service7", "revoke", key.Key); status != 403 {
+		t.Fatal("Unexpected status code from revoke with only grant privilege. Got Status: ", status)
+	}
+	if status := call("PUT", "service7", "set", key.Key); status != 403 {
+		t.Fatal("Unexpected status code from set with only grant privilege. Got Status: ", status)
+	}
+	if status := call("POST", "service8", "grant", key.Key); status != 403 {
+		t.Fatal("Unexpected status code from grant on another service. Got Status: ", status)
+	}
+
+	fmt.Println("Copying and cloning onto privileges the caller can't revoke on service7")
+	_, err = ACL{}.Grant(c, "service7", "object1", "2", "jane", []string{"write"})
+	if err != nil {
+		t.Fatal("Error granting destination privileges: ", err)
+	}
+	replace := func(action string, body string) int {
+		url := fmt.Sprintf("%s/v1/service/%s/object/%s/%s/", ts.URL, "service7", "object1", action)
+		req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key.Key)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	if status := replace("copy", `[{"from_key": "1", "to_key": "2"}]`); status != 403 {
+		t.Fatal("Unexpected status code from copy with only grant privilege. Got Status: ", status)
+	}
+	if status := replace("clone", `[{"from_user": "john", "to_user": "jane", "key": "2"}]`); status != 403 {
+		t.Fatal("Unexpected status code from clone with only grant privilege. Got Status: ", status)
+	}
+	err = ACL{}.Has(c, "service7", "object1", "2", "jane", []string{"write"})
+	if err != nil {
+		t.Fatal("Expected the destination privileges to be left alone: ", err)
+	}
+
+	_, err = ACL{}.Grant(c, "_authorizer", "services", "*", "key:"+key.ID.Hex(), []string{"admin"})
+	if err != nil {
+		t.Fatal("Error granting admin privileges: ", err)
+	}
+	if status := call("PUT", "service8", "set", key.Key); status != 204 {
+		t.Fatal("Unexpected status code from set with admin privilege. Got Status: ", status)
+	}
+	if status := call("POST", "_authorizer", "grant", key.Key); status != 403 {
+		t.Fatal("Unexpected status code from grant on the admin service. Got Status: ", status)
+	}
+}
+
+func testAPIKeys(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	enabled, bootstrap := authEnabled, bootstrapKey
+	authEnabled, bootstrapKey = true, "bootstrap-test-key"
+	defer func() {
+		authEnabled, bootstrapKey = enabled, bootstrap
+	}()
+
+	call := func(method string, url string, key string, body interface{}) (*http.Response, []byte) {
+		dataStr, _ := json.Marshal(body)
+		req, err := http.NewRequest(method, url, bytes.NewReader(dataStr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		resBody, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal("Error getting response body: ", err)
+		}
+		return res, resBody
+	}
+
+	keysUrl := fmt.Sprintf("%s/v1/admin/key/", ts.URL)
+	objectsUrl := fmt.Sprintf("%s/v1/service/%s/object/", ts.URL, "service5")
+	otherUrl := fmt.Sprintf("%s/v1/service/%s/object/", ts.URL, "service6")
+	grantUrl := fmt.Sprintf("%s/v1/service/%s/object/%s/grant/", ts.URL, "service5", "object1")
+	grant := []map[string]interface{}{
+		map[string]interface{}{"user": "john", "key": "1", "privileges": []string{"read"}},
+	}
+
+	fmt.Println("Calling without credentials at URL: ", objectsUrl)
+	res, _ := call("GET", objectsUrl, "", nil)
+	if res.StatusCode != 401 || res.Header.Get("WWW-Authenticate") == "" {
+		t.Fatal("Unexpected response to unauthenticated call. Got Status: ", res.Status)
+	}
+
+	res, _ = call("GET", fmt.Sprintf("%s/v1/openapi.json", ts.URL), "", nil)
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from public call. Got Status: ", res.Status)
+	}
+
+	fmt.Println("Create API keys at URL: ", keysUrl)
+	res, body := call("POST", keysUrl, bootstrapKey, []map[string]interface{}{
+		map[string]interface{}{"name": "reader", "services": []string{"service5"}, "scopes": []string{"read"}},
+		map[string]interface{}{"name": "writer", "services": []string{"service5"}, "scopes": []string{"write"}},
+	})
+	if res.StatusCode != 201 {
+		t.Fatal("Unexpected status code from create API keys call. Got Status: ", res.Status, string(body))
+	}
+
+	fmt.Println("Output from create API keys call: ", string(body))
+
+	keys := []APIKey{}
+	err := json.Unmarshal(body, &keys)
+	if err != nil {
+		t.Fatal("Error parsing response body: ", err)
+	}
+	if len(keys) != 2 || keys[0].Key == "" || keys[1].Key == "" {
+		t.Fatal("Incorrect API keys created: ", keys)
+	}
+	reader, writer := keys[0], keys[1]
+
+	stored := APIKey{}
+	err = apiKeyCollection(c).FindId(reader.ID).One(&stored)
+	if err != nil {
+		t.Fatal("Error getting stored API key: ", err)
+	}
+	if stored.Hash == "" || strings.Contains(reader.Key, stored.Hash) {
+		t.Fatal("API key secret isn't stored hashed: ", stored)
+	}
+
+	res, _ = call("GET", objectsUrl, reader.Key, nil)
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from read call with read key. Got Status: ", res.Status)
+	}
+	res, _ = call("GET", otherUrl, reader.Key, nil)
+	if res.StatusCode != 403 {
+		t.Fatal("Unexpected status code from read call on another service. Got Status: ", res.Status)
+	}
+	res, _ = call("POST", grantUrl, reader.Key, grant)
+	if res.StatusCode != 403 {
+		t.Fatal("Unexpected status code from grant call with read key. Got Status: ", res.Status)
+	}
+
+	// Keys may also be sent as bearer tokens
+	req, _ := http.NewRequest("POST", grantUrl, bytes.NewReader([]byte(`[{"user": "john", "key": "1", "privileges": ["read"]}]`)))
+	req.Header.Set("Authorization", "Bearer "+writer.Key)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 204 {
+		t.Fatal("Unexpected status code from grant call with write key. Got Status: ", res.Status)
+	}
+
+	res, _ = call("GET", keysUrl, writer.Key, nil)
+	if res.StatusCode != 403 {
+		t.Fatal("Unexpected status code from list API keys call with write key. Got Status: ", res.Status)
+	}
+
+	rotateUrl := fmt.Sprintf("%s%s/rotate/?grace_seconds=60", keysUrl, reader.ID.Hex())
+	fmt.Println("Rotate API key at URL: ", rotateUrl)
+	res, body = call("POST", rotateUrl, bootstrapKey, nil)
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from rotate API key call. Got Status: ", res.Status)
+	}
+	rotated := APIKey{}
+	err = json.Unmarshal(body, &rotated)
+	if err != nil {
+		t.Fatal("Error parsing response body: ", err)
+	}
+	if rotated.Key == "" || rotated.Key == reader.Key || rotated.PreviousExpires == nil {
+		t.Fatal("Incorrect rotated API key: ", rotated)
+	}
+
+	for _, key := range []string{reader.Key, rotated.Key} {
+		res, _ = call("GET", objectsUrl, key, nil)
+		if res.StatusCode != 200 {
+			t.Fatal("Unexpected status code from call during rotation grace period. Got Status: ", res.Status)
+		}
+	}
+
+	res, body = call("POST", fmt.Sprintf("%s%s/rotate/", keysUrl, reader.ID.Hex()), bootstrapKey, nil)
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from rotate API key call. Got Status: ", res.Status)
+	}
+	json.Unmarshal(body, &rotated)
+	res, _ = call("GET", objectsUrl, reader.Key, nil)
+	if res.StatusCode != 401 {
+		t.Fatal("Unexpected status code from call with replaced key. Got Status: ", res.Status)
+	}
+
+	fmt.Println("Revoke API key: ", reader.ID.Hex())
+	res, _ = call("DELETE", fmt.Sprintf("%s%s/", keysUrl, reader.ID.Hex()), bootstrapKey, nil)
+	if res.StatusCode != 204 {
+		t.Fatal("Unexpected status code from revoke API key call. Got Status: ", res.Status)
+	}
+	res, _ = call("GET", objectsUrl, rotated.Key, nil)
+	if res.StatusCode != 401 {
+		t.Fatal("Unexpected status code from call with revoked key. Got Status: ", res.Status)
+	}
+	res, _ = call("DELETE", fmt.Sprintf("%s%s/", keysUrl, reader.ID.Hex()), bootstrapKey, nil)
+	if res.StatusCode != 404 {
+		t.Fatal("Unexpected status code from revoking revoked API key. Got Status: ", res.Status)
+	}
+
+	res, _ = call("GET", objectsUrl, "ak_invalid", nil)
+	if res.StatusCode != 401 {
+		t.Fatal("Unexpected status code from call with invalid key. Got Status: ", res.Status)
+	}
+}
+
+func testWebhooks(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	received := []*http.Request{}
+	bodies := [][]byte{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+	}))
+	defer receiver.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer failing.Close()
+
+	url := fmt.Sprintf("%s/v1/service/%s/webhook/", ts.URL, "service3")
+	dataMap := []map[string]interface{}{
+		map[string]interface{}{
+			"url":    receiver.URL,
+			"secret": "secret1",
+		},
+		map[string]interface{}{
+			"url":    failing.URL,
+			"secret": "secret2",
+			"events": []string{"deny"},
+		},
+	}
+	dataStr, err := json.Marshal(dataMap)
+	data := bytes.NewReader(dataStr)
+
+	fmt.Println("Create webhooks at URL: ", url)
+	res, err := http.Post(url, "application/json", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 201 {
+		t.Fatal("Unexpected status code from create webhooks call. Got Status: ", res.Status)
+	}
+
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal("Error getting response body: ", err)
+	}
+
+	fmt.Println("Output from create webhooks call: ", string(body))
+
+	hooks := []Webhook{}
+	err = json.Unmarshal(body, &hooks)
+	if err != nil {
+		t.Fatal("Error parsing response body: ", err)
+	}
+	if len(hooks) != 2 || hooks[0].Secret != "" {
+		t.Fatal("Incorrect webhooks created: ", hooks)
+	}
+
+	_, err = ACL{}.Grant(c, "service3", "object1", "1", "john", []string{"read"})
+	if err != nil {
+		t.Fatal("Error granting privileges: ", err)
+	}
+	_, err = ACL{}.Deny(c, "service3", "object1", "1", "john", []string{"write"})
+	if err != nil {
+		t.Fatal("Error denying privileges: ", err)
+	}
+
+	maxAttempts := webhookMaxAttempts
+	webhookMaxAttempts = 1
+	err = deliverDueWebhooks(c)
+	webhookMaxAttempts = maxAttempts
+	if err != nil {
+		t.Fatal("Error delivering webhooks: ", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatal("Incorrect number of deliveries received: ", len(received))
+	}
+	for idx, r := range received {
+		signature := signWebhook("secret1", r.Header.Get("X-Authorizer-Timestamp"), bodies[idx])
+		if r.Header.Get("X-Authorizer-Signature") != signature {
+			t.Fatal("Incorrect delivery signature: ", r.Header.Get("X-Authorizer-Signature"))
+		}
+	}
+	if received[0].Header.Get("X-Authorizer-Event") != EventGrant {
+		t.Fatal("Incorrect delivery event: ", received[0].Header.Get("X-Authorizer-Event"))
+	}
+
+	deliveries := func(hook Webhook, status string) []WebhookDelivery {
+		url := fmt.Sprintf("%s%s/deliveries/?status=%s", url, hook.ID.Hex(), status)
+		fmt.Println("List deliveries at URL: ", url)
+		res, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != 200 {
+			t.Fatal("Unexpected status code from list deliveries call. Got Status: ", res.Status)
+		}
+
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal("Error getting response body: ", err)
+		}
+
+		fmt.Println("Output from list deliveries call: ", string(body))
+
+		result := []WebhookDelivery{}
+		err = json.Unmarshal(body, &result)
+		if err != nil {
+			t.Fatal("Error parsing response body: ", err)
+		}
+		return result
+	}
+
+	if delivered := deliveries(hooks[0], "delivered"); len(delivered) != 2 || delivered[0].Expires == nil {
+		t.Fatal("Incorrect number of delivered deliveries: ", delivered)
+	}
+	dead := deliveries(hooks[1], "dead")
+	if len(dead) != 1 || dead[0].Event.Action != EventDeny || dead[0].LastStatus != 500 || dead[0].Expires == nil {
+		t.Fatal("Failed delivery should have been dead-lettered: ", dead)
+	}
+
+	retryUrl := fmt.Sprintf("%s%s/deliveries/%s/retry/", url, hooks[1].ID.Hex(), dead[0].ID.Hex())
+	fmt.Println("Retry delivery at URL: ", retryUrl)
+	res, err = http.Post(retryUrl, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 204 {
+		t.Fatal("Unexpected status code from retry delivery call. Got Status: ", res.Status)
+	}
+	if pending := deliveries(hooks[1], "pending"); len(pending) != 1 || pending[0].Attempts != 0 || pending[0].Expires != nil {
+		t.Fatal("Retried delivery should be pending: ", pending)
+	}
+
+	req, err := http.NewRequest("DELETE", url+hooks[1].ID.Hex()+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 204 {
+		t.Fatal("Unexpected status code from delete webhook call. Got Status: ", res.Status)
+	}
 
-	defer releaseMongo(session, db)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiErr := readAPIError(t, res, 404)
+	if apiErr.Code != ErrCodeNotFound {
+		t.Fatal("Incorrect error code for deleted webhook: ", apiErr.Code)
+	}
+}
 
-	ts := Application.StartUnitTest()
-	defer ts.Close()
+func testWatch(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	url := fmt.Sprintf("%s/v1/service/%s/object/%s/watch/", ts.URL, "service1", "object2")
 
-	testGrant(t, ts, c)
+	watch := func(query string) ([]ACLEvent, string) {
+		fmt.Println("Watch privileges at URL: ", url+query)
+		res, err := http.Get(url + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != 200 {
+			t.Fatal("Unexpected status code from watch call. Got Status: ", res.Status)
+		}
 
-	testDeny(t, ts, c)
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal("Error getting response body: ", err)
+		}
 
-	testGrantOverwrite(t, ts, c)
+		fmt.Println("Output from watch call: ", string(body))
 
-	testRevoke(t, ts, c)
+		output := struct {
+			Events    []ACLEvent `json:"events"`
+			NextSince string     `json:"next_since"`
+		}{}
+		err = json.Unmarshal(body, &output)
+		if err != nil {
+			t.Fatal("Error parsing response body: ", err)
+		}
+		return output.Events, output.NextSince
+	}
 
-	testSet(t, ts, c)
+	events, since := watch("?timeout=1")
+	if len(events) != 0 || since == "" {
+		t.Fatal("Watch without a sequence token should start from the latest event: ", events, since)
+	}
 
-	testSetNew(t, ts, c)
+	_, err := ACL{}.Grant(c, "service1", "object2", "watch1", "john", []string{"read"})
+	if err != nil {
+		t.Fatal("Error granting privileges: ", err)
+	}
+	err = ACL{}.Delete(c, "service1", "object2", "watch1", "john")
+	if err != nil {
+		t.Fatal("Error deleting ACL: ", err)
+	}
 
-	testHas(t, ts, c)
+	events, next := watch("?timeout=1&since=" + since)
+	if len(events) != 2 {
+		t.Fatal("Incorrect number of events from watch: ", events)
+	} else if events[0].Action != EventGrant || events[0].Key != "watch1" || events[0].User != "john" {
+		t.Fatal("Incorrect grant event from watch: ", events[0])
+	} else if events[1].Action != EventDelete || events[1].Seq <= events[0].Seq {
+		t.Fatal("Incorrect delete event from watch: ", events[1])
+	} else if next == since {
+		t.Fatal("Watch should advance the sequence token")
+	}
 
-	testGet(t, ts, c)
+	events, _ = watch("?timeout=1&key=other&since=" + since)
+	if len(events) != 0 {
+		t.Fatal("Watch on a key should only return events on that key: ", events)
+	}
 
-	testMatch(t, ts, c)
+	res, err := http.Get(url + "?since=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiErr := readAPIError(t, res, 400)
+	if apiErr.Code != ErrCodeInvalidParameter {
+		t.Fatal("Incorrect error code for invalid sequence token: ", apiErr.Code)
+	}
+}
 
-	testList(t, ts, c)
-	testListUser(t, ts, c)
-	testListKey(t, ts, c)
+func testCopyDryRun(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	url := fmt.Sprintf("%s/v1/service/%s/object/%s/copy/?dry_run=true", ts.URL, "service1", "object1")
+	dataMap := []map[string]interface{}{
+		map[string]interface{}{
+			"from_key": "1",
+			"to_key":   "3",
+		},
+	}
+	dataStr, err := json.Marshal(dataMap)
+	data := bytes.NewReader(dataStr)
 
-	testListServices(t, ts, c)
+	fmt.Println("Copy privileges at URL: ", url)
+	res, err := http.Post(url, "application/json", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from copy call. Got Status: ", res.Status)
+	}
 
-	testListObjects(t, ts, c)
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal("Error getting response body: ", err)
+	}
+
+	fmt.Println("Output from copy call: ", string(body))
+
+	output := []struct {
+		Changes []ACLChange `json:"changes"`
+	}{}
+	err = json.Unmarshal(body, &output)
+	if err != nil {
+		t.Fatal("Error parsing response body: ", err)
+	}
+
+	if len(output) != 1 || len(output[0].Changes) != 1 {
+		t.Fatal("Incorrect number of changes from copy dry run")
+	} else if output[0].Changes[0].Key != "3" || output[0].Changes[0].Before != nil {
+		t.Fatal("Incorrect change from copy dry run: ", output[0].Changes[0])
+	}
+
+	_, err = ACL{}.Get(c, "service1", "object1", "3", "john")
+	if err != mgo.ErrNotFound {
+		t.Fatal("Copy dry run should not have created an ACL")
+	}
+}
+
+func testClone(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	url := fmt.Sprintf("%s/v1/service/%s/object/%s/clone/", ts.URL, "service1", "object1")
+	dataMap := []map[string]interface{}{
+		map[string]interface{}{
+			"from_user": "john",
+			"to_user":   "jane",
+			"key":       "1",
+		},
+	}
+	dataStr, err := json.Marshal(dataMap)
+	data := bytes.NewReader(dataStr)
+
+	fmt.Println("Clone privileges at URL: ", url)
+	res, err := http.Post(url, "application/json", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from clone call. Got Status: ", res.Status)
+	}
+
+	acl, err := ACL{}.Get(c, "service1", "object1", "1", "jane")
+	if err != nil {
+		t.Fatal("Error getting document", err)
+	}
+
+	fmt.Println("Output From Clone: ", acl)
+	if len(acl.Privileges) != 3 {
+		t.Fatal("Incorrect number of privileges: ", len(acl.Privileges))
+	} else if acl.Privileges["fal"] != "allow" {
+		t.Fatal("Privilege not properly cloned")
+	}
+}
+
+func testUserACL(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	url := fmt.Sprintf("%s/v1/user/%s/acl/?service=service1", ts.URL, "john")
+	fmt.Println("User ACL report at URL: ", url)
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from user acl call. Got Status: ", res.Status)
+	}
+
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal("Error getting response body: ", err)
+	}
+
+	fmt.Println("Output from user acl call: ", string(body))
+
+	output := []map[string]interface{}{}
+	err = json.Unmarshal(body, &output)
+	if err != nil {
+		t.Fatal("Error parsing response body: ", err)
+	}
+
+	if len(output) != 2 {
+		t.Fatal("Incorrect number of output from user acl call")
+	}
+}
+
+func testUserACLPrivilege(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	url := fmt.Sprintf("%s/v1/user/%s/acl/?privilege=fal", ts.URL, "john")
+	fmt.Println("User ACL report at URL: ", url)
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from user acl call. Got Status: ", res.Status)
+	}
+
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal("Error getting response body: ", err)
+	}
+
+	fmt.Println("Output from user acl call: ", string(body))
+
+	output := []map[string]interface{}{}
+	err = json.Unmarshal(body, &output)
+	if err != nil {
+		t.Fatal("Error parsing response body: ", err)
+	}
+
+	if len(output) != 1 {
+		t.Fatal("Incorrect number of output from user acl privilege call")
+	} else if output[0]["Key"] != "1" {
+		t.Fatal("Wrong ACL returned from user acl privilege call")
+	}
 }
 
 func testListServices(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
@@ -120,6 +1567,88 @@ func testListObjects(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
 	}
 }
 
+func testListPaged(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	cursor := ""
+	keys := []string{}
+	for page := 0; page < 3; page++ {
+		url := fmt.Sprintf("%s/v1/service/%s/object/%s/list/?limit=1&cursor=%s", ts.URL, "service1", "object1", cursor)
+		fmt.Println("List privileges at URL: ", url)
+		res, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != 200 {
+			t.Fatal("Unexpected status code from paged list call. Got Status: ", res.Status)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal("Error getting response body: ", err)
+		}
+
+		fmt.Println("Output from paged list call: ", string(body))
+
+		output := struct {
+			ACLs       []ACL  `json:"acls"`
+			NextCursor string `json:"next_cursor"`
+		}{}
+		err = json.Unmarshal(body, &output)
+		if err != nil {
+			t.Fatal("Error parsing response body: ", err)
+		}
+
+		if len(output.ACLs) != 1 {
+			t.Fatal("Incorrect number of output from paged list privilege call")
+		}
+		keys = append(keys, output.ACLs[0].Key)
+
+		cursor = output.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	if len(keys) != 2 || keys[0] != "1" || keys[1] != "2" {
+		t.Fatal("Incorrect keys paged from list privilege call: ", keys)
+	}
+}
+
+func testListNDJSON(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	url := fmt.Sprintf("%s/v1/service/%s/object/%s/list/", ts.URL, "service1", "object1")
+
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", url, nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	fmt.Println("Stream privileges at URL: ", url)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from streamed list call. Got Status: ", res.Status)
+	}
+	if res.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatal("Unexpected content type from streamed list call: ", res.Header.Get("Content-Type"))
+	}
+
+	defer res.Body.Close()
+	decoder := json.NewDecoder(res.Body)
+	count := 0
+	for decoder.More() {
+		acl := ACL{}
+		err = decoder.Decode(&acl)
+		if err != nil {
+			t.Fatal("Error parsing streamed response line: ", err)
+		}
+		count++
+	}
+
+	if count != 2 {
+		t.Fatal("Incorrect number of lines from streamed list privilege call: ", count)
+	}
+}
+
 func testListKey(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
 	url := fmt.Sprintf("%s/v1/service/%s/object/%s/list/?key=1", ts.URL, "service1", "object1")
 	fmt.Println("List privileges at URL: ", url)
@@ -350,6 +1879,61 @@ func testHas(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
 	}
 }
 
+func testHasExplain(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	url := fmt.Sprintf("%s/v1/service/%s/object/%s/has/?explain=true", ts.URL, "service1", "object1")
+	dataMap := []map[string]interface{}{
+		map[string]interface{}{
+			"user":       "john",
+			"key":        "1",
+			"privileges": []string{"far", "faz", "foo"},
+		},
+	}
+	dataStr, err := json.Marshal(dataMap)
+	data := bytes.NewReader(dataStr)
+
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", url, data)
+	fmt.Println("Explain privileges at URL: ", url)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatal("Unexpected status code from explain call. Got Status: ", res.Status)
+	}
+
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal("Error getting response body: ", err)
+	}
+
+	fmt.Println("Output from explain call: ", string(body))
+
+	output := []struct {
+		Privilege   string      `json:"privilege"`
+		Explanation Explanation `json:"explanation"`
+	}{}
+	err = json.Unmarshal(body, &output)
+	if err != nil {
+		t.Fatal("Error parsing response body: ", err)
+	}
+
+	if len(output) != 1 {
+		t.Fatal("Incorrect number of output from explain call")
+	}
+
+	explanation := output[0].Explanation
+	if output[0].Privilege != "deny" || explanation.Reason != ReasonExplicitDeny {
+		t.Fatal("Incorrect decision from explain call: ", explanation)
+	} else if len(explanation.Privileges) != 3 ||
+		explanation.Privileges[0].Reason != ReasonAllowed ||
+		explanation.Privileges[1].Reason != ReasonExplicitDeny ||
+		explanation.Privileges[2].Reason != ReasonNotGranted {
+		t.Fatal("Incorrect privilege reasons from explain call: ", explanation.Privileges)
+	}
+}
+
 func testSetNew(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
 	url := fmt.Sprintf("%s/v1/service/%s/object/%s/set/", ts.URL, "service1", "object1")
 	dataMap := []map[string]interface{}{
@@ -523,6 +2107,107 @@ func testGrant(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
 
 }
 
+func testGrantErrors(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	grantUrl := fmt.Sprintf("%s/v1/service/%s/object/%s/grant/", ts.URL, "service1", "object1")
+
+	fmt.Println("Granting malformed body at URL: ", grantUrl)
+	res, err := http.Post(grantUrl, "application/json", bytes.NewReader([]byte("[{")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiErr := readAPIError(t, res, 400)
+	if apiErr.Code != ErrCodeMalformedJSON {
+		t.Fatal("Unexpected error code for malformed body: ", apiErr.Code)
+	}
+
+	grantDataMap := []map[string]interface{}{
+		map[string]interface{}{
+			"user":       "john",
+			"key":        "1",
+			"privileges": []string{"faz"},
+		},
+		map[string]interface{}{
+			"key":        "1",
+			"privileges": []string{"faz"},
+		},
+	}
+	grantDataStr, _ := json.Marshal(grantDataMap)
+
+	fmt.Println("Granting item without user at URL: ", grantUrl)
+	res, err = http.Post(grantUrl, "application/json", bytes.NewReader(grantDataStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiErr = readAPIError(t, res, 400)
+	if apiErr.Code != ErrCodeMissingField || apiErr.Field != "user" || apiErr.Item == nil || *apiErr.Item != 1 {
+		t.Fatal("Unexpected error for item without user: ", apiErr)
+	}
+}
+
+func testRequestLimits(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
+	grantUrl := fmt.Sprintf("%s/v1/service/%s/object/%s/grant/", ts.URL, "service1", "object1")
+	grantDataMap := []map[string]interface{}{
+		map[string]interface{}{
+			"user":       "john",
+			"key":        "1",
+			"privileges": []string{"faz"},
+			"extra":      true,
+		},
+	}
+	grantDataStr, _ := json.Marshal(grantDataMap)
+
+	strictRequests = true
+	defer func() { strictRequests = false }()
+
+	fmt.Println("Granting item with unknown field at URL: ", grantUrl)
+	res, err := http.Post(grantUrl, "application/json", bytes.NewReader(grantDataStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiErr := readAPIError(t, res, 400)
+	if apiErr.Code != ErrCodeUnknownField || apiErr.Field != "extra" {
+		t.Fatal("Unexpected error for item with unknown field: ", apiErr)
+	}
+
+	bodyLimit := maxBodyBytes
+	maxBodyBytes = 16
+	defer func() { maxBodyBytes = bodyLimit }()
+
+	fmt.Println("Granting large body at URL: ", grantUrl)
+	res, err = http.Post(grantUrl, "application/json", bytes.NewReader(grantDataStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiErr = readAPIError(t, res, 413)
+	if apiErr.Code != ErrCodeBodyTooLarge {
+		t.Fatal("Unexpected error for large body: ", apiErr)
+	}
+}
+
+// Reads the error from an error response, failing if the status isn't the expected one
+func readAPIError(t *testing.T, res *http.Response, status int) APIError {
+	if res.StatusCode != status {
+		t.Fatal("Unexpected status code from error call. Got Status: ", res.Status)
+	}
+
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal("Error getting response body: ", err)
+	}
+
+	fmt.Println("Output from error call: ", string(body))
+
+	output := struct {
+		Error APIError `json:"error"`
+	}{}
+	err = json.Unmarshal(body, &output)
+	if err != nil {
+		t.Fatal("Error parsing response body: ", err)
+	}
+	return output.Error
+}
+
 func testGrantOverwrite(t *testing.T, ts *httptest.Server, c *mgo.Collection) {
 	grantUrl := fmt.Sprintf("%s/v1/service/%s/object/%s/grant/", ts.URL, "service1", "object1")
 	grantDataMap := []map[string]interface{}{
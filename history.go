@@ -0,0 +1,327 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"time"
+)
+
+/*
+A revision of an ACL, recorded each time it changes.  Revision numbers the revisions of
+each service/object/key/user from 1, and keeps counting when the ACL is deleted and created
+again.  Privileges are the ACL's privileges after the change, and Deleted marks the
+revision that removed it.  Baseline marks the revision recorded for an ACL that existed
+before history was, holding its privileges when history was first recorded, and dated when
+the ACL was created.
+*/
+type ACLRevision struct {
+	Service    string                 `json:"service" bson:"service"`
+	Object     string                 `json:"object" bson:"object"`
+	Key        string                 `json:"key" bson:"key"`
+	User       string                 `json:"user" bson:"user"`
+	Revision   int64                  `json:"revision" bson:"revision"`
+	Privileges map[string]interface{} `json:"privileges" bson:"privileges"`
+	Deleted    bool                   `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	Baseline   bool                   `json:"baseline,omitempty" bson:"baseline,omitempty"`
+	Time       time.Time              `json:"time" bson:"time"`
+	Caller     string                 `json:"caller" bson:"caller"`
+}
+
+// Gets the history collection in the database of the ACL collection
+func historyCollection(c *mgo.Collection) *mgo.Collection {
+	mongo, _ := Application.Config["mongo"].(map[string]interface{})
+	name, ok := mongo["history_collection"].(string)
+	if !ok {
+		name = "acl_history"
+	}
+	return c.Database.C(name)
+}
+
+// Creates an Index on the history collection, then records baseline revisions for the ACLs
+// from before history was
+func (v ACLRevision) EnsureIndex(c *mgo.Collection) error {
+	index := mgo.Index{
+		Key:        []string{"service", "object", "key", "user", "revision"},
+		Unique:     true,
+		DropDups:   false,
+		Background: false,
+		Sparse:     false,
+	}
+	err := historyCollection(c).EnsureIndex(index)
+	if err != nil {
+		return err
+	}
+	return recordBaselines(c)
+}
+
+/*
+Records a baseline revision for each ACL without any history, so point in time reads and
+restores see the ACLs written before history was recorded.  It only runs until it has
+finished once, which is marked in the counters collection.  The baseline takes the ACL's
+current revision, so a write racing it records the next one, and another process recording
+the same baseline is ignored.
+*/
+func recordBaselines(c *mgo.Collection) error {
+	counters := c.Database.C("counters")
+	marker := bson.M{"counter": historyCollection(c).Name + "_baselines"}
+	count, err := counters.Find(marker).Count()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	acl := struct {
+		ID         interface{}            `bson:"_id"`
+		Service    string                 `bson:"service"`
+		Object     string                 `bson:"object"`
+		Key        string                 `bson:"key"`
+		User       string                 `bson:"user"`
+		Privileges map[string]interface{} `bson:"privileges"`
+		Revision   int64                  `bson:"revision"`
+	}{}
+	recorded := 0
+	iter := c.Find(nil).Iter()
+	for iter.Next(&acl) {
+		selector := bson.M{"service": acl.Service, "object": acl.Object, "key": acl.Key, "user": acl.User}
+		count, err = historyCollection(c).Find(selector).Limit(1).Count()
+		if err != nil {
+			iter.Close()
+			return err
+		} else if count > 0 {
+			continue
+		}
+
+		// ACLs created by an upsert have an ID holding their creation time
+		created := time.Time{}
+		if id, ok := acl.ID.(bson.ObjectId); ok {
+			created = id.Time().UTC()
+		}
+		err = historyCollection(c).Insert(ACLRevision{Service: acl.Service, Object: acl.Object, Key: acl.Key,
+			User: acl.User, Revision: acl.Revision, Privileges: acl.Privileges, Baseline: true, Time: created,
+			Caller: "system"})
+		if err != nil && !mgo.IsDup(err) {
+			iter.Close()
+			return err
+		}
+		recorded++
+	}
+	err = iter.Close()
+	if err != nil {
+		return err
+	}
+
+	log.Info("Recorded baseline history for %d ACLs", recorded)
+	return counters.Insert(marker)
+}
+
+// Selects the revision an ACL was last deleted at in the counters collection
+func deletedSelector(c *mgo.Collection, service string, object string, key string, user string) bson.M {
+	return bson.M{"counter": historyCollection(c).Name, "service": service, "object": object, "key": key,
+		"user": user}
+}
+
+// Gets the revision the ACL was last deleted at, which is 0 if it never was
+func deletedRevision(c *mgo.Collection, service string, object string, key string, user string) (int64, error) {
+	counter := struct {
+		Seq int64 `bson:"seq"`
+	}{}
+	err := c.Database.C("counters").Find(deletedSelector(c, service, object, key, user)).One(&counter)
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return counter.Seq, err
+}
+
+// Records the revision an ACL is being deleted at, so it carries on counting from there if
+// it's created again
+func markDeleted(c *mgo.Collection, service string, object string, key string, user string, revision int64) error {
+	_, err := c.Database.C("counters").Upsert(deletedSelector(c, service, object, key, user),
+		bson.M{"$max": bson.M{"seq": revision}})
+	return err
+}
+
+// Records a revision of an ACL in its history
+func recordRevision(c *mgo.Collection, actor *Actor, revision int64, service string, object string, key string,
+	user string, privileges map[string]interface{}, deleted bool) error {
+	entry := ACLRevision{
+		Service:    service,
+		Object:     object,
+		Key:        key,
+		User:       user,
+		Revision:   revision,
+		Privileges: privileges,
+		Deleted:    deleted,
+		Time:       time.Now().UTC(),
+		Caller:     "system",
+	}
+	if actor != nil {
+		entry.Caller = actor.Caller
+	}
+
+	log.Finest("Recording ACL revision: %+v", entry)
+	return historyCollection(c).Insert(entry)
+}
+
+// Retrieves the revisions of the ACL for the object's key and the user, newest first
+func (a ACL) History(c *mgo.Collection, service string, object string, key string, user string) ([]ACLRevision, error) {
+	selector := bson.M{"service": service, "object": object, "key": key, "user": user}
+	result := []ACLRevision{}
+	err := historyCollection(c).Find(selector).Sort("-revision").All(&result)
+	return result, err
+}
+
+// Retrieves the ACL for the object's key and the user as it was at the ACL's AsOf time.
+// Revisions are numbered in the order their writes landed, so the highest one recorded by
+// then holds the privileges the ACL had.
+func (a ACL) getAsOf(c *mgo.Collection, service string, object string, key string, user string) (ACL, error) {
+	selector := bson.M{"service": service, "object": object, "key": key, "user": user,
+		"time": bson.M{"$lte": a.AsOf}}
+	revision := ACLRevision{}
+	err := historyCollection(c).Find(selector).Sort("-revision").One(&revision)
+	if err == nil && revision.Deleted {
+		err = mgo.ErrNotFound
+	}
+	if err != nil {
+		return ACL{}, err
+	}
+	return ACL{Service: service, Object: object, Key: key, User: user, Privileges: revision.Privileges,
+		Revision: revision.Revision}, nil
+}
+
+/*
+Builds the pipeline for a page of the ACL list as it was at the ACL's AsOf time, taking the
+latest revision of each key and user up to then.  Takes the same filters as listQuery.  The
+cursor is applied before grouping, since a group holds a single key and user.
+*/
+func (a ACL) listAsOfPipe(c *mgo.Collection, service string, object string, key string, user string,
+	privileges []string, value string, page Page) (*mgo.Pipe, error) {
+	selector := bson.M{"service": service, "object": object, "time": bson.M{"$lte": a.AsOf}}
+	if key != "" {
+		selector["key"] = key
+	}
+	if user != "" {
+		selector["user"] = user
+	}
+
+	err := cursorSelector(selector, page.Cursor, true)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := bson.M{"deleted": bson.M{"$ne": true}}
+	privilegeSelector(latest, privileges, value)
+
+	pipeline := []bson.M{
+		{"$match": selector},
+		{"$sort": bson.M{"revision": -1}},
+		{"$group": bson.M{
+			"_id":        bson.M{"key": "$key", "user": "$user"},
+			"service":    bson.M{"$first": "$service"},
+			"object":     bson.M{"$first": "$object"},
+			"key":        bson.M{"$first": "$key"},
+			"user":       bson.M{"$first": "$user"},
+			"privileges": bson.M{"$first": "$privileges"},
+			"revision":   bson.M{"$first": "$revision"},
+			"deleted":    bson.M{"$first": "$deleted"},
+		}},
+		{"$match": latest},
+		{"$sort": bson.D{{Name: "key", Value: 1}, {Name: "user", Value: 1}}},
+	}
+	if page.Limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": page.Limit + 1})
+	}
+
+	log.Finest("Listing ACLs as of %s: %v", a.AsOf, pipeline)
+	return historyCollection(c).Pipe(pipeline), nil
+}
+
+// Restores the ACL for the object's key and the user to a revision, deleting it if the
+// revision did.  Returns mgo.ErrNotFound if there's no such revision.
+func (a ACL) Restore(c *mgo.Collection, service string, object string, key string, user string, revision int64) error {
+	selector := bson.M{"service": service, "object": object, "key": key, "user": user, "revision": revision}
+	restored := ACLRevision{}
+	err := historyCollection(c).Find(selector).One(&restored)
+	if err != nil {
+		return err
+	}
+
+	log.Finest("Restoring ACL to revision: %+v", restored)
+	if !restored.Deleted {
+		_, err = a.Set(c, service, object, key, user, restored.Privileges)
+		return err
+	}
+
+	err = a.Delete(c, service, object, key, user)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Gets the ACL value to read with, which sees the ACLs as they were at the as_of parameter
+// when one is given.  Returns false if the response was already written.
+func readACL(w http.ResponseWriter, r *http.Request) (ACL, bool) {
+	asOf, ok := timeParam(w, r, "as_of")
+	return ACL{AsOf: asOf}, ok
+}
+
+// This is a URL handler for getting the revisions of an ACL
+func historyPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, object := getRequestData(r)
+
+	key := r.URL.Query().Get("key")
+	user := r.URL.Query().Get("user")
+	if key == "" || user == "" {
+		log.Debug("ACL history requested without a key and user")
+		writeError(w, 400, ErrCodeInvalidParameter, "key and user are required")
+		return
+	}
+
+	result, err := ACL{}.History(c, service, object, key, user)
+	if err != nil {
+		log.Error("An error occurred getting ACL history. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred getting ACL history")
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error("Error marshalling ACL history data: %s", err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred getting ACL history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// This is a URL handler for restoring ACLs to earlier revisions
+func restorePrivilegesHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, object := getRequestData(r)
+	actor := requestActor(r)
+
+	items := []restoreItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+
+	for idx, item := range items {
+		err := ACL{Actor: actor}.Restore(c, service, object, item.Key, item.User, item.Revision)
+		if err == ErrChangeNotRecorded {
+			warnNotRecorded(w, idx)
+		} else if err == mgo.ErrNotFound {
+			log.Debug("No revision %d of ACL %s/%s/%s/%s", item.Revision, service, object, item.Key, item.User)
+			writeItemError(w, 404, ErrCodeNotFound, "No such revision", idx, "revision")
+			return
+		} else if err != nil {
+			log.Error("An error occurred restoring ACL. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred restoring privileges", idx, "")
+			return
+		}
+	}
+
+	w.WriteHeader(204)
+}
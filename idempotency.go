@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	log "code.google.com/p/log4go"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gorilla/mux"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The header carrying the idempotency key of a write request
+const idempotencyHeader = "Idempotency-Key"
+
+// The header marking a response replayed for a repeated idempotency key
+const idempotentReplayHeader = "Idempotent-Replayed"
+
+// The longest idempotency key accepted
+const maxIdempotencyKeyLength = 255
+
+// The routes that apply their bodies as they're read.  A request has to be read whole to be
+// fingerprinted, so these don't take idempotency keys, and signed ones are spooled to disk.
+var streamingRoutes = map[string]bool{"ImportACL": true, "RestoreSnapshot": true}
+
+// Checks if the request is to a route that streams its body
+func isStreamingRoute(r *http.Request) bool {
+	match := mux.RouteMatch{}
+	return Application.Router.Match(r, &match) && match.Route != nil && streamingRoutes[match.Route.GetName()]
+}
+
+// How long the response to a request with an idempotency key is kept
+var idempotencyWindow = 24 * time.Hour
+
+// Where responses to requests with idempotency keys are kept
+var idempotency idempotencyStore = mongoIdempotencyStore{}
+
+// Reads the idempotency settings from the "idempotency" section of the config
+func configureIdempotency() {
+	config, ok := Application.Config["idempotency"].(map[string]interface{})
+	if !ok {
+		log.Info("No idempotency settings in config, defaulting to store: mongo, window: %s", idempotencyWindow)
+		return
+	}
+
+	if seconds, ok := config["window_seconds"].(float64); ok && seconds > 0 {
+		idempotencyWindow = time.Duration(seconds) * time.Second
+	}
+
+	store, _ := config["store"].(string)
+	switch store {
+	case "", "mongo":
+		store = "mongo"
+		idempotency = mongoIdempotencyStore{}
+	case "memory":
+		idempotency = newMemoryIdempotencyStore()
+	default:
+		panic(fmt.Sprintf("Idempotency store must be mongo or memory, got '%s'", store))
+	}
+
+	log.Info("Using idempotency settings store: %s, window: %s", store, idempotencyWindow)
+}
+
+/*
+The response to a request made with an idempotency key.  Key is the idempotency key scoped
+to the caller that sent it, and Fingerprint a hash of the request, so a key can't be reused
+for a different request.  Status is 0 while the first request is still being handled.
+Header holds the response headers the handler set, which are replayed with the body.
+*/
+type IdempotentResponse struct {
+	Key         string      `bson:"_id"`
+	Fingerprint string      `bson:"fingerprint"`
+	Status      int         `bson:"status"`
+	Header      http.Header `bson:"header,omitempty"`
+	Body        []byte      `bson:"body,omitempty"`
+	Expires     time.Time   `bson:"expires"`
+}
+
+/*
+Keeps the responses to requests with idempotency keys.  Claim records a new request's key,
+or returns the response already recorded under it, which is in progress while its Status is
+0.  Save records the response once the request is handled, and Release forgets a key so
+the request can be tried again.
+*/
+type idempotencyStore interface {
+	Claim(c *mgo.Collection, response IdempotentResponse) (*IdempotentResponse, error)
+	Save(c *mgo.Collection, response IdempotentResponse) error
+	Release(c *mgo.Collection, key string) error
+}
+
+// Keeps idempotent responses in a collection of the ACL database
+type mongoIdempotencyStore struct{}
+
+// Gets the idempotency collection in the database of the ACL collection
+func idempotencyCollection(c *mgo.Collection) *mgo.Collection {
+	mongo, _ := Application.Config["mongo"].(map[string]interface{})
+	name, ok := mongo["idempotency_collection"].(string)
+	if !ok {
+		name = "idempotency_keys"
+	}
+	return c.Database.C(name)
+}
+
+// Creates an index on the idempotency collection that removes responses once they expire
+func (s mongoIdempotencyStore) EnsureIndex(c *mgo.Collection) error {
+	index := mgo.Index{
+		Key:         []string{"expires"},
+		ExpireAfter: time.Second,
+	}
+	return idempotencyCollection(c).EnsureIndex(index)
+}
+
+// Claims the key by inserting it, taking over a response that expired but wasn't removed yet
+func (s mongoIdempotencyStore) Claim(c *mgo.Collection, response IdempotentResponse) (*IdempotentResponse, error) {
+	err := idempotencyCollection(c).Insert(response)
+	if !mgo.IsDup(err) {
+		return nil, err
+	}
+
+	selector := bson.M{"_id": response.Key, "expires": bson.M{"$lte": time.Now()}}
+	_, err = idempotencyCollection(c).Find(selector).Apply(mgo.Change{Update: response}, &IdempotentResponse{})
+	if err != mgo.ErrNotFound {
+		return nil, err
+	}
+
+	existing := &IdempotentResponse{}
+	err = idempotencyCollection(c).FindId(response.Key).One(existing)
+	if err == mgo.ErrNotFound {
+		// Expired and removed since the insert, so try again
+		return s.Claim(c, response)
+	}
+	return existing, err
+}
+
+func (s mongoIdempotencyStore) Save(c *mgo.Collection, response IdempotentResponse) error {
+	return idempotencyCollection(c).UpdateId(response.Key, response)
+}
+
+func (s mongoIdempotencyStore) Release(c *mgo.Collection, key string) error {
+	err := idempotencyCollection(c).RemoveId(key)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Keeps idempotent responses in memory, for a single server
+type memoryIdempotencyStore struct {
+	lock      sync.Mutex
+	responses map[string]IdempotentResponse
+	nextSweep time.Time
+}
+
+// Creates an empty in memory idempotency store
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{responses: map[string]IdempotentResponse{}}
+}
+
+// Claims the key unless an unexpired response holds it.  Expired responses are swept out
+// once a minute.
+func (s *memoryIdempotencyStore) Claim(c *mgo.Collection, response IdempotentResponse) (*IdempotentResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for key, existing := range s.responses {
+			if !existing.Expires.After(now) {
+				delete(s.responses, key)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	if existing, ok := s.responses[response.Key]; ok && existing.Expires.After(now) {
+		return &existing, nil
+	}
+	s.responses[response.Key] = response
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Save(c *mgo.Collection, response IdempotentResponse) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.responses[response.Key] = response
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(c *mgo.Collection, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.responses, key)
+	return nil
+}
+
+// Passes a response through, keeping a copy of it to store
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = 200
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// Flushes the response, so streamed responses aren't held back by recording them
+func (w *recordingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hashes the parts of a request that must match for its idempotency key to be reused,
+// including the If-Match precondition it was made on
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", r.Method, r.URL.RequestURI(), r.Header.Get("If-Match"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+/*
+Serves a request, making writes with an Idempotency-Key header idempotent.  The first
+request with a key is handled and its response stored for the idempotency window; later
+requests from the same caller with that key get the stored response replayed instead of
+being applied again.  Responses with a 5xx status aren't stored, nor are those of handlers
+that panic, so the request can be retried.
+*/
+func serveIdempotent(w http.ResponseWriter, r *http.Request, c *mgo.Collection, next http.HandlerFunc) {
+	key := r.Header.Get(idempotencyHeader)
+	if key == "" || r.Method == "GET" || r.Method == "HEAD" {
+		next(w, r)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		writeError(w, 400, ErrCodeInvalidParameter, fmt.Sprintf("%s must be at most %d characters",
+			idempotencyHeader, maxIdempotencyKeyLength))
+		return
+	}
+	if isStreamingRoute(r) {
+		writeError(w, 400, ErrCodeInvalidParameter, fmt.Sprintf("%s isn't accepted on this route, "+
+			"its body is streamed rather than read first", idempotencyHeader))
+		return
+	}
+
+	body, apiErr := bufferBody(r)
+	if apiErr != nil {
+		apiErr.Write(w, authErrorStatus[apiErr.Code])
+		return
+	}
+
+	caller := anonymousCaller
+	if requestCaller(r) != nil {
+		caller = requestCaller(r).ID
+	}
+	response := IdempotentResponse{
+		Key:         caller + "\n" + key,
+		Fingerprint: requestFingerprint(r, body),
+		Expires:     time.Now().Add(idempotencyWindow),
+	}
+
+	existing, err := idempotency.Claim(c, response)
+	if err != nil {
+		log.Error("An error occurred claiming idempotency key '%s': %s", key, err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred checking the idempotency key")
+		return
+	}
+
+	if existing != nil {
+		if existing.Fingerprint != response.Fingerprint {
+			log.Debug("Idempotency key '%s' reused for a different request", key)
+			writeError(w, 422, ErrCodeIdempotencyKeyReused, "The idempotency key was used for a different request")
+		} else if existing.Status == 0 {
+			log.Debug("Idempotency key '%s' is still in progress", key)
+			writeError(w, 409, ErrCodeConflict, "A request with the idempotency key is still in progress")
+		} else {
+			log.Debug("Replaying response to idempotency key '%s'", key)
+			for name, values := range existing.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(idempotentReplayHeader, "true")
+			w.WriteHeader(existing.Status)
+			w.Write(existing.Body)
+		}
+		return
+	}
+
+	// Release the claim if the response isn't saved, including when the handler panics,
+	// so the request can be retried
+	saved := false
+	defer func() {
+		if saved {
+			return
+		}
+		if err := idempotency.Release(c, response.Key); err != nil {
+			log.Error("An error occurred releasing idempotency key '%s': %s", key, err)
+		}
+	}()
+
+	// Headers set before the handler, like the request ID, belong to each request
+	before := http.Header{}
+	for name, values := range w.Header() {
+		before[name] = values
+	}
+
+	recorder := &recordingWriter{ResponseWriter: w}
+	next(recorder, r)
+
+	if recorder.status == 0 {
+		recorder.status = 200
+	}
+	if recorder.status >= 500 {
+		return
+	}
+
+	response.Status = recorder.status
+	response.Header = http.Header{}
+	for name, values := range recorder.Header() {
+		if _, ok := before[name]; !ok {
+			response.Header[name] = values
+		}
+	}
+	response.Body = recorder.body.Bytes()
+	if err := idempotency.Save(c, response); err != nil {
+		log.Error("An error occurred storing the response to idempotency key '%s': %s", key, err)
+		return
+	}
+	saved = true
+}
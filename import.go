@@ -0,0 +1,716 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	log "code.google.com/p/log4go"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The formats records can be imported from
+const (
+	ImportFormatJSONL = "jsonl"
+	ImportFormatCSV   = "csv"
+)
+
+// The number of records applied between checkpoints when no batch size is given
+const defaultImportBatchSize = 500
+
+// The most failures listed in an import summary.  Later ones are only counted.
+const maxImportFailures = 100
+
+// The longest JSONL record accepted
+const maxImportLineBytes = 1 << 20
+
+// The columns of a CSV import, which its header row names in any order
+var importColumns = []string{"service", "object", "key", "user", "privileges", "op"}
+
+/*
+A record of an import, applying an operation to an ACL.  Op is grant, deny, revoke or set.
+Grant, deny and revoke take a list of privilege names as Names, and set a map of
+privileges to values as Privileges.
+*/
+type ImportRecord struct {
+	Row        int
+	Op         string
+	Service    string
+	Object     string
+	Key        string
+	User       string
+	Names      []string
+	Privileges map[string]interface{}
+}
+
+// Checks the record can be applied
+func (rec ImportRecord) validate() *APIError {
+	for _, field := range []struct{ name, value string }{{"op", rec.Op}, {"service", rec.Service},
+		{"object", rec.Object}, {"key", rec.Key}, {"user", rec.User}} {
+		if field.value == "" {
+			return missingField(field.name)
+		}
+	}
+
+	switch rec.Op {
+	case EventGrant, EventDeny, EventRevoke:
+		if rec.Names == nil {
+			return missingField("privileges")
+		}
+		for _, privilege := range rec.Names {
+			if err := validatePrivilegeName(privilege); err != nil {
+				return err
+			}
+		}
+	case EventSet:
+		if rec.Privileges == nil {
+			return missingField("privileges")
+		}
+		for privilege := range rec.Privileges {
+			if err := validatePrivilegeName(privilege); err != nil {
+				return err
+			}
+		}
+	default:
+		return invalidField("op", "op must be grant, deny, revoke or set")
+	}
+	return nil
+}
+
+// Applies the record to its ACL
+func (rec ImportRecord) apply(c *mgo.Collection, actor *Actor) error {
+	acl := ACL{Actor: actor}
+	var err error
+	switch rec.Op {
+	case EventGrant:
+		_, err = acl.Grant(c, rec.Service, rec.Object, rec.Key, rec.User, rec.Names)
+	case EventDeny:
+		_, err = acl.Deny(c, rec.Service, rec.Object, rec.Key, rec.User, rec.Names)
+	case EventRevoke:
+		_, err = acl.Revoke(c, rec.Service, rec.Object, rec.Key, rec.User, rec.Names)
+	case EventSet:
+		_, err = acl.Set(c, rec.Service, rec.Object, rec.Key, rec.User, rec.Privileges)
+	}
+	return err
+}
+
+// Reads the records of an import in order.  Read returns a record that couldn't be
+// parsed along with an APIError describing why, so the import can carry on past it, and
+// io.EOF after the last record.
+type importReader interface {
+	Read() (ImportRecord, *APIError, error)
+}
+
+// Reads import records from newline delimited JSON objects
+type jsonlImportReader struct {
+	reader *bufio.Reader
+	row    int
+}
+
+func newJSONLImportReader(r io.Reader) *jsonlImportReader {
+	return &jsonlImportReader{reader: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Reads the next line.  A line longer than maxImportLineBytes is skipped to its end rather
+// than held, and returned as nil with tooLong set.  Returns io.EOF after the last line.
+func (r *jsonlImportReader) readLine() (line []byte, tooLong bool, err error) {
+	line = []byte{}
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			if len(bytes.TrimRight(line, "\r\n")) > maxImportLineBytes {
+				line, tooLong = nil, true
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		} else if err == io.EOF && (len(line) > 0 || tooLong) {
+			return line, tooLong, nil
+		}
+		return line, tooLong, err
+	}
+}
+
+// Reads the next record, skipping blank lines
+func (r *jsonlImportReader) Read() (ImportRecord, *APIError, error) {
+	var line []byte
+	for len(line) == 0 {
+		raw, tooLong, err := r.readLine()
+		if err != nil {
+			return ImportRecord{}, nil, err
+		}
+		if tooLong {
+			r.row++
+			return ImportRecord{Row: r.row}, &APIError{Code: ErrCodeBodyTooLarge,
+				Message: fmt.Sprintf("Record is longer than %d bytes", maxImportLineBytes)}, nil
+		}
+		line = bytes.TrimSpace(raw)
+	}
+	r.row++
+
+	raw := struct {
+		Op         string          `json:"op"`
+		Service    string          `json:"service"`
+		Object     string          `json:"object"`
+		Key        string          `json:"key"`
+		User       string          `json:"user"`
+		Privileges json.RawMessage `json:"privileges"`
+	}{}
+	rec := ImportRecord{Row: r.row}
+	if line[0] != '{' {
+		return rec, &APIError{Code: ErrCodeInvalidItem, Message: "Import records must be objects"}, nil
+	}
+	err := json.Unmarshal(line, &raw)
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		return rec, invalidField(typeErr.Field, typeErr.Field+" must be of type "+typeErr.Type.String()), nil
+	} else if err != nil {
+		return rec, &APIError{Code: ErrCodeMalformedJSON, Message: "Could not parse record. Seems to be malformed JSON."}, nil
+	}
+	rec.Op, rec.Service, rec.Object, rec.Key, rec.User = raw.Op, raw.Service, raw.Object, raw.Key, raw.User
+
+	if len(raw.Privileges) > 0 && string(raw.Privileges) != "null" {
+		if rec.Op == EventSet {
+			err = json.Unmarshal(raw.Privileges, &rec.Privileges)
+		} else {
+			err = json.Unmarshal(raw.Privileges, &rec.Names)
+		}
+		if err != nil {
+			if rec.Op == EventSet {
+				return rec, invalidField("privileges", "privileges of set must be an object"), nil
+			}
+			return rec, invalidField("privileges", "privileges must be a list of names"), nil
+		}
+	}
+	return rec, nil, nil
+}
+
+/*
+Reads import records from CSV with a header row naming the columns.  Privileges are
+separated by semicolons, and for set each is a name=value pair:
+
+	service,object,key,user,privileges,op
+	docs,document,7,alice,read;write,grant
+	docs,document,7,bob,read=allow;write=deny,set
+*/
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+// Creates a CSV import reader, reading its header row.  Returns an APIError if the header
+// doesn't name the import columns.
+func newCSVImportReader(r io.Reader) (*csvImportReader, *APIError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, &APIError{Code: ErrCodeInvalidBody, Message: "CSV import has no header row"}, nil
+	} else if _, ok := err.(*csv.ParseError); ok {
+		return nil, &APIError{Code: ErrCodeInvalidBody, Message: err.Error()}, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	columns := map[string]int{}
+	for idx, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, &APIError{Code: ErrCodeInvalidBody, Message: "CSV import header has no " + name + " column"}, nil
+		}
+	}
+	return &csvImportReader{reader: reader, columns: columns}, nil, nil
+}
+
+func (r *csvImportReader) Read() (ImportRecord, *APIError, error) {
+	fields, err := r.reader.Read()
+	if err == io.EOF {
+		return ImportRecord{}, nil, io.EOF
+	}
+	r.row++
+	rec := ImportRecord{Row: r.row}
+	if _, ok := err.(*csv.ParseError); ok {
+		return rec, &APIError{Code: ErrCodeInvalidItem, Message: err.Error()}, nil
+	} else if err != nil {
+		return rec, nil, err
+	}
+
+	column := func(name string) string {
+		return strings.TrimSpace(fields[r.columns[name]])
+	}
+	rec.Op, rec.Service, rec.Object, rec.Key, rec.User =
+		column("op"), column("service"), column("object"), column("key"), column("user")
+
+	privileges := column("privileges")
+	if rec.Op == EventSet {
+		rec.Privileges = map[string]interface{}{}
+	} else {
+		rec.Names = []string{}
+	}
+	for _, privilege := range strings.Split(privileges, ";") {
+		privilege = strings.TrimSpace(privilege)
+		if privilege == "" {
+			continue
+		}
+		if rec.Op != EventSet {
+			rec.Names = append(rec.Names, privilege)
+			continue
+		}
+		parts := strings.SplitN(privilege, "=", 2)
+		if len(parts) != 2 {
+			return rec, invalidField("privileges", "privileges of set must be name=value pairs"), nil
+		}
+		rec.Privileges[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return rec, nil, nil
+}
+
+// Creates a reader of records in the format
+func newImportReader(r io.Reader, format string) (importReader, *APIError, error) {
+	switch format {
+	case ImportFormatJSONL:
+		return newJSONLImportReader(r), nil, nil
+	case ImportFormatCSV:
+		return newCSVImportReader(r)
+	}
+	return nil, &APIError{Code: ErrCodeInvalidParameter, Message: "format must be jsonl or csv"}, nil
+}
+
+// An error reading the records of an import, as opposed to storing them or its progress
+type importReadError struct {
+	err error
+}
+
+func (e importReadError) Error() string {
+	return "An error occurred reading records: " + e.err.Error()
+}
+
+// A record that wasn't applied, and why
+type ImportFailure struct {
+	Row   int       `json:"row"`
+	Error *APIError `json:"error"`
+}
+
+/*
+The progress of an import.  Rows counts the records read, which were either applied,
+skipped because an earlier run of the import already applied them, or failed.  Retried
+counts the rows an earlier run failed that were tried again.  Checkpoint is the last row
+the import has finished with, and Failures lists the first failures.  Unrecorded counts the
+applied rows whose changes weren't recorded in the audit, event or history logs.
+*/
+type ImportSummary struct {
+	Rows       int             `json:"rows"`
+	Applied    int             `json:"applied"`
+	Skipped    int             `json:"skipped"`
+	Failed     int             `json:"failed"`
+	Retried    int             `json:"retried"`
+	Checkpoint int             `json:"checkpoint"`
+	Done       bool            `json:"done"`
+	Failures   []ImportFailure `json:"failures"`
+	Unrecorded int             `json:"unrecorded,omitempty"`
+}
+
+// Records a failure of a row
+func (s *ImportSummary) fail(row int, apiErr *APIError) {
+	s.Failed++
+	if len(s.Failures) < maxImportFailures {
+		s.Failures = append(s.Failures, ImportFailure{Row: row, Error: apiErr})
+	}
+}
+
+// Where an import got to: the last row it finished with, and the rows up to it that failed
+type ImportCheckpoint struct {
+	Row    int   `json:"row" bson:"row"`
+	Failed []int `json:"failed" bson:"failed"`
+}
+
+/*
+Options of an import.  Rows up to Resume.Row are skipped, having been applied by an
+earlier run, except its failed rows, which are tried again.  When Allows is set, records of
+services it doesn't allow fail.  Progress is called with the summary and checkpoint after
+each batch, to report and save them.
+*/
+type ImportOptions struct {
+	BatchSize int
+	Resume    ImportCheckpoint
+	Allows    func(service string) bool
+	Progress  func(summary ImportSummary, checkpoint ImportCheckpoint) error
+}
+
+/*
+Imports the records, validating each and applying them in batches.  Records that are
+invalid or fail to apply are counted and the import carries on.  Returns an error if the
+records couldn't be read, as an importReadError, or progress couldn't be reported, with the
+summary up to the last finished batch.
+*/
+func importACLs(c *mgo.Collection, actor *Actor, records importReader, options ImportOptions) (ImportSummary, error) {
+	if options.BatchSize < 1 {
+		options.BatchSize = defaultImportBatchSize
+	}
+
+	retry := map[int]bool{}
+	for _, row := range options.Resume.Failed {
+		retry[row] = true
+	}
+
+	// The checkpoint never moves back while skipping, and keeps the failed rows of the
+	// earlier run that haven't been tried again yet
+	summary := ImportSummary{Failures: []ImportFailure{}}
+	failed := []int{}
+	row := 0
+	report := func() error {
+		checkpoint := ImportCheckpoint{Row: row, Failed: append([]int{}, failed...)}
+		if checkpoint.Row < options.Resume.Row {
+			checkpoint.Row = options.Resume.Row
+		}
+		for _, retryRow := range options.Resume.Failed {
+			if retryRow > row {
+				checkpoint.Failed = append(checkpoint.Failed, retryRow)
+			}
+		}
+		summary.Checkpoint = checkpoint.Row
+		if options.Progress == nil {
+			return nil
+		}
+		return options.Progress(summary, checkpoint)
+	}
+
+	batch := 0
+	for {
+		rec, apiErr, err := records.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return summary, importReadError{err}
+		}
+		summary.Rows++
+		row = rec.Row
+
+		if rec.Row <= options.Resume.Row && !retry[rec.Row] {
+			summary.Skipped++
+		} else {
+			if retry[rec.Row] {
+				summary.Retried++
+			}
+			if apiErr == nil {
+				apiErr = rec.validate()
+			}
+			if apiErr == nil && options.Allows != nil && !options.Allows(rec.Service) {
+				apiErr = &APIError{Code: ErrCodeForbidden, Message: "Caller may not change service '" + rec.Service + "'"}
+			}
+
+			if apiErr == nil {
+				if err = rec.apply(c, actor); err == ErrChangeNotRecorded {
+					summary.Unrecorded++
+				} else if err != nil {
+					log.Error("An error occurred importing row %d: %s", rec.Row, err)
+					apiErr = &APIError{Code: ErrCodeStorage, Message: "An error occurred applying the record"}
+				}
+			} else {
+				log.Debug("Invalid import row %d: %s", rec.Row, apiErr)
+			}
+
+			if apiErr != nil {
+				summary.fail(rec.Row, apiErr)
+				failed = append(failed, rec.Row)
+			} else {
+				summary.Applied++
+			}
+		}
+
+		batch++
+		if batch == options.BatchSize {
+			if err = report(); err != nil {
+				return summary, err
+			}
+			batch = 0
+		}
+	}
+
+	summary.Done = true
+	return summary, report()
+}
+
+// Selects the checkpoint of a named import in the counters collection
+func importCheckpointSelector(id string) bson.M {
+	return bson.M{"counter": "imports", "import": id}
+}
+
+// Gets the checkpoint of a named import, which is at row 0 before it has run
+func getImportCheckpoint(c *mgo.Collection, id string) (ImportCheckpoint, error) {
+	checkpoint := ImportCheckpoint{}
+	err := c.Database.C("counters").Find(importCheckpointSelector(id)).One(&checkpoint)
+	if err == mgo.ErrNotFound {
+		return ImportCheckpoint{}, nil
+	}
+	return checkpoint, err
+}
+
+// Records the checkpoint of a named import
+func saveImportCheckpoint(c *mgo.Collection, id string, checkpoint ImportCheckpoint) error {
+	_, err := c.Database.C("counters").Upsert(importCheckpointSelector(id),
+		bson.M{"$set": bson.M{"row": checkpoint.Row, "failed": checkpoint.Failed}})
+	return err
+}
+
+// Gets the format of an import from its format parameter, or the content type when none
+// is given
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		return ImportFormatCSV
+	}
+	return ImportFormatJSONL
+}
+
+/*
+This is a URL handler that imports ACL records from the request body.  The body is read as
+it is applied, so it isn't limited in size.  Imports given an id checkpoint after each
+batch, and resume from the checkpoint when sent again, retrying the rows that failed.
+*/
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+	actor := requestActor(r)
+	params := r.URL.Query()
+
+	options := ImportOptions{}
+	if value := params.Get("batch_size"); value != "" {
+		var err error
+		options.BatchSize, err = strconv.Atoi(value)
+		if err != nil || options.BatchSize < 1 {
+			writeError(w, 400, ErrCodeInvalidParameter, "batch_size must be a positive integer")
+			return
+		}
+	}
+
+	// Callers may only import into the services they may change, and only into the one
+	// given by the service parameter when there is one
+	service := params.Get("service")
+	caller := requestCaller(r)
+	options.Allows = func(recordService string) bool {
+		if service != "" && recordService != service {
+			return false
+		}
+		if caller == nil || !authEnabled {
+			return true
+		}
+		if adminService != "" && recordService == adminService && !caller.allows(OperationAdmin, "") {
+			return false
+		}
+		return caller.allows(OperationWrite, recordService)
+	}
+
+	id := params.Get("id")
+	if id != "" {
+		var err error
+		options.Resume, err = getImportCheckpoint(c, id)
+		if err != nil {
+			log.Error("An error occurred getting import checkpoint '%s': %s", id, err)
+			writeError(w, 500, ErrCodeStorage, "An error occurred getting the import checkpoint")
+			return
+		}
+	}
+
+	records, apiErr, err := newImportReader(r.Body, importFormat(r))
+	if err != nil {
+		log.Error("An error occurred reading import. Message: %s", err)
+		writeError(w, 400, ErrCodeUnreadableBody, "An error occurred reading request body")
+		return
+	} else if apiErr != nil {
+		apiErr.Write(w, 400)
+		return
+	}
+
+	// Progress is streamed as a line per batch to callers accepting newline delimited JSON
+	ndjson := wantsNDJSON(r)
+	flusher, _ := w.(http.Flusher)
+	if ndjson {
+		w.Header().Set("Content-Type", ndjsonContentType)
+	}
+	options.Progress = func(summary ImportSummary, checkpoint ImportCheckpoint) error {
+		if id != "" {
+			if err := saveImportCheckpoint(c, id, checkpoint); err != nil {
+				return err
+			}
+		}
+		log.Info("Import '%s' at row %d: %d applied, %d skipped, %d failed", id, summary.Checkpoint,
+			summary.Applied, summary.Skipped, summary.Failed)
+		if !ndjson || summary.Done {
+			return nil
+		}
+		data, _ := json.Marshal(summary)
+		w.Write(append(data, '\n'))
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	summary, err := importACLs(c, actor, records, options)
+	if err != nil {
+		status := 500
+		apiErr := &APIError{Code: ErrCodeStorage, Message: fmt.Sprintf(
+			"The import stopped after row %d. Send it again to resume", summary.Checkpoint)}
+		if _, ok := err.(importReadError); ok {
+			log.Debug("An error occurred reading import after row %d. Message: %s", summary.Rows, err)
+			status = 400
+			apiErr = &APIError{Code: ErrCodeUnreadableBody, Message: fmt.Sprintf(
+				"An error occurred reading the request body after row %d", summary.Rows)}
+		} else {
+			log.Error("An error occurred importing ACLs at row %d. Message: %s", summary.Checkpoint, err)
+		}
+		if ndjson {
+			data, _ := json.Marshal(map[string]interface{}{"error": apiErr})
+			w.Write(append(data, '\n'))
+			return
+		}
+		apiErr.Write(w, status)
+		return
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		log.Error(err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred importing ACLs")
+		return
+	}
+	if ndjson {
+		data = append(data, '\n')
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Write(data)
+}
+
+// Reads a checkpoint file, which holds the import's checkpoint as JSON, or just the row
+// number of the last row finished with, as files written before failed rows were kept did
+func readImportCheckpointFile(path string) (ImportCheckpoint, error) {
+	checkpoint := ImportCheckpoint{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	} else if err != nil {
+		return checkpoint, err
+	}
+	if json.Unmarshal(data, &checkpoint) == nil {
+		return checkpoint, nil
+	}
+	checkpoint.Row, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return checkpoint, errors.New("checkpoint file " + path + " doesn't hold a checkpoint")
+	}
+	return checkpoint, nil
+}
+
+/*
+Imports ACL records from a file, or stdin when it's "-", from the command line:
+
+	authorizer import [-format jsonl|csv] [-batch 500] [-checkpoint file] records.jsonl
+
+Progress is printed after each batch, and the summary once done.  With a checkpoint file,
+the last finished row and the rows that failed are written to it after each batch, and an
+import run again with it resumes from there, retrying the failed rows.  Exits with status 1
+when rows failed.
+*/
+func importCommand(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "Format of the records, jsonl or csv (default from the file extension)")
+	batchSize := flags.Int("batch", defaultImportBatchSize, "Number of records applied between checkpoints")
+	checkpoint := flags.String("checkpoint", "", "File to checkpoint the import to, and resume it from")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: authorizer import [-format jsonl|csv] [-batch 500] [-checkpoint file] <file>")
+		os.Exit(2)
+	}
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = ImportFormatJSONL
+		if strings.ToLower(filepath.Ext(path)) == ".csv" {
+			*format = ImportFormatCSV
+		}
+	}
+
+	input := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error opening import:", err)
+			os.Exit(2)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	options := ImportOptions{BatchSize: *batchSize}
+	if *checkpoint != "" {
+		var err error
+		options.Resume, err = readImportCheckpointFile(*checkpoint)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading checkpoint:", err)
+			os.Exit(2)
+		}
+		if options.Resume.Row > 0 {
+			fmt.Fprintf(os.Stderr, "Resuming import after row %d, retrying %d failed rows\n", options.Resume.Row,
+				len(options.Resume.Failed))
+		}
+	}
+	options.Progress = func(summary ImportSummary, progress ImportCheckpoint) error {
+		if *checkpoint != "" {
+			data, _ := json.Marshal(progress)
+			err := ioutil.WriteFile(*checkpoint, append(data, '\n'), 0644)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Row %d: %d applied, %d skipped, %d failed\n", summary.Checkpoint,
+			summary.Applied, summary.Skipped, summary.Failed)
+		return nil
+	}
+
+	records, apiErr, err := newImportReader(input, *format)
+	if apiErr != nil {
+		err = apiErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading import:", err)
+		os.Exit(2)
+	}
+
+	session, _, c, err := getMongo()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error connecting to database:", err)
+		os.Exit(2)
+	}
+	defer session.Close()
+
+	summary, err := importACLs(c, &Actor{Caller: "import"}, records, options)
+	data, _ := json.MarshalIndent(summary, "", "    ")
+	fmt.Println(string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import stopped after row %d: %s\n", summary.Checkpoint, err)
+		session.Close()
+		os.Exit(2)
+	}
+	if summary.Failed > 0 {
+		session.Close()
+		os.Exit(1)
+	}
+}
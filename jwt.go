@@ -0,0 +1,392 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"labix.org/v2/mgo"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The keys JWTs are verified with, or nil when JWT authentication isn't configured
+var jwks *jwkSet
+
+// The issuer and audience JWTs must have, which are required with a key set
+var jwtIssuer = ""
+var jwtAudience = ""
+
+// The claims holding a JWT caller's services and scopes
+var jwtServicesClaim = "services"
+var jwtScopesClaim = "scope"
+
+// How far past its expiry a JWT is still accepted, for clock skew
+var jwtLeeway = time.Minute
+
+// Reads the JWT settings from the "jwt" object of the "auth" section of the config
+func configureJWT(auth map[string]interface{}) {
+	config, ok := auth["jwt"].(map[string]interface{})
+	if !ok {
+		log.Info("No JWT settings in config, JWT authentication is disabled")
+		return
+	}
+
+	file, _ := config["jwks_file"].(string)
+	url, _ := config["jwks_url"].(string)
+	if file == "" && url == "" {
+		log.Info("No JWKS file or URL in config, JWT authentication is disabled")
+		return
+	}
+	jwtIssuer, _ = config["issuer"].(string)
+	jwtAudience, _ = config["audience"].(string)
+	if jwtIssuer == "" || jwtAudience == "" {
+		// Otherwise any token the provider signed, for any service, would be accepted
+		panic("JWT authentication needs an issuer and audience in the jwt config")
+	}
+	if claim, ok := config["services_claim"].(string); ok && claim != "" {
+		jwtServicesClaim = claim
+	}
+	if claim, ok := config["scopes_claim"].(string); ok && claim != "" {
+		jwtScopesClaim = claim
+	}
+	if seconds, ok := config["leeway_seconds"].(float64); ok && seconds >= 0 {
+		jwtLeeway = time.Duration(seconds) * time.Second
+	}
+
+	jwks = &jwkSet{file: file, url: url, refresh: time.Hour}
+	if seconds, ok := config["refresh_seconds"].(float64); ok && seconds > 0 {
+		jwks.refresh = time.Duration(seconds) * time.Second
+	}
+
+	log.Info("Using JWT settings JWKS: '%s%s', issuer: '%s', audience: '%s', services claim: '%s', scopes claim: '%s'",
+		file, url, jwtIssuer, jwtAudience, jwtServicesClaim, jwtScopesClaim)
+}
+
+// The least time between reloads of the key set, so tokens with unknown key IDs can't make
+// every request fetch it
+const jwksMinReload = time.Minute
+
+/*
+The public keys of a JSON Web Key Set, read from a file or URL.  The set is reloaded when it
+is older than the refresh interval, or when a token names a key it doesn't have, so the
+identity provider can rotate its keys.
+*/
+type jwkSet struct {
+	file    string
+	url     string
+	refresh time.Duration
+
+	mu     sync.Mutex
+	keys   map[string]crypto.PublicKey
+	loaded time.Time
+}
+
+// A key of a JSON Web Key Set
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Decodes a base64url field of a key as a big integer
+func jwkInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// Converts a key to the public key it describes
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := jwkInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := jwkInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+		x, err := jwkInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := jwkInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+}
+
+// Reads the key set from its file or URL
+func (s *jwkSet) read() ([]byte, error) {
+	if s.file != "" {
+		return ioutil.ReadFile(s.file)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	res, err := client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("JWKS request returned status %s", res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// Loads the key set, skipping keys that aren't for signatures or can't be used
+func (s *jwkSet) load() error {
+	data, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	err = json.Unmarshal(data, &set)
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		public, err := key.publicKey()
+		if err != nil {
+			log.Error("Skipping JWKS key '%s': %s", key.Kid, err)
+			continue
+		}
+		keys[key.Kid] = public
+	}
+
+	s.keys = keys
+	s.loaded = time.Now()
+	log.Debug("Loaded %d JWKS keys", len(keys))
+	return nil
+}
+
+// Gets the key with the ID, reloading the set if it is stale or doesn't have the key.  A
+// token without a key ID may use the only key of a set with one.
+func (s *jwkSet) key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	find := func() crypto.PublicKey {
+		if kid == "" && len(s.keys) == 1 {
+			for _, key := range s.keys {
+				return key
+			}
+		}
+		return s.keys[kid]
+	}
+
+	stale := time.Since(s.loaded) > s.refresh
+	key := find()
+	if stale || (key == nil && time.Since(s.loaded) > jwksMinReload) {
+		err := s.load()
+		if err != nil && s.keys == nil {
+			return nil, err
+		} else if err != nil {
+			// Keep using the keys already loaded until the set can be read again
+			log.Error("An error occurred reloading JWKS, using the keys already loaded: %s", err)
+		}
+		key = find()
+	}
+	if key == nil {
+		return nil, fmt.Errorf("no JWKS key '%s'", kid)
+	}
+	return key, nil
+}
+
+// The hashes of the supported signature algorithms
+var jwtAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// Checks the signature of a token's header and payload with the key
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	hash, ok := jwtAlgorithms[alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm '%s'", alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch public := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm '%s' doesn't match RSA key", alg)
+		}
+		return rsa.VerifyPKCS1v15(public, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return fmt.Errorf("algorithm '%s' doesn't match EC key", alg)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(public, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key")
+}
+
+// Gets a claim that may be a list of strings or a space separated string
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		result := []string{}
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// Gets a numeric date claim
+func claimTime(claims map[string]interface{}, name string) (time.Time, bool) {
+	value, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(value), 0), true
+}
+
+// Verifies a token and returns its claims
+func parseJWT(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed header")
+	}
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err = json.Unmarshal(headerData, &header); err != nil {
+		return nil, fmt.Errorf("malformed header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	key, err := jwks.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed payload")
+	}
+	claims := map[string]interface{}{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed payload")
+	}
+
+	expires, ok := claimTime(claims, "exp")
+	if !ok {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	if now.After(expires.Add(jwtLeeway)) {
+		return nil, fmt.Errorf("token has expired")
+	}
+	if notBefore, ok := claimTime(claims, "nbf"); ok && now.Add(jwtLeeway).Before(notBefore) {
+		return nil, fmt.Errorf("token isn't valid yet")
+	}
+	if issuer, _ := claims["iss"].(string); issuer != jwtIssuer {
+		return nil, fmt.Errorf("token has the wrong issuer")
+	}
+	audienceOK := false
+	for _, audience := range claimStrings(claims, "aud") {
+		if audience == jwtAudience {
+			audienceOK = true
+		}
+	}
+	if !audienceOK {
+		return nil, fmt.Errorf("token has the wrong audience")
+	}
+	if subject, _ := claims["sub"].(string); subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+	return claims, nil
+}
+
+// Authenticates with a JWT bearer token.  The caller is the token's subject, prefixed with
+// "jwt:", with the services and scopes of the configured claims.
+func authenticateJWT(r *http.Request, c *mgo.Collection) (*Caller, *APIError) {
+	token := requestAPIKey(r)
+	if jwks == nil || token == "" || strings.HasPrefix(token, apiKeyPrefix) || strings.Count(token, ".") != 2 {
+		return nil, nil
+	}
+
+	claims, err := parseJWT(token, time.Now())
+	if err != nil {
+		log.Debug("Invalid JWT: %s", err)
+		return nil, &APIError{Code: ErrCodeUnauthenticated, Message: "Invalid token: " + err.Error()}
+	}
+
+	subject, _ := claims["sub"].(string)
+	scopes := []string{}
+	for _, scope := range claimStrings(claims, jwtScopesClaim) {
+		if _, ok := operationRank[scope]; ok {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return &Caller{ID: callerJWTPrefix + subject, Method: "jwt", Services: claimStrings(claims, jwtServicesClaim), Scopes: scopes}, nil
+}
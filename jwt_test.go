@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Encodes a JWT part
+func jwtPart(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Signs a token with an RSA or EC key
+func signJWT(t *testing.T, alg string, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	signed := jwtPart(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + jwtPart(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	var err error
+	switch private := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, private, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	if err != nil {
+		t.Fatal("Error signing token: ", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	set, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+	}})
+
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "jwks.json")
+	if err = ioutil.WriteFile(file, set, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, issuer, audience := jwks, jwtIssuer, jwtAudience
+	defer func() {
+		jwks, jwtIssuer, jwtAudience = keys, issuer, audience
+	}()
+	configureJWT(map[string]interface{}{"jwt": map[string]interface{}{
+		"jwks_file": file,
+		"issuer":    "https://id.example.com/",
+		"audience":  "authorizer",
+	}})
+
+	now := time.Now().Unix()
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		result := map[string]interface{}{
+			"iss":      "https://id.example.com/",
+			"aud":      []string{"other", "authorizer"},
+			"sub":      "billing",
+			"exp":      now + 300,
+			"services": []string{"service1"},
+			"scope":    "openid read write",
+		}
+		for name, value := range changes {
+			if value == nil {
+				delete(result, name)
+			} else {
+				result[name] = value
+			}
+		}
+		return result
+	}
+
+	authenticateToken := func(token string) (*Caller, *APIError) {
+		r, _ := http.NewRequest("GET", "/v1/service/service1/object/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return authenticateJWT(r, nil)
+	}
+
+	caller, apiErr := authenticateToken(signJWT(t, "RS256", "rsa1", rsaKey, claims(nil)))
+	if apiErr != nil {
+		t.Fatal("Error authenticating valid token: ", apiErr)
+	}
+	if caller.ID != "jwt:billing" || caller.Method != "jwt" || !caller.allows(OperationWrite, "service1") ||
+		caller.allows(OperationRead, "service2") || caller.allows(OperationAdmin, "service1") {
+		t.Fatal("Incorrect caller from token: ", caller)
+	}
+
+	caller, apiErr = authenticateToken(signJWT(t, "ES256", "ec1", ecKey, claims(map[string]interface{}{"services": "*"})))
+	if apiErr != nil {
+		t.Fatal("Error authenticating valid EC token: ", apiErr)
+	}
+	if !caller.allows(OperationRead, "service2") {
+		t.Fatal("Incorrect caller from EC token: ", caller)
+	}
+
+	invalid := map[string]string{
+		"expired":        signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]interface{}{"exp": now - 3600})),
+		"no expiry":      signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]interface{}{"exp": nil})),
+		"not yet valid":  signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]interface{}{"nbf": now + 3600})),
+		"wrong issuer":   signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]interface{}{"iss": "https://evil.example.com/"})),
+		"wrong audience": signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]interface{}{"aud": "other"})),
+		"unknown key":    signJWT(t, "RS256", "rsa2", rsaKey, claims(nil)),
+		"wrong key":      signJWT(t, "RS256", "rsa1", otherKey, claims(nil)),
+		"wrong alg":      signJWT(t, "ES256", "rsa1", ecKey, claims(nil)),
+		"unsigned":       jwtPart(map[string]string{"alg": "none"}) + "." + jwtPart(claims(nil)) + ".",
+		"no subject":     signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]interface{}{"sub": nil})),
+		"empty subject":  signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]interface{}{"sub": ""})),
+		"no audience":    signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]interface{}{"aud": nil})),
+	}
+	for name, token := range invalid {
+		_, apiErr = authenticateToken(token)
+		if apiErr == nil || apiErr.Code != ErrCodeUnauthenticated {
+			t.Fatal("Expected token to be rejected: ", name, apiErr)
+		}
+	}
+
+	caller, apiErr = authenticateToken("ak_not.a-jwt")
+	if caller != nil || apiErr != nil {
+		t.Fatal("API keys should be left to the API key authenticator")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected JWT config without an audience to be refused")
+			}
+		}()
+		configureJWT(map[string]interface{}{"jwt": map[string]interface{}{
+			"jwks_file": file,
+			"issuer":    "https://id.example.com/",
+		}})
+	}()
+}
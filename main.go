@@ -7,6 +7,7 @@ import (
 	"labix.org/v2/mgo"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 )
 
 // This is the droplet application object
@@ -15,7 +16,50 @@ var Application *droplet.Application = &droplet.Application{}
 func main() {
 	Initialize()
 
-	Application.Start()
+	session, _, c, err := getMongo()
+	if err == nil {
+		err = ensureIndexes(c)
+		session.Close()
+	}
+	if err != nil {
+		log.Error("Error creating database indexes: %s", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		verifyAuditCommand()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		exportCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		restoreCommand(os.Args[2:])
+		return
+	}
+
+	go runWebhookWorker()
+	if auditCheckpointKey != "" {
+		go runAuditCheckpoints()
+	}
+
+	err = startGRPC()
+	if err != nil {
+		panic(err)
+	}
+
+	if tlsConfig != nil {
+		err = startTLS()
+		if err != nil {
+			log.Error("HTTPS server terminated: %s", err)
+		}
+	} else {
+		Application.Start()
+	}
 
 	log.Info("Server Terminated")
 }
@@ -36,11 +80,13 @@ func Initialize() error {
 	mongo, ok := Application.Config["mongo"].(map[string]interface{})
 	if !ok {
 		log.Info("No mongo connection information available, defaulting to " +
-			"dial string: 'localhost', db name: 'authorizer', collection name: 'acls'")
+			"dial string: 'localhost', db name: 'authorizer', collection name: 'acls', " +
+			"events collection name: 'acl_events'")
 		Application.Config["mongo"] = map[string]interface{}{
-			"dial":       "localhost",
-			"db":         "authorizer",
-			"collection": "acls",
+			"dial":              "localhost",
+			"db":                "authorizer",
+			"collection":        "acls",
+			"events_collection": "acl_events",
 		}
 	} else {
 		if dial, ok := mongo["dial"]; !ok {
@@ -64,9 +110,50 @@ func Initialize() error {
 			log.Info("Using Mongo collection name '%s' from config", collection)
 		}
 
+		if events, ok := mongo["events_collection"]; !ok {
+			log.Info("Mongo events collection name not specified. Using 'acl_events'")
+			mongo["events_collection"] = "acl_events"
+		} else {
+			log.Info("Using Mongo events collection name '%s' from config", events)
+		}
+
+		if history, ok := mongo["history_collection"]; !ok {
+			log.Info("Mongo history collection name not specified. Using 'acl_history'")
+			mongo["history_collection"] = "acl_history"
+		} else {
+			log.Info("Using Mongo history collection name '%s' from config", history)
+		}
+
+		if keys, ok := mongo["idempotency_collection"]; !ok {
+			log.Info("Mongo idempotency collection name not specified. Using 'idempotency_keys'")
+			mongo["idempotency_collection"] = "idempotency_keys"
+		} else {
+			log.Info("Using Mongo idempotency collection name '%s' from config", keys)
+		}
+
+		if audit, ok := mongo["audit_collection"]; !ok {
+			log.Info("Mongo audit collection name not specified. Using 'audit_log'")
+			mongo["audit_collection"] = "audit_log"
+		} else {
+			log.Info("Using Mongo audit collection name '%s' from config", audit)
+		}
+
 		Application.Config["mongo"] = mongo
 	}
 
+	err = configureTLS()
+	if err != nil {
+		log.Error("An error occurred configuring TLS: %s", err)
+		panic(err)
+	}
+
+	configureRequests()
+	configureWebhooks()
+	configureGRPC()
+	configureAuth()
+	configureAudit()
+	configureIdempotency()
+
 	Application.Handler = Handler
 
 	ConfigureRouter()
@@ -86,16 +173,47 @@ func ConfigureRouter() error {
 		}
 	}
 
+	Application.Router.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+
 	v1 := Application.Router.PathPrefix("/v1").Subrouter()
 	v1_srv := v1.PathPrefix("/service").Subrouter()
 	v1_serv := v1_srv.PathPrefix("/{service}").Subrouter()
 	v1_obj := v1_serv.PathPrefix("/object").Subrouter()
 	v1_object := v1_obj.PathPrefix("/{object}").Subrouter()
+	v1_usr := v1.PathPrefix("/user").Subrouter()
+	v1_user := v1_usr.PathPrefix("/{user}").Subrouter()
+	v1_audit := v1.PathPrefix("/audit").Subrouter()
+	v1_admin := v1.PathPrefix("/admin").Subrouter()
+	v1_keys := v1_admin.PathPrefix("/key").Subrouter()
+	v1_key := v1_keys.PathPrefix("/{key}").Subrouter()
+	v1_hook := v1_serv.PathPrefix("/webhook").Subrouter()
+	v1_webhook := v1_hook.PathPrefix("/{webhook}").Subrouter()
+
+	v1.HandleFunc("/openapi.json", openAPIHandler).Methods("GET").Name("OpenAPI")
 
 	v1_srv.HandleFunc("/", getServicesHandler).Methods("GET").Name("ListServices")
 
+	v1.HandleFunc("/import/", importHandler).Methods("POST").Name("ImportACL")
+	v1.HandleFunc("/snapshot/", exportSnapshotHandler).Methods("GET").Name("ExportSnapshot")
+	v1.HandleFunc("/snapshot/", restoreSnapshotHandler).Methods("POST").Name("RestoreSnapshot")
+
+	v1_audit.HandleFunc("/", auditLogHandler).Methods("GET").Name("AuditLog")
+	v1_audit.HandleFunc("/verify/", verifyAuditHandler).Methods("GET").Name("VerifyAudit")
+
 	v1_obj.HandleFunc("/", getObjectsHandler).Methods("GET").Name("ListObjects")
 
+	v1_keys.HandleFunc("/", createAPIKeysHandler).Methods("POST").Name("CreateAPIKey")
+	v1_keys.HandleFunc("/", listAPIKeysHandler).Methods("GET").Name("ListAPIKeys")
+	v1_key.HandleFunc("/rotate/", rotateAPIKeyHandler).Methods("POST").Name("RotateAPIKey")
+	v1_key.HandleFunc("/", revokeAPIKeyHandler).Methods("DELETE").Name("RevokeAPIKey")
+
+	v1_hook.HandleFunc("/", createWebhooksHandler).Methods("POST").Name("CreateWebhook")
+	v1_hook.HandleFunc("/", listWebhooksHandler).Methods("GET").Name("ListWebhooks")
+	v1_webhook.HandleFunc("/", deleteWebhookHandler).Methods("DELETE").Name("DeleteWebhook")
+	v1_webhook.HandleFunc("/deliveries/", listDeliveriesHandler).Methods("GET").Name("ListWebhookDeliveries")
+	v1_webhook.HandleFunc("/deliveries/{delivery}/retry/", retryDeliveryHandler).Methods("POST").
+		Name("RetryWebhookDelivery")
+
 	v1_object.HandleFunc("/grant/", grantPrivilegesHandler).Methods("POST").Name("GrantACL")
 	v1_object.HandleFunc("/deny/", denyPrivilegesHandler).Methods("POST").Name("DenyACL")
 	v1_object.HandleFunc("/revoke/", revokePrivilegesHandler).Methods("POST").Name("RevokeACL")
@@ -104,6 +222,14 @@ func ConfigureRouter() error {
 	v1_object.HandleFunc("/get/", getPrivilegesHandler).Methods("GET").Name("GetACL")
 	v1_object.HandleFunc("/list/", listPrivilegesHandler).Methods("GET").Name("ListACL")
 	v1_object.HandleFunc("/match/", matchPrivilegesHandler).Methods("GET").Name("MatchACL")
+	v1_object.HandleFunc("/copy/", copyPrivilegesHandler).Methods("POST").Name("CopyACL")
+	v1_object.HandleFunc("/move/", movePrivilegesHandler).Methods("POST").Name("MoveACL")
+	v1_object.HandleFunc("/clone/", clonePrivilegesHandler).Methods("POST").Name("CloneACL")
+	v1_object.HandleFunc("/watch/", watchPrivilegesHandler).Methods("GET").Name("WatchACL")
+	v1_object.HandleFunc("/history/", historyPrivilegesHandler).Methods("GET").Name("HistoryACL")
+	v1_object.HandleFunc("/restore/", restorePrivilegesHandler).Methods("POST").Name("RestoreACL")
+
+	v1_user.HandleFunc("/acl/", userPrivilegesHandler).Methods("GET").Name("UserACL")
 
 	if Application.Debug {
 		Application.Router.HandleFunc("/test/", func(w http.ResponseWriter, r *http.Request) {
@@ -117,9 +243,11 @@ func ConfigureRouter() error {
 // Top-level http handler.  This code will get ran on every request
 func Handler(w http.ResponseWriter, r *http.Request) {
 
+	setRequestID(w, r)
+
 	session, db, collection, err := getMongo()
 	if err != nil {
-		http.Error(w, "There was an error, please try again", 500)
+		writeError(w, 503, ErrCodeUnavailable, "There was an error, please try again")
 		return
 	} else {
 		defer session.Close()
@@ -129,7 +257,35 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	context.Set(r, "mongoDb", db)
 	context.Set(r, "mongoColl", collection)
 
-	Application.Router.ServeHTTP(w, r)
+	// Removes the file a signed streaming body was spooled to
+	defer func() {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+	}()
+
+	if !authorize(w, r, collection) {
+		// Already responded in authorize call
+		return
+	}
+
+	serveIdempotent(w, r, collection, Application.Router.ServeHTTP)
+}
+
+// Creates the indexes of the collections in the database of the ACL collection.  Writes
+// rely on the unique ones to detect concurrent changes.
+func ensureIndexes(c *mgo.Collection) error {
+	indexed := []interface {
+		EnsureIndex(c *mgo.Collection) error
+	}{ACL{}, ACLEvent{}, ACLRevision{}, AuditEntry{}, mongoIdempotencyStore{},
+		Webhook{}}
+	for _, model := range indexed {
+		err := model.EnsureIndex(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func getMongo() (*mgo.Session, *mgo.Database, *mgo.Collection, error) {
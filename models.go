@@ -2,13 +2,18 @@ package main
 
 import (
 	log "code.google.com/p/log4go"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"labix.org/v2/mgo"
 	"labix.org/v2/mgo/bson"
+	"time"
 )
 
 /*
 This is the model for an ACL.  It stores the key of the object (the object's identifier),
-the user's email, and the list of privileges.
+the user's email, and the list of privileges.  Revision is the number of the ACL's latest
+revision in its history, and changes on every write.
 */
 type ACL struct {
 	Service    string
@@ -16,18 +21,211 @@ type ACL struct {
 	Key        string
 	User       string
 	Privileges map[string]interface{}
+	Revision   int64
+	// Who changes ACLs through this value, for the audit log.  It isn't stored.
+	Actor *Actor `bson:"-" json:"-"`
+	// When set, reads through this value see the ACLs as they were at this time
+	AsOf time.Time `bson:"-" json:"-"`
+	// When set, writes through this value only change an ACL still at this revision
+	IfRevision int64 `bson:"-" json:"-"`
 }
 
-// Creates an Index on the ACL collection
+// Returned when a page cursor can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Returned when a conditional write finds the ACL isn't at the expected revision
+var ErrRevisionConflict = errors.New("revision conflict")
+
+// Returned when an ACL change was made, but recording it in the audit, event or history logs
+// failed.  The change isn't undone, so it mustn't be made again.
+var ErrChangeNotRecorded = errors.New("change made but not recorded")
+
+/*
+A position in a sorted list of ACLs.  It is handed to clients as an opaque string so
+they can resume a list or match from the last item they received.
+*/
+type Cursor struct {
+	Key  string `json:"k"`
+	User string `json:"u,omitempty"`
+}
+
+// Encodes the cursor to the opaque string given to clients
+func (cur Cursor) String() string {
+	data, _ := json.Marshal(cur)
+	return base64.URLEncoding.EncodeToString(data)
+}
+
+// Decodes a cursor string given to a client
+func ParseCursor(s string) (Cursor, error) {
+	cur := Cursor{}
+	data, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(data, &cur)
+	if err != nil || cur.Key == "" {
+		return cur, ErrInvalidCursor
+	}
+	return cur, nil
+}
+
+// Describes which page of a list to retrieve.  A Limit of 0 retrieves everything, and an
+// empty Cursor starts from the beginning.
+type Page struct {
+	Limit  int
+	Cursor string
+}
+
+// Limits the query to the page size, fetching one extra item to tell if another page follows
+func (p Page) apply(q *mgo.Query) *mgo.Query {
+	if p.Limit > 0 {
+		return q.Limit(p.Limit + 1)
+	}
+	return q
+}
+
+// Adds the privilege filters to a selector.  With an empty value the privileges only need to exist.
+func privilegeSelector(selector bson.M, privileges []string, value string) {
+	for _, privilege := range privileges {
+		if value == "" {
+			selector["privileges."+privilege] = bson.M{"$exists": true}
+		} else {
+			selector["privileges."+privilege] = value
+		}
+	}
+}
+
+// Adds the conditions to a selector that skip past the cursor position.  withUser is true
+// when the results are sorted by user within each key.
+func cursorSelector(selector bson.M, cursor string, withUser bool) error {
+	if cursor == "" {
+		return nil
+	}
+
+	cur, err := ParseCursor(cursor)
+	if err != nil {
+		return err
+	}
+
+	if withUser {
+		selector["$or"] = []bson.M{
+			bson.M{"key": bson.M{"$gt": cur.Key}},
+			bson.M{"key": cur.Key, "user": bson.M{"$gt": cur.User}},
+		}
+	} else {
+		selector["key"] = bson.M{"$gt": cur.Key}
+	}
+	return nil
+}
+
+// Creates the Indexes on the ACL collection, including one for listing a user's ACLs across
+// services in order
 func (a ACL) EnsureIndex(c *mgo.Collection) error {
 	index := mgo.Index{
-		Key:        []string{"Service", "Object", "Key", "User"},
+		Key:        []string{"service", "object", "key", "user"},
 		Unique:     true,
 		DropDups:   false,
 		Background: false,
 		Sparse:     false,
 	}
-	return c.EnsureIndex(index)
+	if err := c.EnsureIndex(index); err != nil {
+		return err
+	}
+	return c.EnsureIndex(mgo.Index{Key: []string{"user", "service", "object", "key"}})
+}
+
+/*
+Records a change to the ACL for the object's key and the user, which left it at the
+revision, in the audit, event and history logs.  before and after are its privileges before
+and after the change, and are nil when it didn't exist.  The change has already been made,
+so it's audited first and each log is written even if another fails.  Every failure is
+logged, and ErrChangeNotRecorded returned if there was one.
+*/
+func (a ACL) recordChange(c *mgo.Collection, service string, object string, key string, user string,
+	action string, revision int64, eventPrivileges interface{}, before map[string]interface{},
+	after map[string]interface{}) error {
+	errs := []error{
+		recordAudit(c, a.Actor, action, service, object, key, user, before, after),
+		recordEvent(c, service, object, key, user, action, eventPrivileges),
+		recordRevision(c, a.Actor, revision, service, object, key, user, after, after == nil),
+	}
+	recorded := true
+	for _, err := range errs {
+		if err != nil {
+			log.Error("An error occurred recording %s of ACL %s/%s/%s/%s: %s", action, service, object, key, user, err)
+			recorded = false
+		}
+	}
+	if !recorded {
+		return ErrChangeNotRecorded
+	}
+	return nil
+}
+
+/*
+Applies an update to the ACL for the object's key and the user, creating it if needed, and
+records the change in the event, history and audit logs.  after computes the privileges the
+update leaves from those the ACL had before, which are empty if it didn't exist.  When the
+ACL value has an IfRevision, only an existing ACL at that revision is changed, and
+ErrRevisionConflict is returned otherwise.
+
+The revision is incremented in the same write as the update, so the last write to land
+always holds the highest revision.  An ACL that doesn't exist is inserted at the revision
+after the one it was last deleted at.
+*/
+func (a ACL) change(c *mgo.Collection, service string, object string, key string, user string, update bson.M,
+	action string, eventPrivileges interface{},
+	after func(before map[string]interface{}) map[string]interface{}) (*mgo.ChangeInfo, error) {
+	selector := bson.M{"service": service, "object": object, "key": key, "user": user}
+	if a.IfRevision > 0 {
+		selector["revision"] = a.IfRevision
+	}
+
+	_, sets := update["$set"]
+	_, unsets := update["$unset"]
+	if !sets && !unsets {
+		update = bson.M{"$set": update}
+	}
+	update["$inc"] = bson.M{"revision": 1}
+
+	for {
+		existing := ACL{}
+		info, err := c.Find(selector).Apply(mgo.Change{Update: update}, &existing)
+		if err == nil {
+			before := existing.Privileges
+			if before == nil {
+				before = map[string]interface{}{}
+			}
+			return info, a.recordChange(c, service, object, key, user, action, existing.Revision+1, eventPrivileges,
+				before, after(copyMap(before)))
+		} else if err != mgo.ErrNotFound {
+			return info, err
+		} else if a.IfRevision > 0 {
+			log.Debug("ACL %s/%s/%s/%s isn't at revision %d", service, object, key, user, a.IfRevision)
+			return info, ErrRevisionConflict
+		}
+
+		deleted, err := deletedRevision(c, service, object, key, user)
+		if err != nil {
+			return nil, err
+		}
+
+		privileges := after(map[string]interface{}{})
+		created := bson.M{"service": service, "object": object, "key": key, "user": user,
+			"privileges": privileges, "revision": deleted + 1}
+		err = c.Insert(created)
+		if mgo.IsDup(err) {
+			log.Debug("ACL %s/%s/%s/%s was created concurrently, retrying", service, object, key, user)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		info = &mgo.ChangeInfo{Updated: 1}
+		return info, a.recordChange(c, service, object, key, user, action, deleted+1, eventPrivileges, nil,
+			privileges)
+	}
 }
 
 // Grants the given privileges on the existing ACL
@@ -42,7 +240,13 @@ func (a ACL) Grant(c *mgo.Collection, service string, object string, key string,
 	}
 
 	log.Finest("Granting Privilege: %s", update)
-	return c.Upsert(selector, bson.M{"$set": update})
+	return a.change(c, service, object, key, user, bson.M{"$set": update}, EventGrant, privileges,
+		func(result map[string]interface{}) map[string]interface{} {
+			for _, privilege := range privileges {
+				result[privilege] = "allow"
+			}
+			return result
+		})
 }
 
 // Denies the privileges from the existing ACL
@@ -55,7 +259,13 @@ func (a ACL) Deny(c *mgo.Collection, service string, object string, key string,
 	}
 
 	log.Finest("Denying Privilege: %s", update)
-	return c.Upsert(selector, bson.M{"$set": update})
+	return a.change(c, service, object, key, user, bson.M{"$set": update}, EventDeny, privileges,
+		func(result map[string]interface{}) map[string]interface{} {
+			for _, privilege := range privileges {
+				result[privilege] = "deny"
+			}
+			return result
+		})
 }
 
 // Revokes the privileges from the existing ACL
@@ -66,7 +276,13 @@ func (a ACL) Revoke(c *mgo.Collection, service string, object string, key string
 		toRevoke["privileges."+privilege] = ""
 	}
 	log.Finest("Revoking Privilege: %s, %s", selector, toRevoke)
-	return c.Upsert(selector, bson.M{"$unset": toRevoke})
+	return a.change(c, service, object, key, user, bson.M{"$unset": toRevoke}, EventRevoke, privileges,
+		func(result map[string]interface{}) map[string]interface{} {
+			for _, privilege := range privileges {
+				delete(result, privilege)
+			}
+			return result
+		})
 }
 
 // Sets the privileges to a whole new ACL
@@ -76,11 +292,67 @@ func (a ACL) Set(c *mgo.Collection, service string, object string, key string, u
 	update["privileges"] = privileges
 
 	log.Finest("Setting Privilege: %s", update)
-	return c.Upsert(selector, update)
+	return a.change(c, service, object, key, user, update, EventSet, privileges,
+		func(map[string]interface{}) map[string]interface{} {
+			return copyMap(privileges)
+		})
+}
+
+/*
+Deletes the ACL for the object's key and the user.  The revision it's deleted at is
+recorded before it's removed, and it's only removed if it's still at the revision before,
+so an ACL created again afterwards carries on counting from it.
+*/
+func (a ACL) Delete(c *mgo.Collection, service string, object string, key string, user string) error {
+	selector := bson.M{"service": service, "object": object, "key": key, "user": user}
+	log.Finest("Deleting ACL: %s", selector)
+
+	for {
+		existing := ACL{}
+		err := c.Find(selector).One(&existing)
+		if err != nil {
+			return err
+		}
+
+		revision := existing.Revision + 1
+		err = markDeleted(c, service, object, key, user, revision)
+		if err != nil {
+			return err
+		}
+
+		current := copyMap(selector)
+		current["revision"] = existing.Revision
+		if existing.Revision == 0 {
+			// Written before ACLs had revisions
+			current["revision"] = bson.M{"$exists": false}
+		}
+		err = c.Remove(current)
+		if err == mgo.ErrNotFound {
+			log.Debug("ACL %s/%s/%s/%s changed while deleting it, retrying", service, object, key, user)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		return a.recordChange(c, service, object, key, user, EventDelete, revision, nil, existing.Privileges, nil)
+	}
 }
 
 // Retrieves the ACL from the collection using the object's key and the user, if the user is granted privileges
 func (a ACL) Has(c *mgo.Collection, service string, object string, key string, user string, privileges []string) error {
+	if !a.AsOf.IsZero() {
+		acl, err := a.getAsOf(c, service, object, key, user)
+		if err != nil {
+			return err
+		}
+		for _, privilege := range privileges {
+			if acl.Privileges[privilege] != "allow" {
+				return mgo.ErrNotFound
+			}
+		}
+		return nil
+	}
+
 	selector := bson.M{"service": service, "object": object, "key": key, "user": user}
 	for _, privilege := range privileges {
 		selector["privileges."+privilege] = "allow"
@@ -91,18 +363,120 @@ func (a ACL) Has(c *mgo.Collection, service string, object string, key string, u
 	return c.Find(selector).One(&result)
 }
 
+// Reason codes explaining a privilege check decision
+const (
+	// The privilege is set to "allow"
+	ReasonAllowed = "allowed"
+	// The privilege is set to "deny"
+	ReasonExplicitDeny = "explicit_deny"
+	// The ACL exists but doesn't hold the privilege
+	ReasonNotGranted = "not_granted"
+	// The privilege holds a value other than "allow" or "deny"
+	ReasonUnknownValue = "unknown_value"
+	// There is no ACL for the key and user
+	ReasonNoACL = "no_acl"
+)
+
+// The source document consulted for a privilege check
+type ExplanationSource struct {
+	Service string `json:"service"`
+	Object  string `json:"object"`
+	Key     string `json:"key"`
+	User    string `json:"user"`
+	Found   bool   `json:"found"`
+}
+
+// Explains the decision for a single privilege in a privilege check
+type PrivilegeExplanation struct {
+	Privilege string      `json:"privilege"`
+	Decision  string      `json:"decision"`
+	Reason    string      `json:"reason"`
+	Value     interface{} `json:"value,omitempty"`
+}
+
+/*
+Explains how a privilege check was decided.  The check is allowed only when every privilege
+is allowed, and Reason is the reason of the first privilege that was denied, or no_acl when
+there is no ACL to consult.
+*/
+type Explanation struct {
+	Decision   string                 `json:"decision"`
+	Reason     string                 `json:"reason"`
+	Sources    []ExplanationSource    `json:"sources"`
+	Privileges []PrivilegeExplanation `json:"privileges"`
+}
+
+// Explains the decision Has makes for the user's privileges on the object's key
+func (a ACL) Explain(c *mgo.Collection, service string, object string, key string, user string,
+	privileges []string) (Explanation, error) {
+	explanation := Explanation{
+		Decision:   "allow",
+		Reason:     ReasonAllowed,
+		Privileges: []PrivilegeExplanation{},
+	}
+
+	acl, err := a.Get(c, service, object, key, user)
+	if err != nil && err != mgo.ErrNotFound {
+		return explanation, err
+	}
+	found := err == nil
+
+	explanation.Sources = []ExplanationSource{
+		ExplanationSource{Service: service, Object: object, Key: key, User: user, Found: found},
+	}
+
+	if !found {
+		explanation.Decision = "deny"
+		explanation.Reason = ReasonNoACL
+	}
+
+	for _, privilege := range privileges {
+		result := PrivilegeExplanation{Privilege: privilege, Decision: "deny"}
+
+		value, ok := acl.Privileges[privilege]
+		switch {
+		case !found:
+			result.Reason = ReasonNoACL
+		case !ok:
+			result.Reason = ReasonNotGranted
+		case value == "allow":
+			result.Decision = "allow"
+			result.Reason = ReasonAllowed
+		case value == "deny":
+			result.Reason = ReasonExplicitDeny
+		default:
+			result.Reason = ReasonUnknownValue
+		}
+		result.Value = value
+
+		if result.Decision == "deny" && explanation.Decision == "allow" {
+			explanation.Decision = "deny"
+			explanation.Reason = result.Reason
+		}
+		explanation.Privileges = append(explanation.Privileges, result)
+	}
+
+	log.Finest("Explained privilege check: %v", explanation)
+	return explanation, nil
+}
+
 // Retrieves the ACL from the collection using the object's key and the user
 func (a ACL) Get(c *mgo.Collection, service string, object string, key string, user string) (ACL, error) {
+	if !a.AsOf.IsZero() {
+		return a.getAsOf(c, service, object, key, user)
+	}
+
 	selector := bson.M{"service": service, "object": object, "key": key, "user": user}
 	result := ACL{}
 	err := c.Find(selector).One(&result)
 	return result, err
 }
 
-// Retrieves the ACL list from the collection
-func (a ACL) List(c *mgo.Collection, service string, object string, key string, user string) ([]ACL, error) {
-	result := []ACL{}
-
+// Builds the query for a page of the ACL list, sorted by key and user.  The key, user and
+// privileges arguments are optional filters.  When value is given, the privileges must hold
+// that value, otherwise they only need to be present.
+func (a ACL) listQuery(c *mgo.Collection, service string, object string, key string, user string,
+	privileges []string, value string, page Page) (*mgo.Query, error) {
 	query := bson.M{"service": service, "object": object}
 	if key != "" {
 		query["key"] = key
@@ -112,22 +486,152 @@ func (a ACL) List(c *mgo.Collection, service string, object string, key string,
 		query["user"] = user
 	}
 
-	err := c.Find(query).All(&result)
+	privilegeSelector(query, privileges, value)
 
-	return result, err
+	err := cursorSelector(query, page.Cursor, true)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Finest("Listing ACLs: %s", query)
+	return page.apply(c.Find(query).Sort("key", "user")), nil
+}
+
+// Retrieves a page of the ACL list from the collection.  See listQuery for the filters.  The
+// returned cursor fetches the next page and is empty when there are no more results.
+func (a ACL) List(c *mgo.Collection, service string, object string, key string, user string,
+	privileges []string, value string, page Page) ([]ACL, string, error) {
+	result := []ACL{}
+
+	if !a.AsOf.IsZero() {
+		pipe, err := a.listAsOfPipe(c, service, object, key, user, privileges, value, page)
+		if err != nil {
+			return result, "", err
+		}
+		err = pipe.All(&result)
+		if err != nil {
+			return result, "", err
+		}
+	} else {
+		query, err := a.listQuery(c, service, object, key, user, privileges, value, page)
+		if err != nil {
+			return result, "", err
+		}
+		err = query.All(&result)
+		if err != nil {
+			return result, "", err
+		}
+	}
+
+	next := ""
+	if page.Limit > 0 && len(result) > page.Limit {
+		result = result[:page.Limit]
+		last := result[len(result)-1]
+		next = Cursor{Key: last.Key, User: last.User}.String()
+	}
+
+	return result, next, nil
 }
 
-// Retrieves a list of the keys for a service/object/user combo that the user has "allow" privileges for
-func (a ACL) Match(c *mgo.Collection, service string, object string, user string, privileges []string) ([]string, error) {
+// Retrieves an iterator over a page of the ACL list.  See listQuery for the filters.  When
+// paging, the iterator yields one ACL past the limit if another page follows.
+func (a ACL) ListIter(c *mgo.Collection, service string, object string, key string, user string,
+	privileges []string, value string, page Page) (*mgo.Iter, error) {
+	if !a.AsOf.IsZero() {
+		pipe, err := a.listAsOfPipe(c, service, object, key, user, privileges, value, page)
+		if err != nil {
+			return nil, err
+		}
+		return pipe.Iter(), nil
+	}
+
+	query, err := a.listQuery(c, service, object, key, user, privileges, value, page)
+	if err != nil {
+		return nil, err
+	}
+	return query.Iter(), nil
+}
+
+// Retrieves an iterator over every ACL for a user across services and objects.  The service,
+// object and privileges arguments are optional filters; an ACL matches a privilege filter
+// when it holds that privilege with any value.
+func (a ACL) ListUser(c *mgo.Collection, user string, service string, object string, privileges []string) *mgo.Iter {
+	query := bson.M{"user": user}
+	if service != "" {
+		query["service"] = service
+	}
+
+	if object != "" {
+		query["object"] = object
+	}
+
+	privilegeSelector(query, privileges, "")
+
+	log.Finest("Listing user ACLs: %s", query)
+	return c.Find(query).Sort("service", "object", "key").Iter()
+}
+
+// Builds the query for a page of the keys for a service/object/user combo that the user has
+// "allow" privileges for, sorted by key.  A value other than "allow" matches the keys where
+// the privileges hold that value instead.
+func (a ACL) matchQuery(c *mgo.Collection, service string, object string, user string, privileges []string,
+	value string, page Page) (*mgo.Query, error) {
+	if value == "" {
+		value = "allow"
+	}
+
 	selector := bson.M{"service": service, "object": object, "user": user}
-	for _, privilege := range privileges {
-		selector["privileges."+privilege] = "allow"
+	privilegeSelector(selector, privileges, value)
+
+	err := cursorSelector(selector, page.Cursor, false)
+	if err != nil {
+		return nil, err
 	}
 
 	log.Finest("Matching user privileges to keys: %s", selector)
+	return page.apply(c.Find(selector).Select(bson.M{"key": 1}).Sort("key")), nil
+}
+
+// Retrieves a page of the keys matching the user's privileges.  See matchQuery for the
+// filters.  The returned cursor fetches the next page and is empty when there are no more results.
+func (a ACL) Match(c *mgo.Collection, service string, object string, user string, privileges []string,
+	value string, page Page) ([]string, string, error) {
 	result := []string{}
-	err := c.Find(selector).Distinct("key", &result)
-	return result, err
+
+	query, err := a.matchQuery(c, service, object, user, privileges, value, page)
+	if err != nil {
+		return result, "", err
+	}
+
+	acls := []ACL{}
+	err = query.All(&acls)
+	if err != nil {
+		return result, "", err
+	}
+
+	for _, acl := range acls {
+		result = append(result, acl.Key)
+	}
+
+	next := ""
+	if page.Limit > 0 && len(result) > page.Limit {
+		result = result[:page.Limit]
+		next = Cursor{Key: result[len(result)-1]}.String()
+	}
+
+	return result, next, nil
+}
+
+// Retrieves an iterator over a page of the matched ACLs, holding only their keys.  See
+// matchQuery for the filters.  When paging, the iterator yields one ACL past the limit if
+// another page follows.
+func (a ACL) MatchIter(c *mgo.Collection, service string, object string, user string, privileges []string,
+	value string, page Page) (*mgo.Iter, error) {
+	query, err := a.matchQuery(c, service, object, user, privileges, value, page)
+	if err != nil {
+		return nil, err
+	}
+	return query.Iter(), nil
 }
 
 // Retrieves the list of services from the collection
@@ -145,3 +649,117 @@ func (a ACL) ListObjects(c *mgo.Collection, service string) ([]string, error) {
 
 	return result, err
 }
+
+/*
+A change to a single ACL made by a copy, move or clone.  Action is "set" when the ACL's
+privileges are replaced with After, or "delete" when the ACL is removed.  Before is nil if
+the ACL didn't exist.
+*/
+type ACLChange struct {
+	Key    string                 `json:"key"`
+	User   string                 `json:"user"`
+	Action string                 `json:"action"`
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+}
+
+// Plans the change that sets the privileges on an ACL, recording its current privileges
+func (a ACL) planSet(c *mgo.Collection, service string, object string, key string, user string,
+	privileges map[string]interface{}) (ACLChange, error) {
+	change := ACLChange{Key: key, User: user, Action: "set", After: privileges}
+
+	existing, err := a.Get(c, service, object, key, user)
+	if err == nil {
+		change.Before = existing.Privileges
+	} else if err != mgo.ErrNotFound {
+		return change, err
+	}
+	return change, nil
+}
+
+// Applies planned changes to the ACLs of a service/object in order.  Returns the changes
+// applied, which are only some of them if one fails.  A change made but not recorded doesn't
+// stop the others, and ErrChangeNotRecorded is returned once they're all applied.
+func (a ACL) applyChanges(c *mgo.Collection, service string, object string, changes []ACLChange) ([]ACLChange, error) {
+	var unrecorded error
+	for idx, change := range changes {
+		var err error
+		if change.Action == "delete" {
+			err = a.Delete(c, service, object, change.Key, change.User)
+		} else {
+			_, err = a.Set(c, service, object, change.Key, change.User, change.After)
+		}
+		if err == ErrChangeNotRecorded {
+			unrecorded = err
+		} else if err != nil {
+			return changes[:idx], err
+		}
+	}
+	return changes, unrecorded
+}
+
+/*
+Copies every user's ACL on one key to another key, replacing the privileges those users
+already had on the destination key.  When move is true the ACLs on the source key are
+deleted once every copy is written, so a failed move never loses privileges, but it isn't
+atomic: a failure can leave some ACLs copied, or on both keys.  When dryRun is true nothing
+is changed.  Returns the changes made, including those made before an error.
+*/
+func (a ACL) CopyKey(c *mgo.Collection, service string, object string, fromKey string, toKey string,
+	move bool, dryRun bool) ([]ACLChange, error) {
+	changes := []ACLChange{}
+	deletes := []ACLChange{}
+
+	sources, _, err := a.List(c, service, object, fromKey, "", nil, "", Page{})
+	if err != nil {
+		return changes, err
+	}
+
+	for _, source := range sources {
+		change, err := a.planSet(c, service, object, toKey, source.User, source.Privileges)
+		if err != nil {
+			return []ACLChange{}, err
+		}
+		changes = append(changes, change)
+
+		if move {
+			deletes = append(deletes, ACLChange{Key: fromKey, User: source.User, Action: "delete",
+				Before: source.Privileges})
+		}
+	}
+	changes = append(changes, deletes...)
+
+	log.Finest("Copying ACLs from key %s to key %s: %v", fromKey, toKey, changes)
+	if dryRun {
+		return changes, nil
+	}
+	return a.applyChanges(c, service, object, changes)
+}
+
+// Clones a user's ACLs onto another user, replacing the privileges the other user already
+// had on those keys.  The key argument optionally limits the clone to a single key.  When
+// dryRun is true nothing is changed.  Returns the changes made, including those made before
+// an error.
+func (a ACL) CloneUser(c *mgo.Collection, service string, object string, key string, fromUser string,
+	toUser string, dryRun bool) ([]ACLChange, error) {
+	changes := []ACLChange{}
+
+	sources, _, err := a.List(c, service, object, key, fromUser, nil, "", Page{})
+	if err != nil {
+		return changes, err
+	}
+
+	for _, source := range sources {
+		change, err := a.planSet(c, service, object, source.Key, toUser, source.Privileges)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+
+	log.Finest("Cloning ACLs from user %s to user %s: %v", fromUser, toUser, changes)
+	if dryRun {
+		return changes, nil
+	}
+	return a.applyChanges(c, service, object, changes)
+}
@@ -0,0 +1,648 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"net/http"
+)
+
+// A JSON object in the OpenAPI document
+type jsonObject map[string]interface{}
+
+// References a schema in the components section
+func schemaRef(name string) jsonObject {
+	return jsonObject{"$ref": "#/components/schemas/" + name}
+}
+
+// A schema for a list of the given schema
+func arrayOf(items jsonObject) jsonObject {
+	return jsonObject{"type": "array", "items": items}
+}
+
+// A schema for an object with the given properties
+func objectOf(required []string, properties jsonObject) jsonObject {
+	schema := jsonObject{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// A simple typed schema with a description
+func typed(typ string, description string) jsonObject {
+	return jsonObject{"type": typ, "description": description}
+}
+
+// A path parameter
+func pathParam(name string, description string) jsonObject {
+	return jsonObject{"name": name, "in": "path", "required": true, "description": description,
+		"schema": jsonObject{"type": "string"}}
+}
+
+// An optional query string parameter
+func queryParam(name string, typ string, description string) jsonObject {
+	return jsonObject{"name": name, "in": "query", "description": description,
+		"schema": jsonObject{"type": typ}}
+}
+
+// A JSON request body holding a list of the given item schema
+func itemsBody(item string) jsonObject {
+	return jsonObject{
+		"required": true,
+		"content":  jsonObject{"application/json": jsonObject{"schema": arrayOf(schemaRef(item))}},
+	}
+}
+
+// A JSON response with the given schema
+func jsonResponse(description string, schema jsonObject) jsonObject {
+	return jsonObject{
+		"description": description,
+		"content":     jsonObject{"application/json": jsonObject{"schema": schema}},
+	}
+}
+
+// The responses of an operation, adding the error responses every operation can return
+func responses(success string, response jsonObject) jsonObject {
+	errorResponse := jsonResponse("Error", schemaRef("Error"))
+	return jsonObject{
+		success: response,
+		"400":   errorResponse,
+		"401":   errorResponse,
+		"403":   errorResponse,
+		"500":   errorResponse,
+		"503":   errorResponse,
+	}
+}
+
+// An operation on a path.  The operation ID is the name of the route in the router.
+func operation(id string, summary string, params []jsonObject, body jsonObject, resp jsonObject) jsonObject {
+	op := jsonObject{"operationId": id, "summary": summary, "responses": resp}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if body != nil {
+		op["requestBody"] = body
+	}
+	return op
+}
+
+// The schemas shared between operations
+func openAPISchemas() jsonObject {
+	privilegeList := arrayOf(jsonObject{"type": "string"})
+	privilegeMap := jsonObject{"type": "object", "additionalProperties": jsonObject{"type": "string"},
+		"description": "Privilege names mapped to \"allow\" or \"deny\""}
+	nullablePrivilegeMap := copyMap(privilegeMap)
+	nullablePrivilegeMap["nullable"] = true
+	ifRevision := typed("integer", "Only write if the ACL is still at this revision, otherwise fail with 409")
+
+	return jsonObject{
+		"Error": objectOf([]string{"error"}, jsonObject{
+			"error": objectOf([]string{"code", "message"}, jsonObject{
+				"code":    typed("string", "Error code clients can switch on"),
+				"message": typed("string", "Human readable description"),
+				"item":    typed("integer", "Index of the request body item the error refers to"),
+				"field":   typed("string", "Field of the request body item the error refers to"),
+			}),
+		}),
+		"ACL": objectOf(nil, jsonObject{
+			"Service":    jsonObject{"type": "string"},
+			"Object":     jsonObject{"type": "string"},
+			"Key":        jsonObject{"type": "string"},
+			"User":       jsonObject{"type": "string"},
+			"Privileges": privilegeMap,
+			"Revision":   typed("integer", "Revision of the ACL, which changes on every write"),
+		}),
+		"ACLPage": objectOf([]string{"acls", "next_cursor"}, jsonObject{
+			"acls":        arrayOf(schemaRef("ACL")),
+			"next_cursor": typed("string", "Cursor of the next page, empty on the last page"),
+		}),
+		"PrivilegeListItem": objectOf([]string{"key", "user", "privileges"}, jsonObject{
+			"key":         jsonObject{"type": "string"},
+			"user":        jsonObject{"type": "string"},
+			"privileges":  privilegeList,
+			"if_revision": ifRevision,
+		}),
+		"PrivilegeMapItem": objectOf([]string{"key", "user", "privileges"}, jsonObject{
+			"key":         jsonObject{"type": "string"},
+			"user":        jsonObject{"type": "string"},
+			"privileges":  privilegeMap,
+			"if_revision": ifRevision,
+		}),
+		"KeyUserItem": objectOf([]string{"key", "user"}, jsonObject{
+			"key":  jsonObject{"type": "string"},
+			"user": jsonObject{"type": "string"},
+		}),
+		"MatchItem": objectOf([]string{"user", "privileges"}, jsonObject{
+			"user":       jsonObject{"type": "string"},
+			"privileges": privilegeList,
+			"value":      typed("string", "Privilege value to match, defaults to \"allow\""),
+			"limit":      typed("integer", "Maximum number of keys to return"),
+			"cursor":     typed("string", "Cursor of the page to return"),
+		}),
+		"CopyItem": objectOf([]string{"from_key", "to_key"}, jsonObject{
+			"from_key": jsonObject{"type": "string"},
+			"to_key":   jsonObject{"type": "string"},
+		}),
+		"RestoreItem": objectOf([]string{"key", "user", "revision"}, jsonObject{
+			"key":      jsonObject{"type": "string"},
+			"user":     jsonObject{"type": "string"},
+			"revision": typed("integer", "Revision to restore, from the ACL's history"),
+		}),
+		"ACLRevision": objectOf([]string{"service", "object", "key", "user", "revision", "privileges", "time", "caller"},
+			jsonObject{
+				"service":    jsonObject{"type": "string"},
+				"object":     jsonObject{"type": "string"},
+				"key":        jsonObject{"type": "string"},
+				"user":       jsonObject{"type": "string"},
+				"revision":   typed("integer", "Number of the revision, counting from 1"),
+				"privileges": typed("object", "Privileges after the revision"),
+				"deleted":    typed("boolean", "The revision deleted the ACL"),
+				"time":       jsonObject{"type": "string", "format": "date-time"},
+				"caller":     typed("string", "Who made the revision"),
+			}),
+		"CloneItem": objectOf([]string{"from_user", "to_user"}, jsonObject{
+			"from_user": jsonObject{"type": "string"},
+			"to_user":   jsonObject{"type": "string"},
+			"key":       typed("string", "Only clone the ACL on this key"),
+		}),
+		"HasResult": objectOf([]string{"key", "user", "privilege"}, jsonObject{
+			"key":         jsonObject{"type": "string"},
+			"user":        jsonObject{"type": "string"},
+			"privilege":   jsonObject{"type": "string", "enum": []string{"allow", "deny"}},
+			"explanation": schemaRef("Explanation"),
+		}),
+		"Explanation": objectOf([]string{"decision", "reason", "sources", "privileges"}, jsonObject{
+			"decision": jsonObject{"type": "string", "enum": []string{"allow", "deny"}},
+			"reason":   typed("string", "Reason code of the decision"),
+			"sources": arrayOf(objectOf(nil, jsonObject{
+				"service": jsonObject{"type": "string"},
+				"object":  jsonObject{"type": "string"},
+				"key":     jsonObject{"type": "string"},
+				"user":    jsonObject{"type": "string"},
+				"found":   jsonObject{"type": "boolean"},
+			})),
+			"privileges": arrayOf(objectOf(nil, jsonObject{
+				"privilege": jsonObject{"type": "string"},
+				"decision":  jsonObject{"type": "string", "enum": []string{"allow", "deny"}},
+				"reason":    typed("string", "Reason code of the decision"),
+				"value":     typed("string", "Value the privilege holds"),
+			})),
+		}),
+		"GetResult": objectOf([]string{"key", "user", "privileges", "revision"}, jsonObject{
+			"key":        jsonObject{"type": "string"},
+			"user":       jsonObject{"type": "string"},
+			"privileges": privilegeMap,
+			"revision":   typed("integer", "Revision of the ACL, 0 when there is none"),
+		}),
+		"MatchResult": objectOf([]string{"user", "keys"}, jsonObject{
+			"user":        jsonObject{"type": "string"},
+			"keys":        arrayOf(jsonObject{"type": "string"}),
+			"next_cursor": typed("string", "Cursor of the next page when the item has a limit"),
+		}),
+		"ACLChange": objectOf([]string{"key", "user", "action"}, jsonObject{
+			"key":    jsonObject{"type": "string"},
+			"user":   jsonObject{"type": "string"},
+			"action": jsonObject{"type": "string", "enum": []string{"set", "delete"}},
+			"before": nullablePrivilegeMap,
+			"after":  nullablePrivilegeMap,
+		}),
+		"ACLEvent": objectOf([]string{"seq", "service", "object", "key", "user", "action", "time"}, jsonObject{
+			"seq":     typed("integer", "Sequence number of the event"),
+			"service": jsonObject{"type": "string"},
+			"object":  jsonObject{"type": "string"},
+			"key":     jsonObject{"type": "string"},
+			"user":    jsonObject{"type": "string"},
+			"action": jsonObject{"type": "string",
+				"enum": []string{"grant", "deny", "revoke", "set", "delete"}},
+			"privileges": jsonObject{
+				"description": "Privilege names of a grant, deny or revoke, or the privileges of a set",
+				"oneOf":       []jsonObject{privilegeList, privilegeMap},
+			},
+			"time": jsonObject{"type": "string", "format": "date-time"},
+		}),
+		"WatchResult": objectOf([]string{"events", "next_since"}, jsonObject{
+			"events":     arrayOf(schemaRef("ACLEvent")),
+			"next_since": typed("string", "Sequence token to watch from next"),
+		}),
+		"WebhookItem": objectOf([]string{"url", "secret"}, jsonObject{
+			"url":    typed("string", "Absolute http or https URL events are posted to"),
+			"events": arrayOf(jsonObject{"type": "string", "enum": []string{"grant", "deny", "revoke", "set", "delete"}}),
+			"object": typed("string", "Only send events on this object"),
+			"secret": typed("string", "Secret deliveries are signed with"),
+		}),
+		"Webhook": objectOf([]string{"id", "service", "url", "events", "created"}, jsonObject{
+			"id":      jsonObject{"type": "string"},
+			"service": jsonObject{"type": "string"},
+			"url":     jsonObject{"type": "string"},
+			"events":  arrayOf(jsonObject{"type": "string"}),
+			"object":  jsonObject{"type": "string"},
+			"created": jsonObject{"type": "string", "format": "date-time"},
+		}),
+		"WebhookDelivery": objectOf([]string{"id", "webhook_id", "event", "status", "attempts"}, jsonObject{
+			"id":           jsonObject{"type": "string"},
+			"webhook_id":   jsonObject{"type": "string"},
+			"event":        schemaRef("ACLEvent"),
+			"status":       jsonObject{"type": "string", "enum": []string{"pending", "delivered", "dead"}},
+			"attempts":     jsonObject{"type": "integer"},
+			"next_attempt": jsonObject{"type": "string", "format": "date-time"},
+			"last_status":  typed("integer", "Status the receiver last responded with"),
+			"last_error":   typed("string", "Why the last attempt failed"),
+			"created":      jsonObject{"type": "string", "format": "date-time"},
+			"delivered":    jsonObject{"type": "string", "format": "date-time"},
+			"expires":      jsonObject{"type": "string", "format": "date-time"},
+		}),
+		"APIKeyItem": objectOf([]string{"name", "services", "scopes"}, jsonObject{
+			"name":     jsonObject{"type": "string"},
+			"services": arrayOf(typed("string", "Service the key may use, or * for every service")),
+			"scopes":   arrayOf(jsonObject{"type": "string", "enum": []string{"read", "write", "admin"}}),
+		}),
+		"APIKey": objectOf([]string{"id", "name", "services", "scopes", "created"}, jsonObject{
+			"id":               jsonObject{"type": "string"},
+			"name":             jsonObject{"type": "string"},
+			"services":         arrayOf(jsonObject{"type": "string"}),
+			"scopes":           arrayOf(jsonObject{"type": "string"}),
+			"created":          jsonObject{"type": "string", "format": "date-time"},
+			"rotated":          jsonObject{"type": "string", "format": "date-time"},
+			"revoked":          jsonObject{"type": "string", "format": "date-time"},
+			"previous_expires": typed("string", "When the secret replaced by the last rotation stops working"),
+			"key":              typed("string", "The key to authenticate with, only returned on creation and rotation"),
+		}),
+		"AuditEntry": objectOf([]string{"id", "seq", "prev_hash", "hash", "time", "caller", "action", "service", "object",
+			"key", "user"}, jsonObject{
+			"id":         jsonObject{"type": "string"},
+			"seq":        typed("integer", "Position of the entry in the audit chain"),
+			"prev_hash":  typed("string", "Hash of the entry before, empty for the first"),
+			"hash":       typed("string", "Hex SHA-256 of the entry and prev_hash"),
+			"time":       jsonObject{"type": "string", "format": "date-time"},
+			"caller":     typed("string", "Who made the change, anonymous without authentication"),
+			"method":     typed("string", "How the caller authenticated"),
+			"request_id": typed("string", "ID of the request that made the change"),
+			"action":     jsonObject{"type": "string"},
+			"service":    jsonObject{"type": "string"},
+			"object":     jsonObject{"type": "string"},
+			"key":        jsonObject{"type": "string"},
+			"user":       jsonObject{"type": "string"},
+			"before":     typed("object", "Privileges before the change, null when the ACL didn't exist"),
+			"after":      typed("object", "Privileges after the change, null when the ACL was removed"),
+		}),
+		"AuditPage": objectOf([]string{"entries", "next_cursor"}, jsonObject{
+			"entries":     arrayOf(schemaRef("AuditEntry")),
+			"next_cursor": typed("string", "Cursor of the next page, empty on the last page"),
+		}),
+		"AuditVerification": objectOf([]string{"valid", "entries", "checkpoints"}, jsonObject{
+			"valid":       jsonObject{"type": "boolean"},
+			"entries":     typed("integer", "Entries verified before the first break"),
+			"checkpoints": typed("integer", "Checkpoints verified before the first break"),
+			"broken": objectOf([]string{"seq", "reason"}, jsonObject{
+				"seq":    typed("integer", "Sequence number of the first entry that doesn't hold"),
+				"id":     jsonObject{"type": "string"},
+				"reason": jsonObject{"type": "string"},
+			}),
+		}),
+		"ImportSummary": objectOf([]string{"rows", "applied", "skipped", "failed", "retried", "checkpoint", "done", "failures"},
+			jsonObject{
+				"rows":       typed("integer", "Records read"),
+				"applied":    typed("integer", "Records applied"),
+				"skipped":    typed("integer", "Records skipped because an earlier run of the import applied them"),
+				"failed":     typed("integer", "Records that were invalid or couldn't be applied"),
+				"retried":    typed("integer", "Records an earlier run of the import failed that were tried again"),
+				"checkpoint": typed("integer", "Last row the import has finished with"),
+				"done":       typed("boolean", "Whether every record has been read"),
+				"failures": arrayOf(objectOf([]string{"row", "error"}, jsonObject{
+					"row": typed("integer", "Row of the record, counting from 1"),
+					"error": objectOf([]string{"code", "message"}, jsonObject{
+						"code":    typed("string", "Error code clients can switch on"),
+						"message": typed("string", "Human readable description"),
+						"field":   typed("string", "Field of the record the error refers to"),
+					}),
+				})),
+			}),
+		"SnapshotRestore": objectOf([]string{"mode", "acls", "written", "unchanged", "deleted"}, jsonObject{
+			"mode":      jsonObject{"type": "string"},
+			"service":   typed("string", "Service of the snapshot, empty for the whole store"),
+			"object":    typed("string", "Object of the snapshot, empty for a whole service"),
+			"acls":      typed("integer", "ACLs in the snapshot"),
+			"written":   typed("integer", "ACLs of the snapshot written"),
+			"unchanged": typed("integer", "ACLs of the snapshot that already matched it"),
+			"deleted":   typed("integer", "ACLs missing from the snapshot deleted by replace"),
+		}),
+		"ChangeResult": objectOf([]string{"dry_run", "changes"}, jsonObject{
+			"from_key":  jsonObject{"type": "string"},
+			"to_key":    jsonObject{"type": "string"},
+			"from_user": jsonObject{"type": "string"},
+			"to_user":   jsonObject{"type": "string"},
+			"key":       jsonObject{"type": "string"},
+			"dry_run":   jsonObject{"type": "boolean"},
+			"changes":   arrayOf(schemaRef("ACLChange")),
+		}),
+	}
+}
+
+// Builds the OpenAPI document describing every route in ConfigureRouter
+func openAPISpec() jsonObject {
+	service := pathParam("service", "Name of the service")
+	object := pathParam("object", "Name of the object within the service")
+	objectParams := []jsonObject{service, object}
+	dryRun := queryParam("dry_run", "boolean", "Only report the changes that would be made")
+	asOf := queryParam("as_of", "string", "Read the ACLs as they were at this RFC 3339 time")
+
+	noContent := jsonObject{"description": "The ACLs were updated"}
+	ifMatch := jsonObject{"name": "If-Match", "in": "header", "schema": jsonObject{"type": "string"},
+		"description": "ETag from a get of a single ACL, to write a single item only if it's unchanged"}
+	writeParams := []jsonObject{service, object, ifMatch}
+	conflict := jsonResponse("An ACL isn't at the expected revision", schemaRef("Error"))
+	writeResponses := func() jsonObject {
+		resp := responses("204", noContent)
+		resp["409"] = conflict
+		return resp
+	}
+	changes := jsonResponse("The changes made", arrayOf(schemaRef("ChangeResult")))
+	acls := jsonResponse("The ACLs, or an ACLPage when paging", jsonObject{
+		"oneOf": []jsonObject{arrayOf(schemaRef("ACL")), schemaRef("ACLPage")},
+	})
+	acls["content"].(jsonObject)["application/x-ndjson"] = jsonObject{"schema": schemaRef("ACL")}
+	watch := jsonResponse("The events, or a Server-Sent Events stream of them", schemaRef("WatchResult"))
+	watch["content"].(jsonObject)["text/event-stream"] = jsonObject{"schema": schemaRef("ACLEvent")}
+	getResult := jsonResponse("The privileges", arrayOf(schemaRef("GetResult")))
+	getResult["headers"] = jsonObject{"ETag": jsonObject{"schema": jsonObject{"type": "string"},
+		"description": "Revision of the ACL when a single one is read"}}
+	userACLs := jsonResponse("The ACLs", arrayOf(schemaRef("ACL")))
+	userACLs["content"].(jsonObject)["application/x-ndjson"] = jsonObject{"schema": schemaRef("ACL")}
+
+	webhook := pathParam("webhook", "ID of the webhook")
+	notFound := jsonResponse("No such webhook or delivery", schemaRef("Error"))
+	webhookResponses := func(success string, response jsonObject) jsonObject {
+		resp := responses(success, response)
+		resp["404"] = notFound
+		return resp
+	}
+
+	apiKey := pathParam("key", "ID of the API key")
+	keyNotFound := jsonResponse("No such API key", schemaRef("Error"))
+	keyResponses := func(success string, response jsonObject) jsonObject {
+		resp := responses(success, response)
+		resp["404"] = keyNotFound
+		return resp
+	}
+
+	// The OpenAPI document can be read without credentials
+	openAPI := operation("OpenAPI", "This OpenAPI document", nil, nil,
+		responses("200", jsonResponse("The OpenAPI document", jsonObject{"type": "object"})))
+	openAPI["security"] = []jsonObject{}
+
+	objectPath := "/v1/service/{service}/object/{object}"
+	webhookPath := "/v1/service/{service}/webhook"
+
+	spec := jsonObject{
+		"openapi": "3.0.3",
+		"info": jsonObject{
+			"title":   "Authorizer",
+			"version": "1",
+		},
+		"paths": jsonObject{
+			"/v1/openapi.json": jsonObject{
+				"get": openAPI,
+			},
+			"/v1/admin/key/": jsonObject{
+				"post": operation("CreateAPIKey", "Create API keys", nil, itemsBody("APIKeyItem"),
+					responses("201", jsonResponse("The API keys", arrayOf(schemaRef("APIKey"))))),
+				"get": operation("ListAPIKeys", "List the API keys, including revoked ones", nil, nil,
+					responses("200", jsonResponse("The API keys", arrayOf(schemaRef("APIKey"))))),
+			},
+			"/v1/admin/key/{key}/": jsonObject{
+				"delete": operation("RevokeAPIKey", "Revoke an API key", []jsonObject{apiKey}, nil,
+					keyResponses("204", jsonObject{"description": "The API key was revoked"})),
+			},
+			"/v1/admin/key/{key}/rotate/": jsonObject{
+				"post": operation("RotateAPIKey", "Give an API key a new secret",
+					[]jsonObject{apiKey,
+						queryParam("grace_seconds", "integer", "Seconds the old secret keeps working, at most a week"),
+					}, nil, keyResponses("200", jsonResponse("The API key", schemaRef("APIKey")))),
+			},
+			"/v1/audit/": jsonObject{
+				"get": operation("AuditLog", "Query the audit log of ACL changes, oldest first",
+					[]jsonObject{
+						queryParam("from", "string", "Only entries at or after this RFC 3339 time"),
+						queryParam("to", "string", "Only entries before this RFC 3339 time"),
+						queryParam("service", "string", "Only entries in this service"),
+						queryParam("object", "string", "Only entries on this object"),
+						queryParam("key", "string", "Only entries on this key"),
+						queryParam("user", "string", "Only entries for this user"),
+						queryParam("caller", "string", "Only entries made by this caller"),
+						queryParam("limit", "integer", "Most entries to return, at most 1000"),
+						queryParam("cursor", "string", "Cursor of the page to return"),
+					}, nil, responses("200", jsonResponse("The entries", schemaRef("AuditPage")))),
+			},
+			"/v1/audit/verify/": jsonObject{
+				"get": operation("VerifyAudit", "Verify the audit log's hash chain and checkpoints", nil, nil,
+					responses("200", jsonResponse("The result, with the first broken link if there is one",
+						schemaRef("AuditVerification")))),
+			},
+			"/v1/import/": jsonObject{
+				"post": operation("ImportACL", "Import grant, deny, revoke and set records in batches",
+					[]jsonObject{
+						queryParam("format", "string", "jsonl or csv, defaulting to csv for a text/csv body"),
+						queryParam("batch_size", "integer", "Records applied between checkpoints, defaults to 500"),
+						queryParam("id", "string", "Name of the import, to checkpoint it and resume it when sent again"),
+						queryParam("service", "string", "Only import records of this service"),
+					}, jsonObject{
+						"required": true,
+						"content": jsonObject{
+							"application/x-ndjson": jsonObject{"schema": typed("string", "A JSON record per line")},
+							"text/csv":             jsonObject{"schema": typed("string", "Records under a header row")},
+						},
+					}, responses("200", jsonObject{
+						"description": "The summary, after a line of progress per batch for NDJSON",
+						"content": jsonObject{
+							"application/json":     jsonObject{"schema": schemaRef("ImportSummary")},
+							"application/x-ndjson": jsonObject{"schema": schemaRef("ImportSummary")},
+						},
+					})),
+			},
+			"/v1/snapshot/": jsonObject{
+				"get": operation("ExportSnapshot", "Export a gzipped snapshot of the ACLs of the store, a service or an object",
+					[]jsonObject{
+						queryParam("service", "string", "Only export the ACLs of this service"),
+						queryParam("object", "string", "Only export the ACLs of this object of the service"),
+					}, nil, responses("200", jsonObject{
+						"description": "The snapshot: a header line, a line per ACL and an end line, as gzipped JSONL",
+						"content":     jsonObject{"application/gzip": jsonObject{"schema": typed("string", "Gzipped JSONL")}},
+					})),
+				"post": operation("RestoreSnapshot", "Restore a snapshot, merging it or replacing the ACLs of its scope",
+					[]jsonObject{
+						queryParam("mode", "string", "merge (the default) keeps ACLs missing from the snapshot, replace deletes them"),
+						queryParam("service", "string", "Only restore a snapshot of this service"),
+					}, jsonObject{
+						"required": true,
+						"content": jsonObject{
+							"application/gzip":     jsonObject{"schema": typed("string", "A snapshot from ExportSnapshot")},
+							"application/x-ndjson": jsonObject{"schema": typed("string", "An uncompressed snapshot")},
+						},
+					}, responses("200", jsonResponse("What was restored", schemaRef("SnapshotRestore")))),
+			},
+			"/v1/service/": jsonObject{
+				"get": operation("ListServices", "List the services with ACLs", nil, nil,
+					responses("200", jsonResponse("Service names", arrayOf(jsonObject{"type": "string"})))),
+			},
+			"/v1/service/{service}/object/": jsonObject{
+				"get": operation("ListObjects", "List the objects of a service with ACLs",
+					[]jsonObject{service}, nil,
+					responses("200", jsonResponse("Object names", arrayOf(jsonObject{"type": "string"})))),
+			},
+			webhookPath + "/": jsonObject{
+				"post": operation("CreateWebhook", "Register webhooks sent the changes to a service's ACLs",
+					[]jsonObject{service}, itemsBody("WebhookItem"),
+					responses("201", jsonResponse("The webhooks", arrayOf(schemaRef("Webhook"))))),
+				"get": operation("ListWebhooks", "List the webhooks of a service", []jsonObject{service}, nil,
+					responses("200", jsonResponse("The webhooks", arrayOf(schemaRef("Webhook"))))),
+			},
+			webhookPath + "/{webhook}/": jsonObject{
+				"delete": operation("DeleteWebhook", "Delete a webhook and its deliveries",
+					[]jsonObject{service, webhook}, nil,
+					webhookResponses("204", jsonObject{"description": "The webhook was deleted"})),
+			},
+			webhookPath + "/{webhook}/deliveries/": jsonObject{
+				"get": operation("ListWebhookDeliveries", "List the deliveries of a webhook, newest first",
+					[]jsonObject{service, webhook,
+						queryParam("status", "string", "Only list deliveries with this status"),
+						queryParam("limit", "integer", "Maximum number of deliveries to return, defaults to 100"),
+					}, nil,
+					webhookResponses("200", jsonResponse("The deliveries", arrayOf(schemaRef("WebhookDelivery"))))),
+			},
+			webhookPath + "/{webhook}/deliveries/{delivery}/retry/": jsonObject{
+				"post": operation("RetryWebhookDelivery", "Send a delivery again, resetting its attempts",
+					[]jsonObject{service, webhook, pathParam("delivery", "ID of the delivery")}, nil,
+					webhookResponses("204", jsonObject{"description": "The delivery was queued"})),
+			},
+			objectPath + "/grant/": jsonObject{
+				"post": operation("GrantACL", "Allow privileges for users on keys", writeParams,
+					itemsBody("PrivilegeListItem"), writeResponses()),
+			},
+			objectPath + "/deny/": jsonObject{
+				"post": operation("DenyACL", "Deny privileges for users on keys", writeParams,
+					itemsBody("PrivilegeListItem"), writeResponses()),
+			},
+			objectPath + "/revoke/": jsonObject{
+				"post": operation("RevokeACL", "Remove privileges for users on keys", writeParams,
+					itemsBody("PrivilegeListItem"), writeResponses()),
+			},
+			objectPath + "/set/": jsonObject{
+				"put": operation("SetACL", "Replace the privileges for users on keys", writeParams,
+					itemsBody("PrivilegeMapItem"), writeResponses()),
+			},
+			objectPath + "/has/": jsonObject{
+				"get": operation("HasACL", "Check users are allowed privileges on keys",
+					[]jsonObject{service, object, asOf,
+						queryParam("explain", "boolean", "Explain how each decision was reached")},
+					itemsBody("PrivilegeListItem"),
+					responses("200", jsonResponse("The decisions", arrayOf(schemaRef("HasResult"))))),
+			},
+			objectPath + "/get/": jsonObject{
+				"get": operation("GetACL", "Get the privileges for users on keys", []jsonObject{service, object, asOf},
+					itemsBody("KeyUserItem"), responses("200", getResult)),
+			},
+			objectPath + "/list/": jsonObject{
+				"get": operation("ListACL", "List the ACLs of an object",
+					[]jsonObject{service, object,
+						queryParam("key", "string", "Only list ACLs on this key"),
+						queryParam("user", "string", "Only list ACLs for this user"),
+						queryParam("privilege", "string", "Only list ACLs holding this privilege, may be repeated"),
+						queryParam("privilege_value", "string", "Value the privilege filters must hold"),
+						queryParam("limit", "integer", "Maximum number of ACLs to return"),
+						queryParam("cursor", "string", "Cursor of the page to return"),
+						asOf,
+					}, nil, responses("200", acls)),
+			},
+			objectPath + "/history/": jsonObject{
+				"get": operation("HistoryACL", "List the revisions of an ACL, newest first",
+					[]jsonObject{service, object,
+						queryParam("key", "string", "Key of the ACL"),
+						queryParam("user", "string", "User of the ACL"),
+					}, nil, responses("200", jsonResponse("The revisions", arrayOf(schemaRef("ACLRevision"))))),
+			},
+			objectPath + "/restore/": jsonObject{
+				"post": operation("RestoreACL", "Restore ACLs to earlier revisions", objectParams,
+					itemsBody("RestoreItem"), responses("204", noContent)),
+			},
+			objectPath + "/match/": jsonObject{
+				"get": operation("MatchACL", "Find the keys users are allowed privileges on", objectParams,
+					itemsBody("MatchItem"),
+					responses("200", jsonResponse("The matched keys", arrayOf(schemaRef("MatchResult"))))),
+			},
+			objectPath + "/copy/": jsonObject{
+				"post": operation("CopyACL", "Copy every ACL on a key to another key",
+					[]jsonObject{service, object, dryRun}, itemsBody("CopyItem"), responses("200", changes)),
+			},
+			objectPath + "/move/": jsonObject{
+				"post": operation("MoveACL", "Move every ACL on a key to another key",
+					[]jsonObject{service, object, dryRun}, itemsBody("CopyItem"), responses("200", changes)),
+			},
+			objectPath + "/clone/": jsonObject{
+				"post": operation("CloneACL", "Clone a user's ACLs onto another user",
+					[]jsonObject{service, object, dryRun}, itemsBody("CloneItem"), responses("200", changes)),
+			},
+			objectPath + "/watch/": jsonObject{
+				"get": operation("WatchACL", "Watch the changes made to the ACLs of an object",
+					[]jsonObject{service, object,
+						queryParam("since", "string", "Sequence token to send the events after, defaults to now"),
+						queryParam("key", "string", "Only send events on this key"),
+						queryParam("timeout", "integer", "Seconds a long poll waits for events, at most 50"),
+					}, nil, responses("200", watch)),
+			},
+			"/v1/user/{user}/acl/": jsonObject{
+				"get": operation("UserACL", "List every ACL of a user across services and objects",
+					[]jsonObject{
+						pathParam("user", "The user"),
+						queryParam("service", "string", "Only list ACLs in this service"),
+						queryParam("object", "string", "Only list ACLs on this object"),
+						queryParam("privilege", "string", "Only list ACLs holding this privilege, may be repeated"),
+					}, nil, responses("200", userACLs)),
+			},
+		},
+		"security": []jsonObject{jsonObject{"apiKey": []string{}}, jsonObject{"bearer": []string{}},
+			jsonObject{"signature": []string{}}},
+		"components": jsonObject{
+			"schemas": openAPISchemas(),
+			"securitySchemes": jsonObject{
+				"apiKey": jsonObject{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearer": jsonObject{"type": "http", "scheme": "bearer",
+					"description": "An API key, or a JWT from the configured identity provider"},
+				"signature": jsonObject{"type": "apiKey", "in": "header", "name": "X-Authorizer-Signature",
+					"description": "HMAC request signature, sent with X-Authorizer-Key-Id and X-Authorizer-Timestamp"},
+			},
+		},
+	}
+
+	// Every write can be made idempotent with a key, except those streaming their bodies
+	idempotencyKey := jsonObject{"name": "Idempotency-Key", "in": "header", "schema": jsonObject{"type": "string"},
+		"description": "Key replaying the stored response when the request is repeated"}
+	for _, path := range spec["paths"].(jsonObject) {
+		for method, op := range path.(jsonObject) {
+			op := op.(jsonObject)
+			if method == "get" || streamingRoutes[op["operationId"].(string)] {
+				continue
+			}
+			params, _ := op["parameters"].([]jsonObject)
+			op["parameters"] = append(params, idempotencyKey)
+			resp := op["responses"].(jsonObject)
+			if _, ok := resp["409"]; !ok {
+				resp["409"] = jsonResponse("A request with the idempotency key is in progress", schemaRef("Error"))
+			}
+			resp["422"] = jsonResponse("The idempotency key was used for a different request", schemaRef("Error"))
+		}
+	}
+	return spec
+}
+
+// This is a URL handler for getting the OpenAPI document
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(openAPISpec())
+	if err != nil {
+		log.Error("Error marshalling OpenAPI document: %s", err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred getting the OpenAPI document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIRoutes(t *testing.T) {
+	router := Application.Router
+	defer func() { Application.Router = router }()
+
+	Application.Router = mux.NewRouter()
+	ConfigureRouter()
+
+	paths := openAPISpec()["paths"].(jsonObject)
+
+	routes := map[string]bool{}
+	err := Application.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetName() == "" {
+			return nil
+		}
+		if _, ok := routeOperations[route.GetName()]; !ok {
+			t.Error("Route has no operation for authorization: ", route.GetName())
+		}
+
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+
+		path, ok := paths[template].(jsonObject)
+		if !ok {
+			t.Error("Route missing from OpenAPI document: ", route.GetName(), template)
+			return nil
+		}
+
+		for _, method := range methods {
+			op, ok := path[strings.ToLower(method)].(jsonObject)
+			if !ok {
+				t.Error("Route method missing from OpenAPI document: ", route.GetName(), method, template)
+			} else if op["operationId"] != route.GetName() {
+				t.Error("OpenAPI operation ID doesn't match route name: ", op["operationId"], route.GetName())
+			}
+			routes[fmt.Sprintf("%s %s", strings.ToLower(method), template)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Error walking routes: ", err)
+	}
+
+	for template, path := range paths {
+		for method := range path.(jsonObject) {
+			if !routes[fmt.Sprintf("%s %s", method, template)] {
+				t.Error("OpenAPI document describes a route that doesn't exist: ", method, template)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"labix.org/v2/mgo"
+)
+
+// The reserved service holding who may manage each service, or empty to only check the
+// scopes of callers
+var adminService = ""
+
+// The object of the admin service whose keys are the names of the services managed
+const adminObject = "services"
+
+// The key of the admin service granting privileges on every service
+const adminAllServices = "*"
+
+// The privileges of the admin service.  Admin includes the others.
+const (
+	PrivilegeGrant  = "grant"
+	PrivilegeRevoke = "revoke"
+	PrivilegeAdmin  = "admin"
+)
+
+// The privileges on its service each write route needs.  Set, copy, move, clone, restore and
+// import can replace the privileges already at their destination, so they need both.
+var routePrivileges = map[string][]string{
+	"GrantACL":              {PrivilegeGrant},
+	"DenyACL":               {PrivilegeGrant},
+	"RevokeACL":             {PrivilegeRevoke},
+	"SetACL":                {PrivilegeGrant, PrivilegeRevoke},
+	"CopyACL":               {PrivilegeGrant, PrivilegeRevoke},
+	"MoveACL":               {PrivilegeGrant, PrivilegeRevoke},
+	"CloneACL":              {PrivilegeGrant, PrivilegeRevoke},
+	"RestoreACL":            {PrivilegeGrant, PrivilegeRevoke},
+	"ImportACL":             {PrivilegeGrant, PrivilegeRevoke},
+	"CreateWebhook":         {PrivilegeAdmin},
+	"ListWebhooks":          {PrivilegeAdmin},
+	"DeleteWebhook":         {PrivilegeAdmin},
+	"ListWebhookDeliveries": {PrivilegeAdmin},
+	"RetryWebhookDelivery":  {PrivilegeAdmin},
+	"ExportSnapshot":        {PrivilegeAdmin},
+	"RestoreSnapshot":       {PrivilegeAdmin},
+}
+
+// Checks if the caller has the privilege on the service in the admin service, either on the
+// service's key or the key of every service
+func hasAdminPrivilege(c *mgo.Collection, caller *Caller, service string, privilege string) (bool, error) {
+	for _, key := range []string{service, adminAllServices} {
+		err := ACL{}.Has(c, adminService, adminObject, key, caller.ID, []string{privilege})
+		if err == nil {
+			return true, nil
+		} else if err != mgo.ErrNotFound {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+/*
+Checks the admin service grants the caller the privileges the route needs on its service.
+Callers with the admin scope on every service, like the bootstrap key, manage everything,
+and are the only ones who may change the admin service itself.
+*/
+func authorizeAdmin(c *mgo.Collection, caller *Caller, route string, service string) *APIError {
+	privileges, ok := routePrivileges[route]
+	if adminService == "" || !ok || caller.allows(OperationAdmin, "") {
+		return nil
+	}
+
+	forbidden := &APIError{Code: ErrCodeForbidden, Message: "Caller may not manage this service"}
+	if service == adminService {
+		return forbidden
+	}
+
+	storageError := func(err error) *APIError {
+		log.Error("An error occurred checking admin privileges of %s on %s: %s", caller.ID, service, err)
+		return &APIError{Code: ErrCodeStorage, Message: "An error occurred checking privileges"}
+	}
+
+	admin, err := hasAdminPrivilege(c, caller, service, PrivilegeAdmin)
+	if err != nil {
+		return storageError(err)
+	} else if admin {
+		return nil
+	}
+
+	for _, privilege := range privileges {
+		allowed := false
+		if privilege != PrivilegeAdmin {
+			allowed, err = hasAdminPrivilege(c, caller, service, privilege)
+			if err != nil {
+				return storageError(err)
+			}
+		}
+		if !allowed {
+			log.Debug("Caller %s has no '%s' privilege on service '%s'", caller.ID, privilege, service)
+			return forbidden
+		}
+	}
+	return nil
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// The largest request body accepted, in bytes
+var maxBodyBytes int64 = 1 << 20
+
+// The most items accepted in a request body
+var maxBatchItems = 1000
+
+// When true, request body items with fields the endpoint doesn't know are rejected
+var strictRequests = false
+
+// Reads the request limits from the "requests" section of the config
+func configureRequests() {
+	requests, ok := Application.Config["requests"].(map[string]interface{})
+	if !ok {
+		log.Info("No request limits in config, defaulting to max body bytes: %d, "+
+			"max batch items: %d, strict: %t", maxBodyBytes, maxBatchItems, strictRequests)
+		return
+	}
+
+	if size, ok := requests["max_body_bytes"].(float64); ok {
+		maxBodyBytes = int64(size)
+	}
+	if items, ok := requests["max_batch_items"].(float64); ok {
+		maxBatchItems = int(items)
+	}
+	if strict, ok := requests["strict"].(bool); ok {
+		strictRequests = strict
+	}
+
+	log.Info("Using request limits max body bytes: %d, max batch items: %d, strict: %t",
+		maxBodyBytes, maxBatchItems, strictRequests)
+}
+
+// A request body item that can check its own fields once decoded
+type requestItem interface {
+	// Returns an error describing the first invalid field, or nil if the item is valid
+	validate() *APIError
+}
+
+// A missing field error
+func missingField(field string) *APIError {
+	return &APIError{Code: ErrCodeMissingField, Message: "Missing " + field + " from an item", Field: field}
+}
+
+// An invalid field error
+func invalidField(field string, message string) *APIError {
+	return &APIError{Code: ErrCodeInvalidField, Message: message, Field: field}
+}
+
+// Checks privilege names can be stored as document fields
+func validatePrivilegeName(name string) *APIError {
+	if name == "" || strings.Contains(name, ".") || strings.HasPrefix(name, "$") {
+		return invalidField("privileges", "Invalid privilege name '"+name+
+			"'. Names can't be empty, contain '.' or start with '$'")
+	}
+	return nil
+}
+
+// An item of a grant, deny, revoke or has request.  IfRevision optionally makes a write
+// conditional on the ACL's revision.
+type privilegeListItem struct {
+	Key        string   `json:"key"`
+	User       string   `json:"user"`
+	Privileges []string `json:"privileges"`
+	IfRevision int64    `json:"if_revision"`
+}
+
+func (i *privilegeListItem) validate() *APIError {
+	if i.Key == "" {
+		return missingField("key")
+	}
+	if i.User == "" {
+		return missingField("user")
+	}
+	if i.Privileges == nil {
+		return missingField("privileges")
+	}
+	for _, privilege := range i.Privileges {
+		if err := validatePrivilegeName(privilege); err != nil {
+			return err
+		}
+	}
+	if i.IfRevision < 0 {
+		return invalidField("if_revision", "if_revision must be a positive integer")
+	}
+	return nil
+}
+
+// An item of a set request.  IfRevision optionally makes the write conditional on the
+// ACL's revision.
+type privilegeMapItem struct {
+	Key        string                 `json:"key"`
+	User       string                 `json:"user"`
+	Privileges map[string]interface{} `json:"privileges"`
+	IfRevision int64                  `json:"if_revision"`
+}
+
+func (i *privilegeMapItem) validate() *APIError {
+	if i.Key == "" {
+		return missingField("key")
+	}
+	if i.User == "" {
+		return missingField("user")
+	}
+	if i.Privileges == nil {
+		return missingField("privileges")
+	}
+	for privilege := range i.Privileges {
+		if err := validatePrivilegeName(privilege); err != nil {
+			return err
+		}
+	}
+	if i.IfRevision < 0 {
+		return invalidField("if_revision", "if_revision must be a positive integer")
+	}
+	return nil
+}
+
+// An item of a get request
+type keyUserItem struct {
+	Key  string `json:"key"`
+	User string `json:"user"`
+}
+
+func (i *keyUserItem) validate() *APIError {
+	if i.Key == "" {
+		return missingField("key")
+	}
+	if i.User == "" {
+		return missingField("user")
+	}
+	return nil
+}
+
+// An item of a match request
+type matchItem struct {
+	User       string   `json:"user"`
+	Privileges []string `json:"privileges"`
+	Value      string   `json:"value"`
+	Limit      int      `json:"limit"`
+	Cursor     string   `json:"cursor"`
+}
+
+func (i *matchItem) validate() *APIError {
+	if i.User == "" {
+		return missingField("user")
+	}
+	if i.Privileges == nil {
+		return missingField("privileges")
+	}
+	for _, privilege := range i.Privileges {
+		if err := validatePrivilegeName(privilege); err != nil {
+			return err
+		}
+	}
+	if i.Limit < 0 {
+		return invalidField("limit", "limit must be a positive integer")
+	}
+	if i.Cursor != "" {
+		if _, err := ParseCursor(i.Cursor); err != nil {
+			return &APIError{Code: ErrCodeInvalidCursor, Message: "Invalid cursor in an item", Field: "cursor"}
+		}
+	}
+	return nil
+}
+
+// The page of keys the match item asks for
+func (i *matchItem) page() Page {
+	return Page{Limit: i.Limit, Cursor: i.Cursor}
+}
+
+// An item of a copy or move request
+type copyItem struct {
+	FromKey string `json:"from_key"`
+	ToKey   string `json:"to_key"`
+}
+
+func (i *copyItem) validate() *APIError {
+	if i.FromKey == "" {
+		return missingField("from_key")
+	}
+	if i.ToKey == "" {
+		return missingField("to_key")
+	}
+	if i.FromKey == i.ToKey {
+		return invalidField("to_key", "from_key and to_key must be different")
+	}
+	return nil
+}
+
+// An item of a clone request.  Key optionally limits the clone to a single key.
+type cloneItem struct {
+	FromUser string `json:"from_user"`
+	ToUser   string `json:"to_user"`
+	Key      string `json:"key"`
+}
+
+func (i *cloneItem) validate() *APIError {
+	if i.FromUser == "" {
+		return missingField("from_user")
+	}
+	if i.ToUser == "" {
+		return missingField("to_user")
+	}
+	if i.FromUser == i.ToUser {
+		return invalidField("to_user", "from_user and to_user must be different")
+	}
+	return nil
+}
+
+// An item of a restore request
+type restoreItem struct {
+	Key      string `json:"key"`
+	User     string `json:"user"`
+	Revision int64  `json:"revision"`
+}
+
+func (i *restoreItem) validate() *APIError {
+	if i.Key == "" {
+		return missingField("key")
+	}
+	if i.User == "" {
+		return missingField("user")
+	}
+	if i.Revision < 1 {
+		return missingField("revision")
+	}
+	return nil
+}
+
+// An item of a webhook registration.  Events and Object optionally limit the changes sent.
+type webhookItem struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Object string   `json:"object"`
+	Secret string   `json:"secret"`
+}
+
+func (i *webhookItem) validate() *APIError {
+	if i.URL == "" {
+		return missingField("url")
+	}
+	target, err := url.Parse(i.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return invalidField("url", "url must be an absolute http or https URL")
+	}
+	for _, event := range i.Events {
+		switch event {
+		case EventGrant, EventDeny, EventRevoke, EventSet, EventDelete:
+		default:
+			return invalidField("events", "Unknown event '"+event+
+				"'. Events must be grant, deny, revoke, set or delete")
+		}
+	}
+	if i.Secret == "" {
+		return missingField("secret")
+	}
+	return nil
+}
+
+// An item of an API key creation.  Services may be "*" for every service.
+type apiKeyItem struct {
+	Name     string   `json:"name"`
+	Services []string `json:"services"`
+	Scopes   []string `json:"scopes"`
+}
+
+func (i *apiKeyItem) validate() *APIError {
+	if i.Name == "" {
+		return missingField("name")
+	}
+	if len(i.Services) == 0 {
+		return missingField("services")
+	}
+	for _, service := range i.Services {
+		if service == "" {
+			return invalidField("services", "Services can't be empty")
+		}
+	}
+	if len(i.Scopes) == 0 {
+		return missingField("scopes")
+	}
+	for _, scope := range i.Scopes {
+		if _, ok := operationRank[scope]; !ok {
+			return invalidField("scopes", "Unknown scope '"+scope+"'. Scopes must be read, write or admin")
+		}
+	}
+	return nil
+}
+
+// Gets the body of the request as a list of raw items, enforcing the size limits
+func getBody(w http.ResponseWriter, r *http.Request) ([]json.RawMessage, bool) {
+
+	bodyBytes, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		if _, ok := err.(*http.MaxBytesError); ok {
+			log.Debug("Request body larger than %d bytes", maxBodyBytes)
+			writeError(w, 413, ErrCodeBodyTooLarge, "Request body is too large")
+			return nil, false
+		}
+		log.Error("An error occurred reading request body. Message: %s", err)
+		writeError(w, 400, ErrCodeUnreadableBody, "An error occurred reading request body")
+		return nil, false
+	}
+
+	log.Debug("Request Body: %s", bodyBytes)
+
+	var body []json.RawMessage
+	err = json.Unmarshal(bodyBytes, &body)
+	if err != nil {
+		log.Debug("An error occurrect parsing request body. Message: %s", err)
+		if _, ok := err.(*json.SyntaxError); ok {
+			writeError(w, 400, ErrCodeMalformedJSON, "Could not parse request body.  Seems to be malformed JSON.")
+		} else {
+			writeError(w, 400, ErrCodeInvalidBody, "Request body must be a list of objects")
+		}
+		return nil, false
+	}
+
+	if maxBatchItems > 0 && len(body) > maxBatchItems {
+		log.Debug("Request body has %d items, more than %d", len(body), maxBatchItems)
+		writeError(w, 400, ErrCodeTooManyItems, "Request body has too many items")
+		return nil, false
+	}
+
+	return body, true
+}
+
+// Decodes a raw request body item, describing what was wrong if it couldn't be decoded
+func decodeItem(raw json.RawMessage, item requestItem) *APIError {
+	if len(raw) == 0 || raw[0] != '{' {
+		return &APIError{Code: ErrCodeInvalidItem, Message: "Request body items must be objects"}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if strictRequests {
+		decoder.DisallowUnknownFields()
+	}
+
+	err := decoder.Decode(item)
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		return invalidField(typeErr.Field, typeErr.Field+" must be of type "+typeErr.Type.String())
+	} else if err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &APIError{Code: ErrCodeUnknownField, Message: "Unknown field " + field, Field: field}
+	} else if err != nil {
+		return &APIError{Code: ErrCodeInvalidItem, Message: err.Error()}
+	}
+
+	return item.validate()
+}
+
+// Decodes and validates the request body into items, which must point to a slice of a
+// requestItem type.  Returns false if the response was already written because the body
+// was invalid.
+func getItems(w http.ResponseWriter, r *http.Request, items interface{}) bool {
+	body, ok := getBody(w, r)
+	if !ok {
+		// Already responded in getBody call
+		return false
+	}
+
+	slice := reflect.ValueOf(items).Elem()
+	slice.Set(reflect.MakeSlice(slice.Type(), len(body), len(body)))
+
+	for idx, raw := range body {
+		item := slice.Index(idx).Addr().Interface().(requestItem)
+		if apiErr := decodeItem(raw, item); apiErr != nil {
+			log.Debug("Invalid item %d in request body: %s", idx, apiErr)
+			apiErr.Item = &idx
+			apiErr.Write(w, 400)
+			return false
+		}
+	}
+
+	log.Debug("Request Items: %+v", items)
+	return true
+}
@@ -7,3 +7,9 @@ go get github.com/gorilla/sessions
 go get github.com/gorilla/securecookie
 go get labix.org/v2/mgo
 go get github.com/johnnadratowski/droplet
+go get google.golang.org/grpc
+go get google.golang.org/protobuf
+
+# Only needed to regenerate authorizerpb with go generate, which also needs protoc
+go install google.golang.org/protobuf/cmd/protoc-gen-go@latest
+go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@latest
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	log "code.google.com/p/log4go"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// How far a signed request's timestamp may be from the server's clock
+var signatureWindow = 5 * time.Minute
+
+// The key signing keys are encrypted with before they're stored, from the config.  Without
+// it keys are created without a signing key, and signed requests are refused.
+var signingEncryptionKey []byte
+
+// What the signing key is derived from an API key's secret with
+const signingKeyLabel = "authorizer request signing"
+
+// The headers of a signed request
+const (
+	signatureKeyHeader       = "X-Authorizer-Key-Id"
+	signatureTimestampHeader = "X-Authorizer-Timestamp"
+	signatureHeader          = "X-Authorizer-Signature"
+)
+
+// The largest body of a signed request to a streaming route, which is spooled to disk to
+// check its signature before it's applied
+var maxSignedStreamBytes int64 = 256 << 20
+
+// Builds the string a request signature is made over: the method, the path with its query,
+// the Unix timestamp and the hex SHA-256 of the body, one per line
+func signatureString(method string, uri string, timestamp string, bodySum []byte) string {
+	return method + "\n" + uri + "\n" + timestamp + "\n" + hex.EncodeToString(bodySum)
+}
+
+// Derives the key requests are signed with from an API key's secret.  Unlike the stored hash
+// of the secret, it can't be worked out from what's in the database.
+func deriveSigningKey(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingKeyLabel))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Gets the AES-GCM cipher signing keys are encrypted with
+func signingCipher() (cipher.AEAD, error) {
+	if len(signingEncryptionKey) == 0 {
+		return nil, errors.New("No signing_encryption_key is configured")
+	}
+	block, err := aes.NewCipher(signingEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypts a signing key for storing.  Returns an empty string when no encryption key is
+// configured, so the key can't be used to sign requests.
+func sealSigningKey(key string) (string, error) {
+	if len(signingEncryptionKey) == 0 {
+		return "", nil
+	}
+	aead, err := signingCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(aead.Seal(nonce, nonce, []byte(key), nil)), nil
+}
+
+// Decrypts a stored signing key
+func openSigningKey(sealed string) (string, error) {
+	aead, err := signingCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := hex.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("Stored signing key is too short")
+	}
+	key, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	return string(key), err
+}
+
+// Signs a request with the signing key derived from an API key's secret
+func signRequest(key string, method string, uri string, timestamp string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return signRequestSum(key, method, uri, timestamp, sum[:])
+}
+
+// Signs a request given the SHA-256 of its body
+func signRequestSum(key string, method string, uri string, timestamp string, bodySum []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(signatureString(method, uri, timestamp, bodySum)))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Checks if the signature was made with a stored, encrypted signing key
+func signedWith(sealed string, signature string, method string, uri string, timestamp string, bodySum []byte) bool {
+	if sealed == "" {
+		return false
+	}
+	key, err := openSigningKey(sealed)
+	if err != nil {
+		log.Error("An error occurred decrypting signing key. Message: %s", err)
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(signRequestSum(key, method, uri, timestamp, bodySum)))
+}
+
+// Checks if the signature was made with the key's current secret, or its previous one
+// during a rotation
+func (k APIKey) verifies(signature string, method string, uri string, timestamp string, bodySum []byte,
+	now time.Time) bool {
+	if signedWith(k.SigningKey, signature, method, uri, timestamp, bodySum) {
+		return true
+	}
+	return k.PreviousExpires != nil && now.Before(*k.PreviousExpires) &&
+		signedWith(k.PreviousSigningKey, signature, method, uri, timestamp, bodySum)
+}
+
+// Reads the body of a request into memory, to verify its signature or fingerprint it,
+// leaving it to be read again by the handler
+func bufferBody(r *http.Request) ([]byte, *APIError) {
+	if r.Body == nil {
+		return []byte{}, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		log.Error("An error occurred buffering request body. Message: %s", err)
+		return nil, &APIError{Code: ErrCodeUnreadableBody, Message: "An error occurred reading request body"}
+	}
+	if int64(len(body)) > maxBodyBytes {
+		return nil, &APIError{Code: ErrCodeBodyTooLarge, Message: "Request body is too large"}
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// A request body spooled to a temporary file, which is removed when it's closed
+type spooledBody struct {
+	*os.File
+}
+
+func (b spooledBody) Close() error {
+	b.File.Close()
+	return os.Remove(b.Name())
+}
+
+/*
+Copies the body of a request to a streaming route to a temporary file, hashing it on the
+way, and leaves the file to be read by the handler.  Returns the SHA-256 of the body.
+Streamed bodies can be far larger than max_body_bytes, so this checks a signed one before
+any of it is applied without holding it in memory.
+*/
+func spoolBody(r *http.Request) ([]byte, *APIError) {
+	sum := sha256.New()
+	if r.Body == nil {
+		return sum.Sum(nil), nil
+	}
+
+	file, err := ioutil.TempFile("", "authorizer-body")
+	if err != nil {
+		log.Error("An error occurred creating a file to spool request body to. Message: %s", err)
+		return nil, &APIError{Code: ErrCodeInternal, Message: "An error occurred reading request body"}
+	}
+	spooled := spooledBody{file}
+
+	size, err := io.Copy(io.MultiWriter(file, sum), io.LimitReader(r.Body, maxSignedStreamBytes+1))
+	if err == nil {
+		_, err = file.Seek(0, 0)
+	}
+	if err != nil {
+		spooled.Close()
+		log.Error("An error occurred spooling request body. Message: %s", err)
+		return nil, &APIError{Code: ErrCodeUnreadableBody, Message: "An error occurred reading request body"}
+	}
+	if size > maxSignedStreamBytes {
+		spooled.Close()
+		return nil, &APIError{Code: ErrCodeBodyTooLarge, Message: "Request body is too large"}
+	}
+
+	r.Body = spooled
+	return sum.Sum(nil), nil
+}
+
+// Authenticates with a request signed with an API key.  Signatures only hold for the
+// signature window around their timestamp, so captured requests can't be replayed later.
+func authenticateSignature(r *http.Request, c *mgo.Collection) (*Caller, *APIError) {
+	signature := r.Header.Get(signatureHeader)
+	if signature == "" {
+		return nil, nil
+	}
+
+	if len(signingEncryptionKey) == 0 {
+		return nil, &APIError{Code: ErrCodeUnauthenticated, Message: "Signed requests aren't enabled on this server"}
+	}
+
+	invalid := &APIError{Code: ErrCodeUnauthenticated, Message: "Invalid request signature"}
+	keyID := r.Header.Get(signatureKeyHeader)
+	if !bson.IsObjectIdHex(keyID) {
+		return nil, invalid
+	}
+	id := bson.ObjectIdHex(keyID)
+
+	timestamp := r.Header.Get(signatureTimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	now := time.Now()
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew > signatureWindow || skew < -signatureWindow {
+		log.Debug("Signed request timestamp %s is outside the signature window", timestamp)
+		return nil, &APIError{Code: ErrCodeUnauthenticated, Message: "Request signature has expired"}
+	}
+
+	// Streamed bodies are spooled rather than held, so they aren't limited to max_body_bytes
+	var bodySum []byte
+	if isStreamingRoute(r) {
+		var apiErr *APIError
+		bodySum, apiErr = spoolBody(r)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+	} else {
+		body, apiErr := bufferBody(r)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		sum := sha256.Sum256(body)
+		bodySum = sum[:]
+	}
+
+	stored, err := APIKey{}.Get(c, id)
+	if err == mgo.ErrNotFound {
+		return nil, invalid
+	} else if err != nil {
+		log.Error("An error occurred getting API key %s: %s", id.Hex(), err)
+		return nil, &APIError{Code: ErrCodeStorage, Message: "An error occurred checking request signature"}
+	}
+	if stored.Revoked != nil || !stored.verifies(signature, r.Method, r.URL.RequestURI(), timestamp, bodySum, now) {
+		return nil, invalid
+	}
+
+	return &Caller{ID: callerKeyPrefix + stored.ID.Hex(), Method: "signature", Services: stored.Services, Scopes: stored.Scopes}, nil
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	log "code.google.com/p/log4go"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"os"
+	"reflect"
+	"time"
+)
+
+// The format named in the header of every snapshot
+const snapshotFormat = "authorizer-snapshot"
+
+// The version of the snapshot format written.  Snapshots of later versions aren't restored.
+const snapshotVersion = 1
+
+// The longest line of a snapshot accepted
+const maxSnapshotLineBytes = 16 << 20
+
+// The ways a snapshot can be restored
+const (
+	// Writes the snapshot's ACLs, leaving others as they are
+	SnapshotMerge = "merge"
+	// Writes the snapshot's ACLs and deletes the others in its scope
+	SnapshotReplace = "replace"
+)
+
+/*
+The first line of a snapshot.  Service and Object are the scope exported, and are empty
+when the snapshot holds every service or every object of its service.
+*/
+type SnapshotHeader struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Service string    `json:"service,omitempty"`
+	Object  string    `json:"object,omitempty"`
+}
+
+// A line of a snapshot after the header: an ACL, or the end of the snapshot with the
+// number of ACLs before it, so a truncated snapshot can be told apart
+type snapshotLine struct {
+	Service    string                 `json:"service,omitempty"`
+	Object     string                 `json:"object,omitempty"`
+	Key        string                 `json:"key,omitempty"`
+	User       string                 `json:"user,omitempty"`
+	Privileges map[string]interface{} `json:"privileges,omitempty"`
+	End        bool                   `json:"end,omitempty"`
+	Count      int                    `json:"count,omitempty"`
+}
+
+// Selects the ACLs of a snapshot's scope
+func snapshotSelector(service string, object string) bson.M {
+	selector := bson.M{}
+	if service != "" {
+		selector["service"] = service
+	}
+	if object != "" {
+		selector["object"] = object
+	}
+	return selector
+}
+
+/*
+Writes a gzipped snapshot of the ACLs of the scope: every ACL when service is empty, the
+ACLs of a service, or of one of its objects.  The snapshot is a JSON header line, a line per
+ACL in order, and an end line counting them.  Returns the number of ACLs written.
+*/
+func writeSnapshot(c *mgo.Collection, w io.Writer, service string, object string) (int, error) {
+	zipped := gzip.NewWriter(w)
+	encoder := json.NewEncoder(zipped)
+
+	header := SnapshotHeader{Format: snapshotFormat, Version: snapshotVersion, Created: time.Now().UTC(),
+		Service: service, Object: object}
+	err := encoder.Encode(header)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	iter := c.Find(snapshotSelector(service, object)).Sort("service", "object", "key", "user").Iter()
+	acl := ACL{}
+	for iter.Next(&acl) {
+		err = encoder.Encode(snapshotLine{Service: acl.Service, Object: acl.Object, Key: acl.Key, User: acl.User,
+			Privileges: acl.Privileges})
+		if err != nil {
+			iter.Close()
+			return count, err
+		}
+		count++
+		acl = ACL{}
+	}
+	if err = iter.Close(); err != nil {
+		return count, err
+	}
+
+	err = encoder.Encode(snapshotLine{End: true, Count: count})
+	if err != nil {
+		return count, err
+	}
+	return count, zipped.Close()
+}
+
+// The result of restoring a snapshot.  ACLs counts those in the snapshot, which were
+// either written or already matched it, and Deleted the ACLs replace removed.  Unrecorded
+// counts the writes and deletes made but not recorded in the audit, event or history logs.
+type SnapshotRestore struct {
+	Mode       string `json:"mode"`
+	Service    string `json:"service,omitempty"`
+	Object     string `json:"object,omitempty"`
+	ACLs       int    `json:"acls"`
+	Written    int    `json:"written"`
+	Unchanged  int    `json:"unchanged"`
+	Deleted    int    `json:"deleted"`
+	Unrecorded int    `json:"unrecorded,omitempty"`
+}
+
+// An ACL of a snapshot, identifying it for replace
+type snapshotACL struct {
+	service, object, key, user string
+}
+
+// Reads a snapshot, gzipped or not
+func snapshotScanner(r io.Reader) (*bufio.Scanner, error) {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(2)
+
+	var reader io.Reader = buffered
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zipped, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		reader = zipped
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxSnapshotLineBytes)
+	return scanner, nil
+}
+
+/*
+Reads and checks a whole snapshot, copying its ACLs to the spool as JSON lines.  Returns
+its header and the number of ACLs, or an APIError if it's invalid or truncated.  When
+service isn't empty, the snapshot must be of that service.
+*/
+func readSnapshot(r io.Reader, service string, spool io.Writer) (SnapshotHeader, int, *APIError) {
+	header := SnapshotHeader{}
+	count := 0
+	invalid := func(message string) (SnapshotHeader, int, *APIError) {
+		return header, count, &APIError{Code: ErrCodeInvalidBody, Message: message}
+	}
+
+	scanner, err := snapshotScanner(r)
+	if err != nil {
+		return invalid("Snapshot isn't valid gzip: " + err.Error())
+	}
+
+	if !scanner.Scan() {
+		if err = scanner.Err(); err != nil {
+			return invalid("An error occurred reading snapshot: " + err.Error())
+		}
+		return invalid("Snapshot is empty")
+	}
+	if json.Unmarshal(scanner.Bytes(), &header) != nil || header.Format != snapshotFormat {
+		return invalid("Snapshot doesn't start with an " + snapshotFormat + " header")
+	}
+	if header.Version < 1 || header.Version > snapshotVersion {
+		return invalid(fmt.Sprintf("Snapshot version %d isn't supported, the latest is %d", header.Version,
+			snapshotVersion))
+	}
+	if header.Object != "" && header.Service == "" {
+		return invalid("Snapshot header has an object without a service")
+	}
+	if service != "" && header.Service != service {
+		return header, count, &APIError{Code: ErrCodeForbidden, Message: "Snapshot isn't of service '" + service + "'"}
+	}
+
+	encoder := json.NewEncoder(spool)
+	ended := false
+	for !ended && scanner.Scan() {
+		line := snapshotLine{}
+		if json.Unmarshal(scanner.Bytes(), &line) != nil {
+			return invalid(fmt.Sprintf("Line %d of snapshot isn't a JSON object", count+2))
+		}
+		if line.End {
+			if line.Count != count {
+				return invalid(fmt.Sprintf("Snapshot end counts %d ACLs, but it holds %d", line.Count, count))
+			}
+			ended = true
+			continue
+		}
+
+		if line.Service == "" || line.Object == "" || line.Key == "" || line.User == "" {
+			return invalid(fmt.Sprintf("ACL %d of snapshot is missing its service, object, key or user", count+1))
+		}
+		if (header.Service != "" && line.Service != header.Service) || (header.Object != "" && line.Object != header.Object) {
+			return invalid(fmt.Sprintf("ACL %d of snapshot is outside its scope", count+1))
+		}
+		for privilege := range line.Privileges {
+			if apiErr := validatePrivilegeName(privilege); apiErr != nil {
+				apiErr.Message = fmt.Sprintf("ACL %d of snapshot: %s", count+1, apiErr.Message)
+				return header, count, apiErr
+			}
+		}
+		if err = encoder.Encode(line); err != nil {
+			return invalid("An error occurred spooling snapshot: " + err.Error())
+		}
+		count++
+	}
+	if err = scanner.Err(); err != nil {
+		return invalid("An error occurred reading snapshot: " + err.Error())
+	}
+	if !ended {
+		return invalid(fmt.Sprintf("Snapshot is truncated after %d ACLs", count))
+	}
+	return header, count, nil
+}
+
+/*
+Restores a snapshot.  Merge writes the snapshot's ACLs over the current ones, and replace
+also deletes the ACLs in the snapshot's scope that it doesn't hold.  ACLs already matching
+the snapshot aren't written again.  When service isn't empty, only a snapshot of that
+service may be restored.
+
+The whole snapshot is read and checked, spooled to a temporary file, before anything is
+changed, so an invalid or truncated snapshot returns an APIError without restoring any of
+it.  A storage error while writing can still stop the restore part way.
+*/
+func restoreSnapshot(c *mgo.Collection, actor *Actor, r io.Reader, mode string,
+	service string) (SnapshotRestore, *APIError, error) {
+	result := SnapshotRestore{Mode: mode}
+	if mode != SnapshotMerge && mode != SnapshotReplace {
+		return result, &APIError{Code: ErrCodeInvalidParameter, Message: "mode must be merge or replace"}, nil
+	}
+
+	spool, err := ioutil.TempFile("", "authorizer-snapshot")
+	if err != nil {
+		return result, nil, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	header, count, apiErr := readSnapshot(r, service, spool)
+	result.Service, result.Object = header.Service, header.Object
+	if apiErr != nil {
+		result.ACLs = count
+		return result, apiErr, nil
+	}
+	if _, err = spool.Seek(0, 0); err != nil {
+		return result, nil, err
+	}
+
+	restored := map[snapshotACL]bool{}
+	decoder := json.NewDecoder(bufio.NewReader(spool))
+	for result.ACLs < count {
+		line := snapshotLine{}
+		if err = decoder.Decode(&line); err != nil {
+			return result, nil, err
+		}
+		if line.Privileges == nil {
+			line.Privileges = map[string]interface{}{}
+		}
+		result.ACLs++
+
+		id := snapshotACL{line.Service, line.Object, line.Key, line.User}
+		if mode == SnapshotReplace {
+			restored[id] = true
+		}
+
+		existing, err := ACL{}.Get(c, line.Service, line.Object, line.Key, line.User)
+		if err != nil && err != mgo.ErrNotFound {
+			return result, nil, err
+		}
+		if err == nil && (reflect.DeepEqual(existing.Privileges, line.Privileges) ||
+			len(existing.Privileges)+len(line.Privileges) == 0) {
+			result.Unchanged++
+			continue
+		}
+
+		_, err = ACL{Actor: actor}.Set(c, line.Service, line.Object, line.Key, line.User, line.Privileges)
+		if err == ErrChangeNotRecorded {
+			result.Unrecorded++
+		} else if err != nil {
+			return result, nil, err
+		}
+		result.Written++
+	}
+
+	if mode != SnapshotReplace {
+		return result, nil, nil
+	}
+
+	// Deletes are found before they're made, so the iteration doesn't see its own changes
+	removed := []snapshotACL{}
+	iter := c.Find(snapshotSelector(header.Service, header.Object)).Iter()
+	acl := ACL{}
+	for iter.Next(&acl) {
+		id := snapshotACL{acl.Service, acl.Object, acl.Key, acl.User}
+		if !restored[id] {
+			removed = append(removed, id)
+		}
+		acl = ACL{}
+	}
+	if err = iter.Close(); err != nil {
+		return result, nil, err
+	}
+
+	for _, id := range removed {
+		err = ACL{Actor: actor}.Delete(c, id.service, id.object, id.key, id.user)
+		if err == ErrChangeNotRecorded {
+			result.Unrecorded++
+		} else if err != nil && err != mgo.ErrNotFound {
+			return result, nil, err
+		}
+		result.Deleted++
+	}
+	return result, nil, nil
+}
+
+// This is a URL handler that exports a snapshot of the ACLs of the whole store, a service
+// or an object
+func exportSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+	service := r.URL.Query().Get("service")
+	object := r.URL.Query().Get("object")
+	if object != "" && service == "" {
+		writeError(w, 400, ErrCodeInvalidParameter, "object requires a service")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="acls.jsonl.gz"`)
+	count, err := writeSnapshot(c, w, service, object)
+	if err != nil {
+		// The response has started, so it's left truncated, which restoring detects
+		log.Error("An error occurred exporting snapshot after %d ACLs. URL: %s\nMessage: %s", count,
+			r.URL.RequestURI(), err)
+		return
+	}
+	log.Info("Exported snapshot of %d ACLs", count)
+}
+
+// This is a URL handler that restores a snapshot in the request body
+func restoreSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	c, _, _ := getRequestData(r)
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = SnapshotMerge
+	}
+
+	result, apiErr, err := restoreSnapshot(c, requestActor(r), r.Body, mode, r.URL.Query().Get("service"))
+	if apiErr != nil {
+		log.Debug("Invalid snapshot after %d ACLs, nothing was restored: %s", result.ACLs, apiErr)
+		status := 400
+		if apiErr.Code == ErrCodeForbidden {
+			status = 403
+		}
+		apiErr.Write(w, status)
+		return
+	} else if err != nil {
+		log.Error("An error occurred restoring snapshot after %d ACLs. URL: %s\nMessage: %s", result.ACLs,
+			r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred restoring snapshot")
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred restoring snapshot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+/*
+Exports a snapshot from the command line, to a file or stdout:
+
+	authorizer export [-service name [-object name]] [-o acls.jsonl.gz]
+*/
+func exportCommand(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	service := flags.String("service", "", "Only export the ACLs of this service")
+	object := flags.String("object", "", "Only export the ACLs of this object of the service")
+	output := flags.String("o", "-", "File to write the snapshot to, or - for stdout")
+	flags.Parse(args)
+
+	if *object != "" && *service == "" {
+		fmt.Fprintln(os.Stderr, "-object requires -service")
+		os.Exit(2)
+	}
+
+	session, _, c, err := getMongo()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error connecting to database:", err)
+		os.Exit(2)
+	}
+	defer session.Close()
+
+	out := os.Stdout
+	if *output != "-" {
+		out, err = os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error creating snapshot:", err)
+			session.Close()
+			os.Exit(2)
+		}
+		defer out.Close()
+	}
+
+	count, err := writeSnapshot(c, out, *service, *object)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error exporting snapshot:", err)
+		session.Close()
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d ACLs\n", count)
+}
+
+/*
+Restores a snapshot from a file, or stdin when it's "-", from the command line, printing
+the result:
+
+	authorizer restore [-mode merge|replace] acls.jsonl.gz
+*/
+func restoreCommand(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	mode := flags.String("mode", SnapshotMerge, "merge to keep ACLs missing from the snapshot, replace to delete them")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: authorizer restore [-mode merge|replace] <file>")
+		os.Exit(2)
+	}
+
+	input := os.Stdin
+	if flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error opening snapshot:", err)
+			os.Exit(2)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	session, _, c, err := getMongo()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error connecting to database:", err)
+		os.Exit(2)
+	}
+	defer session.Close()
+
+	result, apiErr, err := restoreSnapshot(c, &Actor{Caller: "restore"}, input, *mode, "")
+	data, _ := json.MarshalIndent(result, "", "    ")
+	fmt.Println(string(data))
+	if apiErr != nil {
+		err = apiErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error restoring snapshot:", err)
+		session.Close()
+		os.Exit(1)
+	}
+}
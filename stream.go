@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"labix.org/v2/mgo"
+	"net/http"
+	"strings"
+)
+
+// The number of items written to a streamed response between flushes
+const streamFlushCount = 100
+
+// The content type of newline delimited JSON responses
+const ndjsonContentType = "application/x-ndjson"
+
+// Checks if the request's Accept header lists the media type
+func accepts(r *http.Request, mediaType string) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.Split(accept, ";")[0]) == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the request asked for a newline delimited JSON response
+func wantsNDJSON(r *http.Request) bool {
+	return accepts(r, ndjsonContentType)
+}
+
+/*
+Writes items to a response as they are read, either as the elements of a JSON array or
+as newline delimited JSON, flushing periodically so large results are never held in
+memory.  Nothing is written until the first item, so an error response can still be sent
+while Count is 0.
+*/
+type streamWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	ndjson  bool
+	Count   int
+}
+
+// Creates a stream writer, using newline delimited JSON if the request accepts it
+func newStreamWriter(w http.ResponseWriter, r *http.Request) *streamWriter {
+	flusher, _ := w.(http.Flusher)
+	return &streamWriter{w: w, flusher: flusher, ndjson: wantsNDJSON(r)}
+}
+
+// Writes the response headers and opening of the stream
+func (s *streamWriter) start() {
+	if s.ndjson {
+		s.w.Header().Set("Content-Type", ndjsonContentType)
+	} else {
+		s.w.Header().Set("Content-Type", "application/json")
+		s.w.Write([]byte("["))
+	}
+}
+
+// Writes an item to the stream
+func (s *streamWriter) Write(item interface{}) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	if s.Count == 0 {
+		s.start()
+	} else if !s.ndjson {
+		s.w.Write([]byte(","))
+	}
+
+	if s.ndjson {
+		data = append(data, '\n')
+	}
+
+	_, err = s.w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	s.Count++
+	if s.flusher != nil && s.Count%streamFlushCount == 0 {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// Finishes the stream
+func (s *streamWriter) Close() {
+	if s.Count == 0 {
+		s.start()
+	}
+	if !s.ndjson {
+		s.w.Write([]byte("]"))
+	}
+}
+
+// Ends a stream that failed.  If nothing was written yet an error response is sent.
+// Otherwise a newline delimited stream ends with an {"error"} line, and a JSON array is
+// left without its closing bracket, so the client can't mistake it for a whole one.
+func (s *streamWriter) Abort() {
+	apiErr := APIError{Code: ErrCodeStorage, Message: "An error occurred getting privilege list"}
+	if s.Count == 0 {
+		apiErr.Write(s.w, 500)
+		return
+	}
+	if s.ndjson {
+		data, _ := json.Marshal(map[string]interface{}{"error": apiErr})
+		s.w.Write(append(data, '\n'))
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
+// Streams the ACLs from the iterator to the response.  With a limit, at most that many ACLs
+// are written, and a newline delimited stream ends with a {"next_cursor"} line if the
+// iterator holds more.  Errors are returned for logging after aborting the stream.
+func writeACLStream(w http.ResponseWriter, r *http.Request, iter *mgo.Iter, limit int) error {
+	stream := newStreamWriter(w, r)
+
+	var err error
+	next := ""
+	last := ACL{}
+	acl := ACL{}
+	for iter.Next(&acl) {
+		if limit > 0 && stream.Count == limit {
+			next = Cursor{Key: last.Key, User: last.User}.String()
+			break
+		}
+
+		err = stream.Write(acl)
+		if err != nil {
+			break
+		}
+
+		last = acl
+		acl = ACL{}
+	}
+
+	if closeErr := iter.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		stream.Abort()
+		return err
+	}
+
+	if next != "" && stream.ndjson {
+		stream.Write(map[string]interface{}{"next_cursor": next})
+	}
+	stream.Close()
+	return nil
+}
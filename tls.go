@@ -0,0 +1,170 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/gorilla/context"
+	"io/ioutil"
+	"labix.org/v2/mgo"
+	"net"
+	"net/http"
+	"time"
+)
+
+// The TLS settings the server terminates TLS with, or nil when it serves plain HTTP
+var tlsConfig *tls.Config
+
+// The client certificate policies of the client_auth setting
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":    tls.NoClientCert,
+	"request": tls.VerifyClientCertIfGiven,
+	"require": tls.RequireAndVerifyClientCert,
+}
+
+// Reads the TLS settings from the "tls" object of the "server" section of the config
+func configureTLS() error {
+	server, _ := Application.Config["server"].(map[string]interface{})
+	config, ok := server["tls"].(map[string]interface{})
+	if !ok {
+		log.Info("No TLS settings in config, serving plain HTTP")
+		return nil
+	}
+
+	certFile, _ := config["cert_file"].(string)
+	keyFile, _ := config["key_file"].(string)
+	if certFile == "" || keyFile == "" {
+		log.Info("TLS cert or key file not specified, serving plain HTTP")
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		log.Error("An error occurred loading TLS cert '%s' and key '%s': %s", certFile, keyFile, err)
+		return err
+	}
+	result := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+	caFile, _ := config["client_ca_file"].(string)
+	clientAuth, _ := config["client_auth"].(string)
+	if clientAuth == "" {
+		clientAuth = "none"
+		if caFile != "" {
+			clientAuth = "request"
+		}
+	}
+	result.ClientAuth, ok = clientAuthTypes[clientAuth]
+	if !ok {
+		return fmt.Errorf("TLS client_auth must be none, request or require, got '%s'", clientAuth)
+	}
+
+	if caFile != "" {
+		data, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			log.Error("An error occurred reading TLS client CA bundle '%s': %s", caFile, err)
+			return err
+		}
+		result.ClientCAs = x509.NewCertPool()
+		if !result.ClientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("TLS client CA bundle '%s' has no certificates", caFile)
+		}
+	} else if result.ClientAuth != tls.NoClientCert {
+		return fmt.Errorf("TLS client_auth '%s' needs a client_ca_file", clientAuth)
+	}
+
+	tlsConfig = result
+	log.Info("Using TLS cert '%s', client CA bundle '%s', client auth '%s'", certFile, caFile, clientAuth)
+	return nil
+}
+
+// Serves the API over TLS with the server settings droplet uses for plain HTTP
+func startTLS() error {
+	server, _ := Application.Config["server"].(map[string]interface{})
+	host, _ := server["host"].(string)
+	port, _ := server["port"].(string)
+
+	httpServer := &http.Server{
+		Addr:      net.JoinHostPort(host, port),
+		Handler:   context.ClearHandler(http.HandlerFunc(Handler)),
+		TLSConfig: tlsConfig,
+	}
+	if seconds, ok := server["read_timeout"].(float64); ok {
+		httpServer.ReadTimeout = time.Duration(seconds) * time.Second
+	}
+	if seconds, ok := server["write_timeout"].(float64); ok {
+		httpServer.WriteTimeout = time.Duration(seconds) * time.Second
+	}
+	if bytes, ok := server["max_header_bytes"].(float64); ok {
+		httpServer.MaxHeaderBytes = int(bytes)
+	}
+
+	log.Info("Serving HTTPS on %s", httpServer.Addr)
+	return httpServer.ListenAndServeTLS("", "")
+}
+
+// The services and scopes of each client certificate identity
+var clientCertIdentities = map[string]Caller{}
+
+// Reads the client certificate identities from the "client_certs" object of the "auth"
+// section of the config
+func configureClientCerts(auth map[string]interface{}) {
+	certs, ok := auth["client_certs"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for identity, value := range certs {
+		config, _ := value.(map[string]interface{})
+		caller := Caller{ID: callerCertPrefix + identity, Method: "client_cert"}
+		caller.Services = claimStrings(config, "services")
+		caller.Scopes = claimStrings(config, "scopes")
+		clientCertIdentities[identity] = caller
+		log.Info("Using client certificate identity '%s' with services %v and scopes %v",
+			identity, caller.Services, caller.Scopes)
+	}
+}
+
+// Gets the identities of a verified client certificate: its DNS, URI and email SANs, then
+// its subject common name
+func clientCertNames(r *http.Request) []string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	names := []string{}
+	names = append(names, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
+
+/*
+Gets the caller of a verified client certificate, or nil when there isn't one.  A
+certificate whose identity is configured gets its services and scopes.  Others are still
+known by their first identity, for the audit log and admin checks, but may use nothing.
+*/
+func clientCertCaller(r *http.Request) *Caller {
+	names := clientCertNames(r)
+	for _, name := range names {
+		if caller, ok := clientCertIdentities[name]; ok {
+			return &caller
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return &Caller{ID: callerCertPrefix + names[0], Method: "client_cert", Services: []string{}, Scopes: []string{}}
+}
+
+// Authenticates with a verified client certificate.  It's tried last, so a certificate that
+// only sets up the connection doesn't hide the caller's other credentials.
+func authenticateClientCert(r *http.Request, c *mgo.Collection) (*Caller, *APIError) {
+	return clientCertCaller(r), nil
+}
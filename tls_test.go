@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Creates a certificate signed by the parent, or self-signed when the parent is nil
+func testCert(t *testing.T, template *x509.Certificate, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestClientCertificateIdentity(t *testing.T) {
+	ca, caKey, caPEM, _ := testCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	_, _, serverPEM, serverKeyPEM := testCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "authorizer"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	_, _, clientPEM, clientKeyPEM := testCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "billing"},
+		DNSNames:    []string{"billing.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string][]byte{"ca.pem": caPEM, "server.pem": serverPEM, "server-key.pem": serverKeyPEM}
+	for name, data := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, current, identities := Application.Config, tlsConfig, clientCertIdentities
+	defer func() {
+		Application.Config, tlsConfig, clientCertIdentities = config, current, identities
+	}()
+	Application.Config = map[string]interface{}{"server": map[string]interface{}{"tls": map[string]interface{}{
+		"cert_file":      filepath.Join(dir, "server.pem"),
+		"key_file":       filepath.Join(dir, "server-key.pem"),
+		"client_ca_file": filepath.Join(dir, "ca.pem"),
+		"client_auth":    "require",
+	}}}
+	err = configureTLS()
+	if err != nil {
+		t.Fatal("Error configuring TLS: ", err)
+	}
+	if tlsConfig == nil || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatal("Incorrect TLS config: ", tlsConfig)
+	}
+
+	clientCertIdentities = map[string]Caller{}
+	configureClientCerts(map[string]interface{}{"client_certs": map[string]interface{}{
+		"billing.internal": map[string]interface{}{"services": []interface{}{"billing"}, "scopes": []interface{}{"write"}},
+	}})
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ := authenticateClientCert(r, nil)
+		if caller == nil {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(caller.ID + " " + caller.Method + " " + strings.Join(caller.Services, ",")))
+	}))
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+
+	res, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal("Error calling with client certificate: ", err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || string(body) != "cert:billing.internal client_cert billing" {
+		t.Fatal("Incorrect caller from client certificate: ", res.Status, string(body))
+	}
+
+	clientCertIdentities = map[string]Caller{}
+	res, err = client.Get(ts.URL)
+	if err != nil {
+		t.Fatal("Error calling with unconfigured client certificate: ", err)
+	}
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || string(body) != "cert:billing.internal client_cert " {
+		t.Fatal("Incorrect caller from unconfigured client certificate: ", res.Status, string(body))
+	}
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	res, err = anonymous.Get(ts.URL)
+	if err == nil {
+		res.Body.Close()
+		t.Fatal("Expected a call without a client certificate to be refused")
+	}
+}
@@ -4,19 +4,6 @@ import (
 	"strings"
 )
 
-// Convert []interface to []string
-func interfaceSliceToStr(toCopy []interface{}) ([]string, bool) {
-	strs := make([]string, len(toCopy))
-	for i, _ := range toCopy {
-		str, ok := toCopy[i].(string)
-		if !ok {
-			return nil, false
-		}
-		strs[i] = str
-	}
-	return strs, true
-}
-
 // See if the key is in the ACL list
 func itemInAclList(key string, user string, list []ACL) bool {
 	for _, b := range list {
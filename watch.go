@@ -0,0 +1,221 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"context"
+	"encoding/json"
+	"fmt"
+	"labix.org/v2/mgo"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// How long a long-poll watch waits for events when the request doesn't say
+const defaultWatchTimeout = 30 * time.Second
+
+// The longest a long-poll watch may wait.  It stays under the server's write timeout.
+const maxWatchTimeout = 50 * time.Second
+
+// How often watchers read the event log without being notified, to pick up events
+// recorded by other processes
+const watchPollInterval = time.Second
+
+// How often an event stream sends a comment to keep idle connections open
+const watchHeartbeatInterval = 15 * time.Second
+
+// The most events read from the log at once
+const watchBatchSize = 100
+
+// The content type of Server-Sent Events responses
+const eventStreamContentType = "text/event-stream"
+
+// Checks if the request asked for a Server-Sent Events response
+func wantsEventStream(r *http.Request) bool {
+	return accepts(r, eventStreamContentType)
+}
+
+// This is a URL handler that sends the changes made to the ACLs of an object, either as a
+// Server-Sent Events stream or as a long poll that returns once there are events
+func watchPrivilegesHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, object := getRequestData(r)
+
+	query := r.URL.Query()
+	key := query.Get("key")
+
+	// Event streams resume from the Last-Event-ID header when they reconnect
+	since := query.Get("since")
+	if since == "" {
+		since = r.Header.Get("Last-Event-ID")
+	}
+
+	var seq int64
+	var err error
+	if since == "" {
+		seq, err = latestEventSeq(c, service, object)
+		if err != nil {
+			log.Error("An error occurred getting the latest event. URL: %s\nMessage: %s",
+				r.URL.RequestURI(), err)
+			writeError(w, 500, ErrCodeStorage, "An error occurred watching privileges")
+			return
+		}
+	} else {
+		seq, err = strconv.ParseInt(since, 10, 64)
+		if err != nil || seq < 0 {
+			log.Debug("Invalid watch sequence token: %s", since)
+			writeError(w, 400, ErrCodeInvalidParameter, "since must be a sequence token returned by watch")
+			return
+		}
+	}
+
+	timeout := defaultWatchTimeout
+	if seconds := query.Get("timeout"); seconds != "" {
+		value, err := strconv.Atoi(seconds)
+		if err != nil || value < 1 || time.Duration(value)*time.Second > maxWatchTimeout {
+			log.Debug("Invalid watch timeout: %s", seconds)
+			writeError(w, 400, ErrCodeInvalidParameter,
+				fmt.Sprintf("timeout must be between 1 and %d seconds", int(maxWatchTimeout/time.Second)))
+			return
+		}
+		timeout = time.Duration(value) * time.Second
+	}
+
+	if wantsEventStream(r) {
+		err = streamEvents(w, r, c, service, object, key, seq)
+	} else {
+		err = pollEvents(w, r, c, service, object, key, seq, timeout)
+	}
+	if err != nil {
+		log.Error("An error occurred watching privileges. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+	}
+}
+
+// Responds with the events after the sequence number as soon as there are any, or with no
+// events once the timeout passes.  The response holds the token to resume from.
+func pollEvents(w http.ResponseWriter, r *http.Request, c *mgo.Collection, service string, object string,
+	key string, seq int64, timeout time.Duration) error {
+	notify := broker.subscribe(service, object)
+	defer broker.unsubscribe(notify)
+
+	deadline := time.After(timeout)
+	timedOut := false
+
+	for {
+		events, next, err := eventsSince(c, service, object, key, seq, watchBatchSize)
+		if err != nil {
+			writeError(w, 500, ErrCodeStorage, "An error occurred watching privileges")
+			return err
+		}
+		seq = next
+
+		if len(events) > 0 || timedOut {
+			data, err := json.Marshal(map[string]interface{}{
+				"events":     events,
+				"next_since": strconv.FormatInt(seq, 10),
+			})
+			if err != nil {
+				writeError(w, 500, ErrCodeInternal, "An error occurred watching privileges")
+				return err
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return nil
+		}
+
+		select {
+		case <-notify:
+		case <-time.After(watchPollInterval):
+		case <-deadline:
+			timedOut = true
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Streams the events after the sequence number as Server-Sent Events until the client
+// disconnects.  Each event's id is the token to resume from.
+func streamEvents(w http.ResponseWriter, r *http.Request, c *mgo.Collection, service string, object string,
+	key string, seq int64) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, 500, ErrCodeInternal, "Streaming is not supported")
+		return fmt.Errorf("response writer can't flush")
+	}
+
+	w.Header().Set("Content-Type", eventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(200)
+	flusher.Flush()
+
+	send := func(events []ACLEvent) error {
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Action, data)
+			if err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	heartbeat := func() error {
+		if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	// Once the stream has started, errors end it and the client reconnects and resumes
+	return followEvents(r.Context(), c, service, object, key, seq, send, heartbeat)
+}
+
+// Passes the events after the sequence number to send as they are recorded, until the
+// context is done.  The optional heartbeat is called whenever the events have been idle
+// for the heartbeat interval.
+func followEvents(ctx context.Context, c *mgo.Collection, service string, object string, key string,
+	seq int64, send func(events []ACLEvent) error, heartbeat func() error) error {
+	notify := broker.subscribe(service, object)
+	defer broker.unsubscribe(notify)
+
+	ticker := time.NewTicker(watchHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		events, next, err := eventsSince(c, service, object, key, seq, watchBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(events) > 0 {
+			err = send(events)
+			if err != nil {
+				return err
+			}
+		}
+		if next != seq {
+			// There may be more events waiting in the log
+			seq = next
+			continue
+		}
+
+		select {
+		case <-notify:
+		case <-time.After(watchPollInterval):
+		case <-ticker.C:
+			if heartbeat != nil {
+				if err := heartbeat(); err != nil {
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
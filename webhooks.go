@@ -0,0 +1,581 @@
+package main
+
+import (
+	"bytes"
+	log "code.google.com/p/log4go"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"io"
+	"io/ioutil"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The number of times a delivery is attempted before it is dead-lettered
+var webhookMaxAttempts = 8
+
+// The delay before the first retry of a delivery.  It doubles on each retry.
+var webhookBackoff = 10 * time.Second
+
+// The longest delay between retries of a delivery
+var webhookMaxBackoff = time.Hour
+
+// How long a receiver has to respond to a delivery
+var webhookTimeout = 10 * time.Second
+
+// How long delivered and dead deliveries are kept before they're removed
+var webhookRetention = 7 * 24 * time.Hour
+
+// How often the delivery worker looks for due deliveries without being woken
+const webhookPollInterval = 5 * time.Second
+
+// The statuses of a delivery
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Reads the webhook delivery settings from the "webhooks" section of the config
+func configureWebhooks() {
+	webhooks, ok := Application.Config["webhooks"].(map[string]interface{})
+	if !ok {
+		log.Info("No webhook settings in config, defaulting to max attempts: %d, backoff: %s, "+
+			"max backoff: %s, timeout: %s, retention: %s", webhookMaxAttempts, webhookBackoff, webhookMaxBackoff,
+			webhookTimeout, webhookRetention)
+		return
+	}
+
+	if attempts, ok := webhooks["max_attempts"].(float64); ok {
+		webhookMaxAttempts = int(attempts)
+	}
+	if backoff, ok := webhooks["backoff_seconds"].(float64); ok {
+		webhookBackoff = time.Duration(backoff) * time.Second
+	}
+	if backoff, ok := webhooks["max_backoff_seconds"].(float64); ok {
+		webhookMaxBackoff = time.Duration(backoff) * time.Second
+	}
+	if timeout, ok := webhooks["timeout_seconds"].(float64); ok {
+		webhookTimeout = time.Duration(timeout) * time.Second
+		webhookClient.Timeout = webhookTimeout
+	}
+	if retention, ok := webhooks["retention_seconds"].(float64); ok && retention > 0 {
+		webhookRetention = time.Duration(retention) * time.Second
+	}
+
+	log.Info("Using webhook settings max attempts: %d, backoff: %s, max backoff: %s, timeout: %s, retention: %s",
+		webhookMaxAttempts, webhookBackoff, webhookMaxBackoff, webhookTimeout, webhookRetention)
+}
+
+/*
+A webhook registered on a service.  Change events on the service are posted to the URL,
+signed with the secret.  Events and Object optionally limit the changes sent to those
+actions and that object.  The secret is never returned.
+*/
+type Webhook struct {
+	ID      bson.ObjectId `json:"id" bson:"_id"`
+	Service string        `json:"service" bson:"service"`
+	URL     string        `json:"url" bson:"url"`
+	Events  []string      `json:"events" bson:"events"`
+	Object  string        `json:"object,omitempty" bson:"object,omitempty"`
+	Secret  string        `json:"-" bson:"secret"`
+	Created time.Time     `json:"created" bson:"created"`
+}
+
+/*
+A change event queued for a webhook.  A pending delivery is attempted at NextAttempt and
+retried with backoff until it is delivered, or dead-lettered once the configured number of
+attempts fail.  Once it's delivered or dead it expires after the configured retention.
+*/
+type WebhookDelivery struct {
+	ID          bson.ObjectId `json:"id" bson:"_id"`
+	WebhookID   bson.ObjectId `json:"webhook_id" bson:"webhook_id"`
+	Event       ACLEvent      `json:"event" bson:"event"`
+	Status      string        `json:"status" bson:"status"`
+	Attempts    int           `json:"attempts" bson:"attempts"`
+	NextAttempt time.Time     `json:"next_attempt" bson:"next_attempt"`
+	LastStatus  int           `json:"last_status,omitempty" bson:"last_status,omitempty"`
+	LastError   string        `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	Created     time.Time     `json:"created" bson:"created"`
+	Delivered   *time.Time    `json:"delivered,omitempty" bson:"delivered,omitempty"`
+	Expires     *time.Time    `json:"expires,omitempty" bson:"expires,omitempty"`
+}
+
+// Gets the webhook collection in the database of the ACL collection
+func webhookCollection(c *mgo.Collection) *mgo.Collection {
+	return c.Database.C("webhooks")
+}
+
+// Gets the delivery collection in the database of the ACL collection
+func deliveryCollection(c *mgo.Collection) *mgo.Collection {
+	return c.Database.C("webhook_deliveries")
+}
+
+/*
+Creates the indexes for listing a service's webhooks, claiming due deliveries and listing a
+webhook's deliveries, and one that removes finished deliveries once they expire.  The
+expiry is stored on each delivery, so changing the retention doesn't change the index.
+*/
+func (h Webhook) EnsureIndex(c *mgo.Collection) error {
+	err := webhookCollection(c).EnsureIndex(mgo.Index{Key: []string{"service", "created"}})
+	if err != nil {
+		return err
+	}
+
+	deliveries := deliveryCollection(c)
+	for _, key := range [][]string{{"status", "next_attempt"}, {"webhook_id", "-created"}} {
+		err = deliveries.EnsureIndex(mgo.Index{Key: key})
+		if err != nil {
+			return err
+		}
+	}
+	return deliveries.EnsureIndex(mgo.Index{Key: []string{"expires"}, ExpireAfter: time.Second})
+}
+
+// Checks if the webhook wants the event
+func (h Webhook) wants(event ACLEvent) bool {
+	if h.Object != "" && h.Object != event.Object {
+		return false
+	}
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, action := range h.Events {
+		if action == event.Action {
+			return true
+		}
+	}
+	return false
+}
+
+// Registers a webhook on the service
+func (h Webhook) Create(c *mgo.Collection, service string, item webhookItem) (Webhook, error) {
+	hook := Webhook{
+		ID:      bson.NewObjectId(),
+		Service: service,
+		URL:     item.URL,
+		Events:  item.Events,
+		Object:  item.Object,
+		Secret:  item.Secret,
+		Created: time.Now().UTC(),
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+
+	log.Finest("Creating webhook: %s %s", hook.ID.Hex(), hook.URL)
+	return hook, webhookCollection(c).Insert(hook)
+}
+
+// Gets a webhook of the service
+func (h Webhook) Get(c *mgo.Collection, service string, id bson.ObjectId) (Webhook, error) {
+	result := Webhook{}
+	err := webhookCollection(c).Find(bson.M{"_id": id, "service": service}).One(&result)
+	return result, err
+}
+
+// Lists the webhooks of the service
+func (h Webhook) List(c *mgo.Collection, service string) ([]Webhook, error) {
+	result := []Webhook{}
+	err := webhookCollection(c).Find(bson.M{"service": service}).Sort("created").All(&result)
+	return result, err
+}
+
+// Deletes a webhook of the service along with its deliveries
+func (h Webhook) Delete(c *mgo.Collection, service string, id bson.ObjectId) error {
+	err := webhookCollection(c).Remove(bson.M{"_id": id, "service": service})
+	if err != nil {
+		return err
+	}
+	_, err = deliveryCollection(c).RemoveAll(bson.M{"webhook_id": id})
+	return err
+}
+
+// Lists the deliveries of a webhook, newest first, optionally only those with a status
+func (h Webhook) Deliveries(c *mgo.Collection, id bson.ObjectId, status string, limit int) ([]WebhookDelivery, error) {
+	selector := bson.M{"webhook_id": id}
+	if status != "" {
+		selector["status"] = status
+	}
+
+	result := []WebhookDelivery{}
+	err := deliveryCollection(c).Find(selector).Sort("-created").Limit(limit).All(&result)
+	return result, err
+}
+
+// Queues a delivery to be attempted again now, resetting its attempts
+func (h Webhook) Retry(c *mgo.Collection, id bson.ObjectId, deliveryID bson.ObjectId) error {
+	return deliveryCollection(c).Update(bson.M{"_id": deliveryID, "webhook_id": id}, bson.M{
+		"$set":   bson.M{"status": DeliveryPending, "attempts": 0, "next_attempt": time.Now().UTC()},
+		"$unset": bson.M{"last_status": "", "last_error": "", "expires": ""},
+	})
+}
+
+// Wakes the delivery worker when deliveries are queued
+var webhookWake = make(chan struct{}, 1)
+
+// Wakes the delivery worker without blocking
+func wakeWebhookWorker() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// Queues a delivery of the event for each webhook on its service that wants it
+func enqueueWebhooks(c *mgo.Collection, event ACLEvent) error {
+	hooks, err := Webhook{}.List(c, event.Service)
+	if err != nil {
+		return err
+	}
+
+	queued := false
+	for _, hook := range hooks {
+		if !hook.wants(event) {
+			continue
+		}
+
+		now := time.Now().UTC()
+		delivery := WebhookDelivery{
+			ID:          bson.NewObjectId(),
+			WebhookID:   hook.ID,
+			Event:       event,
+			Status:      DeliveryPending,
+			NextAttempt: now,
+			Created:     now,
+		}
+		err = deliveryCollection(c).Insert(delivery)
+		if err != nil {
+			return err
+		}
+		queued = true
+	}
+
+	if queued {
+		wakeWebhookWorker()
+	}
+	return nil
+}
+
+// Signs a delivery body sent at the timestamp with the webhook's secret
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// The HTTP client deliveries are sent with
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// Posts a delivery to the webhook's URL, returning the response status
+func sendWebhook(hook Webhook, delivery WebhookDelivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Authorizer-Event", delivery.Event.Action)
+	req.Header.Set("X-Authorizer-Delivery", delivery.ID.Hex())
+	req.Header.Set("X-Authorizer-Timestamp", timestamp)
+	req.Header.Set("X-Authorizer-Signature", signWebhook(hook.Secret, timestamp, body))
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("receiver responded %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// The delay before the retry following the given number of attempts
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookBackoff << uint(attempts-1)
+	if delay <= 0 || delay > webhookMaxBackoff {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
+
+// Claims the next due delivery, pushing its next attempt back so no other worker takes it
+// while it is sent.  Returns nil when no delivery is due.
+func claimDelivery(c *mgo.Collection) (*WebhookDelivery, error) {
+	now := time.Now().UTC()
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{"next_attempt": now.Add(2 * webhookTimeout)}},
+		ReturnNew: true,
+	}
+
+	delivery := &WebhookDelivery{}
+	selector := bson.M{"status": DeliveryPending, "next_attempt": bson.M{"$lte": now}}
+	_, err := deliveryCollection(c).Find(selector).Sort("next_attempt").Apply(change, delivery)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+// Attempts a claimed delivery and records the outcome
+func attemptDelivery(c *mgo.Collection, delivery *WebhookDelivery) error {
+	update := bson.M{"attempts": delivery.Attempts + 1}
+
+	hook := Webhook{}
+	err := webhookCollection(c).FindId(delivery.WebhookID).One(&hook)
+	if err == mgo.ErrNotFound {
+		update["status"] = DeliveryDead
+		update["last_error"] = "webhook was deleted"
+		update["expires"] = time.Now().UTC().Add(webhookRetention)
+		return deliveryCollection(c).UpdateId(delivery.ID, bson.M{"$set": update})
+	} else if err != nil {
+		return err
+	}
+
+	status, err := sendWebhook(hook, *delivery)
+	update["last_status"] = status
+	if err == nil {
+		log.Debug("Delivered %s to webhook %s", delivery.ID.Hex(), hook.ID.Hex())
+		update["status"] = DeliveryDelivered
+		update["last_error"] = ""
+		update["delivered"] = time.Now().UTC()
+		update["expires"] = time.Now().UTC().Add(webhookRetention)
+	} else if delivery.Attempts+1 >= webhookMaxAttempts {
+		log.Info("Dead-lettering delivery %s to webhook %s: %s", delivery.ID.Hex(), hook.ID.Hex(), err)
+		update["status"] = DeliveryDead
+		update["last_error"] = err.Error()
+		update["expires"] = time.Now().UTC().Add(webhookRetention)
+	} else {
+		log.Debug("Delivery %s to webhook %s failed, retrying: %s", delivery.ID.Hex(), hook.ID.Hex(), err)
+		update["last_error"] = err.Error()
+		update["next_attempt"] = time.Now().UTC().Add(webhookRetryDelay(delivery.Attempts + 1))
+	}
+
+	return deliveryCollection(c).UpdateId(delivery.ID, bson.M{"$set": update})
+}
+
+// Attempts every due delivery
+func deliverDueWebhooks(c *mgo.Collection) error {
+	for {
+		delivery, err := claimDelivery(c)
+		if err != nil || delivery == nil {
+			return err
+		}
+
+		err = attemptDelivery(c, delivery)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Delivers queued webhooks in the background, when woken by a write and periodically to
+// pick up retries and deliveries queued by other processes
+func runWebhookWorker() {
+	for {
+		select {
+		case <-webhookWake:
+		case <-time.After(webhookPollInterval):
+		}
+
+		session, _, c, err := getMongo()
+		if err != nil {
+			log.Error("Error connecting to database for webhook deliveries: %s", err)
+			continue
+		}
+
+		err = deliverDueWebhooks(c)
+		if err != nil {
+			log.Error("An error occurred delivering webhooks: %s", err)
+		}
+		session.Close()
+	}
+}
+
+// Gets the webhook ID from the request URL, responding with a not found error if it isn't one
+func getWebhookID(w http.ResponseWriter, r *http.Request, name string) (bson.ObjectId, bool) {
+	id := mux.Vars(r)[name]
+	if !bson.IsObjectIdHex(id) {
+		writeError(w, 404, ErrCodeNotFound, "No such "+name)
+		return "", false
+	}
+	return bson.ObjectIdHex(id), true
+}
+
+// This is a URL handler that registers webhooks on a service
+func createWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, _ := getRequestData(r)
+
+	items := []webhookItem{}
+	if !getItems(w, r, &items) {
+		// Already responded in getItems call
+		return
+	}
+
+	result := []Webhook{}
+	for idx, item := range items {
+		hook, err := Webhook{}.Create(c, service, item)
+		if err != nil {
+			log.Error("An error occurred creating webhook. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+			writeItemError(w, 500, ErrCodeStorage, "An error occurred creating webhook", idx, "")
+			return
+		}
+		result = append(result, hook)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred creating webhooks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	w.Write(data)
+}
+
+// This is a URL handler that lists the webhooks of a service
+func listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, _ := getRequestData(r)
+
+	result, err := Webhook{}.List(c, service)
+	if err != nil {
+		log.Error("An error occurred listing webhooks. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred listing webhooks")
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred listing webhooks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// This is a URL handler that deletes a webhook of a service
+func deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, _ := getRequestData(r)
+	id, ok := getWebhookID(w, r, "webhook")
+	if !ok {
+		return
+	}
+
+	err := Webhook{}.Delete(c, service, id)
+	if err == mgo.ErrNotFound {
+		writeError(w, 404, ErrCodeNotFound, "No such webhook")
+		return
+	} else if err != nil {
+		log.Error("An error occurred deleting webhook. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred deleting webhook")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// This is a URL handler that lists the deliveries of a webhook
+func listDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, _ := getRequestData(r)
+	id, ok := getWebhookID(w, r, "webhook")
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	status := query.Get("status")
+	switch status {
+	case "", DeliveryPending, DeliveryDelivered, DeliveryDead:
+	default:
+		writeError(w, 400, ErrCodeInvalidParameter, "status must be pending, delivered or dead")
+		return
+	}
+
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			writeError(w, 400, ErrCodeInvalidParameter, "limit must be a positive integer")
+			return
+		}
+	}
+
+	_, err := Webhook{}.Get(c, service, id)
+	if err == mgo.ErrNotFound {
+		writeError(w, 404, ErrCodeNotFound, "No such webhook")
+		return
+	} else if err != nil {
+		log.Error("An error occurred getting webhook. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred listing deliveries")
+		return
+	}
+
+	result, err := Webhook{}.Deliveries(c, id, status, limit)
+	if err != nil {
+		log.Error("An error occurred listing deliveries. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred listing deliveries")
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err)
+		writeError(w, 500, ErrCodeInternal, "An error occurred listing deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// This is a URL handler that queues a delivery of a webhook to be sent again, e.g. once a
+// dead-lettered delivery's receiver is fixed
+func retryDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	c, service, _ := getRequestData(r)
+	id, ok := getWebhookID(w, r, "webhook")
+	if !ok {
+		return
+	}
+	deliveryID, ok := getWebhookID(w, r, "delivery")
+	if !ok {
+		return
+	}
+
+	_, err := Webhook{}.Get(c, service, id)
+	if err == nil {
+		err = Webhook{}.Retry(c, id, deliveryID)
+	}
+	if err == mgo.ErrNotFound {
+		writeError(w, 404, ErrCodeNotFound, "No such delivery")
+		return
+	} else if err != nil {
+		log.Error("An error occurred retrying delivery. URL: %s\nMessage: %s", r.URL.RequestURI(), err)
+		writeError(w, 500, ErrCodeStorage, "An error occurred retrying delivery")
+		return
+	}
+
+	wakeWebhookWorker()
+	w.WriteHeader(204)
+}